@@ -26,6 +26,32 @@ type Cache interface {
 	// or cache is flush.
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
 
+	// SetNX sets the given data to the cache with a duration TTL only if the key doesn't exist,
+	// reporting whether the data has been set. It is meant for best-effort idempotency keys and flags,
+	// see dlock for locks.
+	SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error)
+
+	// GetOrSet gets the data from the cache and unmarshall it to dest (cache-aside),
+	// on a miss the value returned by loader is stored in the cache with a duration TTL and unmarshalled to dest.
+	// The loader is only called on a miss, its error is returned and nothing is stored.
+	//
+	// Concurrent misses of the same key all call the loader (cache stampede),
+	// implementations can serialize the loads, eg: with a dlock.DistributedLock.
+	GetOrSet(ctx context.Context, key string, dest interface{}, ttl time.Duration, loader func(ctx context.Context) (interface{}, error)) error
+
+	// Increment atomically increments the counter stored at key by delta and returns its new value.
+	// A missing key is created at 0 before the increment and never expires, see Expire.
+	// Counters are stored as plain integers: they cannot be read with Get.
+	Increment(ctx context.Context, key string, delta int64) (int64, error)
+
+	// Decrement atomically decrements the counter stored at key by delta and returns its new value.
+	// Same with Increment: a missing key is created at 0 before the decrement.
+	Decrement(ctx context.Context, key string, delta int64) (int64, error)
+
+	// Expire sets a duration TTL to an existing key, eg: the window of a counter.
+	// If the key doesn't exist or the cache expired, cache.ErrNotFound will be returned.
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+
 	// Delete deletes data from the cache.
 	// if the key doesn't exist, nil error will be return.
 	Delete(ctx context.Context, key string) error
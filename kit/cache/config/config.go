@@ -0,0 +1,79 @@
+package cacheconfig
+
+import (
+	"context"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/cache"
+	"github.com/anthonycorbacho/workspace/kit/cache/inmem"
+	kitredis "github.com/anthonycorbacho/workspace/kit/cache/redis"
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/log"
+	"github.com/redis/go-redis/v9"
+)
+
+// Config represent a Cache configuration, it is defined by its kind.
+//
+// The supported kinds are "redis" and "inmem", the kit does not provide a "sql" cache yet.
+type Config struct {
+	Kind  string `yaml:"kind"`
+	Redis *Redis `yaml:"redis"`
+}
+
+// Cache creates the cache defined by the configuration.
+// The returned function closes the cache and must be called once the cache is no longer used.
+func (c *Config) Cache(ctx context.Context) (cache.Cache, func(), error) {
+	closeFn := func() {}
+	switch c.Kind {
+	case "redis":
+		if c.Redis == nil {
+			return nil, closeFn, errors.New("redis cache missing")
+		}
+		rc, err := kitredis.New(c.Redis.options())
+		if err != nil {
+			return nil, closeFn, errors.Wrap(err, "failed to create redis cache")
+		}
+		closeFn = func() {
+			if err := rc.Close(); err != nil {
+				log.L().Warn(ctx, "failed to close redis cache", log.Error(err))
+			}
+		}
+		return rc, closeFn, nil
+	case "inmem":
+		ic := inmem.New()
+		closeFn = func() {
+			_ = ic.Close()
+		}
+		return ic, closeFn, nil
+	}
+
+	return nil, closeFn, errors.Newf("unknown cache provider '%s'", c.Kind)
+}
+
+// Redis is a redis cache configuration.
+// Zero values fall back to the go-redis defaults.
+type Redis struct {
+	Addr         string        `yaml:"addr"`
+	Username     string        `yaml:"username"`
+	Password     string        `yaml:"password" env:"REDIS_PASSWORD,overwrite"`
+	DB           int           `yaml:"db"`
+	PoolSize     int           `yaml:"poolSize"`
+	MinIdleConns int           `yaml:"minIdleConns"`
+	DialTimeout  time.Duration `yaml:"dialTimeout"`
+	ReadTimeout  time.Duration `yaml:"readTimeout"`
+	WriteTimeout time.Duration `yaml:"writeTimeout"`
+}
+
+func (r *Redis) options() *redis.Options {
+	return &redis.Options{
+		Addr:         r.Addr,
+		Username:     r.Username,
+		Password:     r.Password,
+		DB:           r.DB,
+		PoolSize:     r.PoolSize,
+		MinIdleConns: r.MinIdleConns,
+		DialTimeout:  r.DialTimeout,
+		ReadTimeout:  r.ReadTimeout,
+		WriteTimeout: r.WriteTimeout,
+	}
+}
@@ -0,0 +1,64 @@
+package cacheconfig
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/cache/inmem"
+	kitredis "github.com/anthonycorbacho/workspace/kit/cache/redis"
+	"github.com/anthonycorbacho/workspace/kit/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisConfig(t *testing.T) {
+	if os.Getenv("TESTINGREDIS_URL") == "" {
+		t.Skip("Skipping, no env variable TESTINGREDIS_URL")
+	}
+
+	rawConf := strings.NewReader(`kind: "redis"
+redis:
+  addr: "` + os.Getenv("TESTINGREDIS_URL") + `"
+  poolSize: 4
+  dialTimeout: 2s`)
+	c := Config{}
+	// we use config from to ensure that the OS env is well respected for the password
+	err := config.From(rawConf, &c)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 4, c.Redis.PoolSize)
+	assert.Equal(t, 2*time.Second, c.Redis.DialTimeout)
+
+	ch, close, err := c.Cache(context.TODO())
+	defer close()
+	assert.NoError(t, err)
+	assert.IsType(t, &kitredis.Cache{}, ch)
+}
+
+func TestInmemConfig(t *testing.T) {
+	c := Config{}
+	err := config.From(strings.NewReader(`kind: "inmem"`), &c)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ch, close, err := c.Cache(context.TODO())
+	defer close()
+	assert.NoError(t, err)
+	assert.IsType(t, &inmem.Cache{}, ch)
+}
+
+func TestUnknownConfig(t *testing.T) {
+	c := Config{Kind: "memcached"}
+	_, close, err := c.Cache(context.TODO())
+	defer close()
+	assert.Error(t, err)
+
+	c = Config{Kind: "redis"}
+	_, close, err = c.Cache(context.TODO())
+	defer close()
+	assert.Error(t, err)
+}
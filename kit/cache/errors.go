@@ -5,6 +5,9 @@ const (
 	ErrKeyInvalid   = Error("cache key is not valid")
 	ErrValueInvalid = Error("cache value is invalid")
 	ErrNotFound     = Error("cache value not found")
+	ErrCloseTimeout = Error("cache close timed out")
+	ErrTxAborted    = Error("cache transaction aborted")
+	ErrClosed       = Error("cache is closed")
 )
 
 // Error represents a cache error.
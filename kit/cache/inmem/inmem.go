@@ -0,0 +1,405 @@
+// Package inmem provides an in-memory implementation of the cache.Cache, for tests and single node deployments.
+package inmem
+
+import (
+	"context"
+	"hash/fnv"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/cache"
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/log"
+)
+
+// enforce the Cache to implement the cache.Cache interface.
+var _ cache.Cache = (*Cache)(nil)
+
+// shardCount is the number of shards of the Cache, each shard having its own lock.
+const shardCount = 32
+
+// defaultSweepInterval is the default interval between two removals of the expired values.
+const defaultSweepInterval = time.Minute
+
+// Option defines a Cache option.
+type Option func(*Cache)
+
+// WithLogger defines the logger used to report values that cannot be unmarshalled.
+// By default, the global logger is used.
+func WithLogger(logger *log.Logger) Option {
+	return func(c *Cache) {
+		c.logger = logger
+	}
+}
+
+// WithSweepInterval defines the interval between two removals of the expired values from memory,
+// an interval lower or equal to 0 disables the sweeper. By default, the expired values are removed every minute.
+//
+// Expired values are never returned, even before being removed.
+func WithSweepInterval(interval time.Duration) Option {
+	return func(c *Cache) {
+		c.sweepInterval = interval
+	}
+}
+
+type item struct {
+	value []byte
+	// expiresAt is zero when the value never expires.
+	expiresAt time.Time
+}
+
+func (i item) expired(now time.Time) bool {
+	return !i.expiresAt.IsZero() && !now.Before(i.expiresAt)
+}
+
+type shard struct {
+	items map[string]item
+	lock  sync.Mutex
+}
+
+// Cache is an in-memory cache, safe for concurrent use.
+//
+// Values are marshalled with cache.Marshal like the redis cache, so they behave the same way
+// (eg: a value stored with a type and read with another one), and counters are stored as plain integers.
+type Cache struct {
+	shards        [shardCount]*shard
+	logger        *log.Logger
+	sweepInterval time.Duration
+	now           func() time.Time
+	stop          chan struct{}
+	stopOnce      sync.Once
+	sweeper       sync.WaitGroup
+}
+
+// New creates a new in-memory Cache.
+// Close must be called to stop the sweeper once the cache is no longer used.
+func New(opts ...Option) *Cache {
+	c := &Cache{
+		logger:        log.L(),
+		sweepInterval: defaultSweepInterval,
+		now:           time.Now,
+		stop:          make(chan struct{}),
+	}
+	for i := range c.shards {
+		c.shards[i] = &shard{items: map[string]item{}}
+	}
+	for _, o := range opts {
+		o(c)
+	}
+
+	if c.sweepInterval > 0 {
+		c.sweeper.Add(1)
+		go c.sweep()
+	}
+	return c
+}
+
+// Close stops the sweeper, the values stay available.
+func (c *Cache) Close() error {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+	c.sweeper.Wait()
+	return nil
+}
+
+// sweep removes the expired values every sweep interval, until the cache is closed.
+func (c *Cache) sweep() {
+	defer c.sweeper.Done()
+
+	ticker := time.NewTicker(c.sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.removeExpired()
+		}
+	}
+}
+
+// removeExpired removes the expired values of all the shards.
+func (c *Cache) removeExpired() {
+	for _, s := range c.shards {
+		s.lock.Lock()
+		now := c.now()
+		for key, i := range s.items {
+			if i.expired(now) {
+				delete(s.items, key)
+			}
+		}
+		s.lock.Unlock()
+	}
+}
+
+// shard returns the shard holding the key.
+func (c *Cache) shard(key string) *shard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return c.shards[h.Sum32()%shardCount]
+}
+
+// load returns the value of the key, expired values are removed.
+// The shard lock must be held.
+func (c *Cache) load(s *shard, key string) ([]byte, bool) {
+	i, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	if i.expired(c.now()) {
+		delete(s.items, key)
+		return nil, false
+	}
+	return i.value, true
+}
+
+// expiresAt returns the expiration time of a value stored now with the expiration duration.
+func (c *Cache) expiresAt(expiration time.Duration) time.Time {
+	if expiration <= 0 {
+		return time.Time{}
+	}
+	return c.now().Add(expiration)
+}
+
+// getBytes returns the stored data of the key.
+func (c *Cache) getBytes(key string) ([]byte, bool) {
+	s := c.shard(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return c.load(s, key)
+}
+
+// setBytes stores the data of the key with the expiration duration.
+func (c *Cache) setBytes(key string, b []byte, expiration time.Duration) {
+	s := c.shard(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.items[key] = item{value: b, expiresAt: c.expiresAt(expiration)}
+}
+
+func (c *Cache) Get(ctx context.Context, key string, value interface{}) error {
+	if len(key) == 0 {
+		return cache.ErrKeyInvalid
+	}
+
+	b, ok := c.getBytes(key)
+	if !ok {
+		return cache.ErrNotFound
+	}
+	if err := cache.Unmarshal(b, value); err != nil {
+		return errors.Wrapf(err, "unmarshal value of key '%s'", key)
+	}
+	return nil
+}
+
+func (c *Cache) MultiGet(ctx context.Context, keys []string, value interface{}) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	// Making sure that we are getting the correct interface
+	// we are expecting to get a &[]myType
+	typeOf := reflect.TypeOf(value)
+	if typeOf.Kind() != reflect.Ptr {
+		return errors.New("value should be a pointer")
+	}
+
+	valueOf := reflect.ValueOf(value).Elem()
+	if valueOf.Kind() != reflect.Slice {
+		return errors.New("value should be a pointer of slice")
+	}
+
+	// type represent the type of the slice
+	typ := valueOf.Type().Elem()
+	for _, key := range keys {
+		b, ok := c.getBytes(key)
+		if !ok {
+			continue
+		}
+
+		object := reflect.New(typ).Interface()
+		if err := cache.Unmarshal(b, object); err != nil {
+			// skip the invalid value, but make it visible.
+			c.logger.Warn(ctx, "cache value unmarshal failure", log.String("key", key), log.Error(err))
+			continue
+		}
+		valueOf.Set(reflect.Append(valueOf, reflect.ValueOf(object).Elem()))
+	}
+	return nil
+}
+
+func (c *Cache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	if len(key) == 0 {
+		return cache.ErrKeyInvalid
+	}
+
+	if value == nil {
+		return cache.ErrValueInvalid
+	}
+
+	b, err := cache.Marshal(value)
+	if err != nil {
+		return errors.Wrapf(err, "marshalling value for key '%s'", key)
+	}
+	c.setBytes(key, b, expiration)
+	return nil
+}
+
+// SetNX sets the given value to the cache with the ttl duration only if the key doesn't exist,
+// reporting whether the value has been set.
+func (c *Cache) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	if len(key) == 0 {
+		return false, cache.ErrKeyInvalid
+	}
+
+	if value == nil {
+		return false, cache.ErrValueInvalid
+	}
+
+	b, err := cache.Marshal(value)
+	if err != nil {
+		return false, errors.Wrapf(err, "marshalling value for key '%s'", key)
+	}
+
+	s := c.shard(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := c.load(s, key); ok {
+		return false, nil
+	}
+	s.items[key] = item{value: b, expiresAt: c.expiresAt(ttl)}
+	return true, nil
+}
+
+// MultiSet sets all the given items to the cache with the same duration TTL.
+// Same with the redis cache: if any of the values cannot be marshalled, nothing is written
+// and the marshalling errors of every failing key are returned.
+func (c *Cache) MultiSet(ctx context.Context, items map[string]interface{}, expiration time.Duration) error {
+	values := make(map[string][]byte, len(items))
+	var errs []error
+	for key, value := range items {
+		if len(key) == 0 {
+			errs = append(errs, cache.ErrKeyInvalid)
+			continue
+		}
+		if value == nil {
+			errs = append(errs, errors.Wrapf(cache.ErrValueInvalid, "key '%s'", key))
+			continue
+		}
+		b, err := cache.Marshal(value)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "marshalling value for key '%s'", key))
+			continue
+		}
+		values[key] = b
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	for key, b := range values {
+		c.setBytes(key, b, expiration)
+	}
+	return nil
+}
+
+// GetOrSet gets the value of the key and unmarshall it to dest, on a miss the value returned by loader is stored
+// with the ttl duration and unmarshalled to dest.
+// Concurrent misses of the same key all call the loader.
+func (c *Cache) GetOrSet(ctx context.Context, key string, dest interface{}, ttl time.Duration, loader func(ctx context.Context) (interface{}, error)) error {
+	err := c.Get(ctx, key, dest)
+	if err == nil || errors.Is(err, cache.ErrKeyInvalid) {
+		return err
+	}
+
+	value, err := loader(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "load value of key '%s'", key)
+	}
+	if value == nil {
+		return cache.ErrValueInvalid
+	}
+
+	b, err := cache.Marshal(value)
+	if err != nil {
+		return errors.Wrapf(err, "marshalling value for key '%s'", key)
+	}
+	c.setBytes(key, b, ttl)
+
+	// dest is populated from the stored data, the same way a later Get would.
+	if err := cache.Unmarshal(b, dest); err != nil {
+		return errors.Wrapf(err, "unmarshal value of key '%s'", key)
+	}
+	return nil
+}
+
+// Increment atomically increments the counter stored at key by delta and returns its new value.
+// Like redis INCRBY, the expiration of the key is kept.
+func (c *Cache) Increment(ctx context.Context, key string, delta int64) (int64, error) {
+	if len(key) == 0 {
+		return 0, cache.ErrKeyInvalid
+	}
+
+	s := c.shard(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var n int64
+	i := item{}
+	if b, ok := c.load(s, key); ok {
+		var err error
+		n, err = strconv.ParseInt(string(b), 10, 64)
+		if err != nil {
+			return 0, errors.Newf("incrementing counter of key '%s': value is not an integer", key)
+		}
+		i = s.items[key]
+	}
+	n += delta
+	i.value = []byte(strconv.FormatInt(n, 10))
+	s.items[key] = i
+	return n, nil
+}
+
+// Decrement atomically decrements the counter stored at key by delta and returns its new value.
+func (c *Cache) Decrement(ctx context.Context, key string, delta int64) (int64, error) {
+	return c.Increment(ctx, key, -delta)
+}
+
+// Expire sets the ttl of an existing key, cache.ErrNotFound is returned if the key doesn't exist.
+// Like redis EXPIRE, a ttl lower or equal to 0 deletes the key.
+func (c *Cache) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	if len(key) == 0 {
+		return cache.ErrKeyInvalid
+	}
+
+	s := c.shard(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := c.load(s, key); !ok {
+		return cache.ErrNotFound
+	}
+	if ttl <= 0 {
+		delete(s.items, key)
+		return nil
+	}
+	i := s.items[key]
+	i.expiresAt = c.expiresAt(ttl)
+	s.items[key] = i
+	return nil
+}
+
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	if len(key) == 0 {
+		return cache.ErrKeyInvalid
+	}
+
+	s := c.shard(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.items, key)
+	return nil
+}
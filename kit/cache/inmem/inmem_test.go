@@ -0,0 +1,266 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/cache"
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// clock is a manually advanced time.
+type clock struct {
+	now  time.Time
+	lock sync.Mutex
+}
+
+func (c *clock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// newCache creates a Cache without sweeper, following the given clock.
+func newCache(t *testing.T, clk *clock) *Cache {
+	c := New(WithSweepInterval(0))
+	c.now = clk.Now
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+type myStruct struct {
+	Value  string
+	Number int
+}
+
+func TestSetAndGet(t *testing.T) {
+	ctx := context.Background()
+	clk := &clock{now: time.Now()}
+	c := newCache(t, clk)
+
+	assert.NoError(t, c.Set(ctx, "key", myStruct{Value: "value", Number: 42}, 0))
+	var got myStruct
+	assert.NoError(t, c.Get(ctx, "key", &got))
+	assert.Equal(t, myStruct{Value: "value", Number: 42}, got)
+
+	// the value is marshalled, like with redis.
+	var other struct{ Value string }
+	assert.NoError(t, c.Get(ctx, "key", &other))
+	assert.Equal(t, "value", other.Value)
+	var s string
+	assert.Error(t, c.Get(ctx, "key", &s))
+
+	assert.ErrorIs(t, c.Get(ctx, "not_exist", &got), cache.ErrNotFound)
+	assert.ErrorIs(t, c.Get(ctx, "", &got), cache.ErrKeyInvalid)
+	assert.ErrorIs(t, c.Set(ctx, "", got, 0), cache.ErrKeyInvalid)
+	assert.ErrorIs(t, c.Set(ctx, "key", nil, 0), cache.ErrValueInvalid)
+	assert.Error(t, c.Set(ctx, "key", make(chan int), 0))
+
+	assert.NoError(t, c.Delete(ctx, "key"))
+	assert.ErrorIs(t, c.Get(ctx, "key", &got), cache.ErrNotFound)
+	assert.NoError(t, c.Delete(ctx, "key"))
+}
+
+func TestExpiration(t *testing.T) {
+	ctx := context.Background()
+	clk := &clock{now: time.Now()}
+	c := newCache(t, clk)
+
+	assert.NoError(t, c.Set(ctx, "short", "value", time.Second))
+	assert.NoError(t, c.Set(ctx, "forever", "value", 0))
+
+	clk.Add(time.Second)
+	var s string
+	assert.ErrorIs(t, c.Get(ctx, "short", &s), cache.ErrNotFound)
+	assert.NoError(t, c.Get(ctx, "forever", &s))
+
+	// a new Set resets the expiration.
+	assert.NoError(t, c.Set(ctx, "forever", "value", time.Second))
+	clk.Add(time.Second)
+	assert.ErrorIs(t, c.Get(ctx, "forever", &s), cache.ErrNotFound)
+}
+
+func TestSweeper(t *testing.T) {
+	ctx := context.Background()
+	c := New(WithSweepInterval(10 * time.Millisecond))
+	defer c.Close()
+
+	assert.NoError(t, c.Set(ctx, "key", "value", time.Millisecond))
+	assert.NoError(t, c.Set(ctx, "forever", "value", 0))
+	s := c.shard("key")
+	assert.Eventually(t, func() bool {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		_, ok := s.items["key"]
+		return !ok
+	}, 5*time.Second, 10*time.Millisecond)
+
+	var v string
+	assert.NoError(t, c.Get(ctx, "forever", &v))
+
+	// Close stops the sweeper and can be called again.
+	assert.NoError(t, c.Close())
+	assert.NoError(t, c.Close())
+}
+
+func TestSetNX(t *testing.T) {
+	ctx := context.Background()
+	clk := &clock{now: time.Now()}
+	c := newCache(t, clk)
+
+	set, err := c.SetNX(ctx, "key", "first", time.Second)
+	assert.NoError(t, err)
+	assert.True(t, set)
+	set, err = c.SetNX(ctx, "key", "second", time.Second)
+	assert.NoError(t, err)
+	assert.False(t, set)
+	var s string
+	assert.NoError(t, c.Get(ctx, "key", &s))
+	assert.Equal(t, "first", s)
+
+	// set again once expired.
+	clk.Add(time.Second)
+	set, err = c.SetNX(ctx, "key", "second", time.Second)
+	assert.NoError(t, err)
+	assert.True(t, set)
+
+	_, err = c.SetNX(ctx, "", "value", 0)
+	assert.ErrorIs(t, err, cache.ErrKeyInvalid)
+	_, err = c.SetNX(ctx, "other", nil, 0)
+	assert.ErrorIs(t, err, cache.ErrValueInvalid)
+}
+
+func TestMultiGet(t *testing.T) {
+	ctx := context.Background()
+	clk := &clock{now: time.Now()}
+	c := newCache(t, clk)
+
+	assert.NoError(t, c.Set(ctx, "first", myStruct{Value: "first"}, 0))
+	assert.NoError(t, c.Set(ctx, "second", myStruct{Value: "second"}, 0))
+	assert.NoError(t, c.Set(ctx, "invalid", "not a struct", 0))
+	assert.NoError(t, c.Set(ctx, "expired", myStruct{Value: "expired"}, time.Second))
+	clk.Add(time.Second)
+
+	var values []myStruct
+	assert.NoError(t, c.MultiGet(ctx, []string{"first", "not_exist", "invalid", "expired", "second"}, &values))
+	assert.Equal(t, []myStruct{{Value: "first"}, {Value: "second"}}, values)
+
+	assert.Error(t, c.MultiGet(ctx, []string{"first"}, values))
+	var single myStruct
+	assert.Error(t, c.MultiGet(ctx, []string{"first"}, &single))
+	assert.NoError(t, c.MultiGet(ctx, nil, &values))
+}
+
+func TestMultiSet(t *testing.T) {
+	ctx := context.Background()
+	c := newCache(t, &clock{now: time.Now()})
+
+	assert.NoError(t, c.MultiSet(ctx, map[string]interface{}{"first": "1", "second": "2"}, 0))
+	var values []string
+	assert.NoError(t, c.MultiGet(ctx, []string{"first", "second"}, &values))
+	assert.Equal(t, []string{"1", "2"}, values)
+
+	// nothing is written when a value is invalid.
+	err := c.MultiSet(ctx, map[string]interface{}{"valid": "value", "invalid": make(chan int), "nil": nil}, 0)
+	assert.True(t, errors.Is(err, cache.ErrValueInvalid))
+	assert.Contains(t, err.Error(), "marshalling value for key 'invalid'")
+	var s string
+	assert.ErrorIs(t, c.Get(ctx, "valid", &s), cache.ErrNotFound)
+}
+
+func TestGetOrSet(t *testing.T) {
+	ctx := context.Background()
+	c := newCache(t, &clock{now: time.Now()})
+
+	loads := 0
+	loader := func(ctx context.Context) (interface{}, error) {
+		loads++
+		return myStruct{Value: "loaded"}, nil
+	}
+
+	var got myStruct
+	assert.NoError(t, c.GetOrSet(ctx, "key", &got, time.Minute, loader))
+	assert.Equal(t, myStruct{Value: "loaded"}, got)
+	got = myStruct{}
+	assert.NoError(t, c.GetOrSet(ctx, "key", &got, time.Minute, loader))
+	assert.Equal(t, myStruct{Value: "loaded"}, got)
+	assert.Equal(t, 1, loads)
+
+	errLoad := errors.New("load failure")
+	err := c.GetOrSet(ctx, "failing", &got, time.Minute, func(ctx context.Context) (interface{}, error) {
+		return nil, errLoad
+	})
+	assert.ErrorIs(t, err, errLoad)
+	assert.ErrorIs(t, c.Get(ctx, "failing", &got), cache.ErrNotFound)
+	assert.ErrorIs(t, c.GetOrSet(ctx, "", &got, time.Minute, loader), cache.ErrKeyInvalid)
+}
+
+func TestCounter(t *testing.T) {
+	ctx := context.Background()
+	clk := &clock{now: time.Now()}
+	c := newCache(t, clk)
+
+	// the window cannot be set before the first increment.
+	assert.ErrorIs(t, c.Expire(ctx, "counter", time.Minute), cache.ErrNotFound)
+
+	n, err := c.Increment(ctx, "counter", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.NoError(t, c.Expire(ctx, "counter", time.Minute))
+	n, err = c.Increment(ctx, "counter", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), n)
+	n, err = c.Decrement(ctx, "counter", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+
+	// the increments keep the window.
+	clk.Add(time.Minute)
+	n, err = c.Increment(ctx, "counter", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	assert.NoError(t, c.Set(ctx, "value", "not a counter", 0))
+	_, err = c.Increment(ctx, "value", 1)
+	assert.Error(t, err)
+
+	assert.NoError(t, c.Expire(ctx, "value", 0))
+	var s string
+	assert.ErrorIs(t, c.Get(ctx, "value", &s), cache.ErrNotFound)
+
+	_, err = c.Increment(ctx, "", 1)
+	assert.ErrorIs(t, err, cache.ErrKeyInvalid)
+}
+
+func TestConcurrentIncrement(t *testing.T) {
+	ctx := context.Background()
+	c := New()
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, _ = c.Increment(ctx, "counter", 1)
+				_ = c.Set(ctx, fmt.Sprintf("key_%d", i), j, 0)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	n, err := c.Increment(ctx, "counter", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5000), n)
+}
@@ -0,0 +1,78 @@
+package redis
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/anthonycorbacho/workspace/kit/cache"
+	"github.com/anthonycorbacho/workspace/kit/errors"
+)
+
+// compressedFlag prefixes the compressed values.
+// 0xc1 is never used by msgpack, so it cannot be mistaken for the first byte of an uncompressed value.
+const compressedFlag = 0xc1
+
+// WithCompression gzips the values larger than threshold bytes before storing them.
+// Compressed values are always transparently decompressed on Get, so compressed and
+// uncompressed entries can coexist. By default, values are not compressed.
+func WithCompression(threshold int) Option {
+	return func(c *Cache) {
+		c.compressionThreshold = threshold
+	}
+}
+
+// marshal encodes the value, compressed if it is larger than the compression threshold.
+func (c *Cache) marshal(value interface{}) ([]byte, error) {
+	b, err := cache.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return c.compress(b)
+}
+
+// unmarshal decodes the stored data, compressed or not, into value.
+func (c *Cache) unmarshal(data []byte, value interface{}) error {
+	b, err := decompress(data)
+	if err != nil {
+		return err
+	}
+	return cache.Unmarshal(b, value)
+}
+
+// compress compresses the encoded value if it is larger than the compression threshold.
+func (c *Cache) compress(b []byte) ([]byte, error) {
+	if c.compressionThreshold <= 0 || len(b) <= c.compressionThreshold {
+		return b, nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(compressedFlag)
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, errors.Wrap(err, "compress value")
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrap(err, "compress value")
+	}
+	return buf.Bytes(), nil
+}
+
+// decompress decompresses the stored value if it has been compressed.
+func decompress(b []byte) ([]byte, error) {
+	if len(b) == 0 || b[0] != compressedFlag {
+		return b, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(b[1:]))
+	if err != nil {
+		return nil, errors.Wrap(err, "decompress value")
+	}
+	defer r.Close()
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "decompress value")
+	}
+	return out, nil
+}
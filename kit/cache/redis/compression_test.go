@@ -0,0 +1,85 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/anthonycorbacho/workspace/kit/cache"
+	"github.com/anthonycorbacho/workspace/kit/id"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompression(t *testing.T) {
+	c := &Cache{compressionThreshold: 64}
+	large := strings.Repeat("large value ", 100)
+
+	// large values are compressed and flagged.
+	b, err := c.marshal(large)
+	require.NoError(t, err)
+	assert.Equal(t, byte(compressedFlag), b[0])
+	assert.Less(t, len(b), len(large))
+
+	var value string
+	require.NoError(t, c.unmarshal(b, &value))
+	assert.Equal(t, large, value)
+
+	// small values are stored as is.
+	b, err = c.marshal("small")
+	require.NoError(t, err)
+	raw, err := cache.Marshal("small")
+	require.NoError(t, err)
+	assert.Equal(t, raw, b)
+
+	require.NoError(t, c.unmarshal(b, &value))
+	assert.Equal(t, "small", value)
+
+	// compressed values are decompressed even when compression is disabled.
+	b, err = c.marshal(large)
+	require.NoError(t, err)
+	require.NoError(t, (&Cache{}).unmarshal(b, &value))
+	assert.Equal(t, large, value)
+}
+
+func (r *redisTestSuite) TestCompression() {
+	ctx := context.TODO()
+	c, err := New(r.cache.client.(*redis.Client).Options(), WithCompression(64))
+	r.Require().NoError(err)
+	defer c.Close()
+
+	large := strings.Repeat("large value ", 100)
+	largeKey := fmt.Sprintf("key_%s", id.New())
+	smallKey := fmt.Sprintf("key_%s", id.New())
+	defer r.cache.Delete(ctx, largeKey)
+	defer r.cache.Delete(ctx, smallKey)
+	r.Require().NoError(c.Set(ctx, largeKey, large, 0))
+	r.Require().NoError(c.Set(ctx, smallKey, "small", 0))
+
+	// inspect the stored bytes
+	stored, err := c.client.Get(ctx, largeKey).Bytes()
+	r.Require().NoError(err)
+	r.Equal(byte(compressedFlag), stored[0])
+	r.Less(len(stored), len(large))
+
+	stored, err = c.client.Get(ctx, smallKey).Bytes()
+	r.Require().NoError(err)
+	raw, err := cache.Marshal("small")
+	r.Require().NoError(err)
+	r.Equal(raw, stored)
+
+	// both are readable, including by a cache without compression.
+	var value string
+	r.Require().NoError(c.Get(ctx, largeKey, &value))
+	r.Equal(large, value)
+	r.Require().NoError(r.cache.Get(ctx, largeKey, &value))
+	r.Equal(large, value)
+	r.Require().NoError(r.cache.Get(ctx, smallKey, &value))
+	r.Equal("small", value)
+
+	values := []string{}
+	r.Require().NoError(r.cache.MultiGet(ctx, []string{largeKey, smallKey}, &values))
+	r.Equal([]string{large, "small"}, values)
+}
@@ -0,0 +1,71 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/cache"
+	"github.com/anthonycorbacho/workspace/kit/errors"
+)
+
+// Increment atomically increments the counter stored at key by delta (INCRBY) and returns its new value.
+// The counter is stored as a plain integer, not marshalled like Set: it cannot be read with Get.
+func (c *Cache) Increment(ctx context.Context, key string, delta int64) (int64, error) {
+	if len(key) == 0 {
+		return 0, cache.ErrKeyInvalid
+	}
+
+	if c.closed.Load() {
+		return 0, cache.ErrClosed
+	}
+
+	n, err := c.client.IncrBy(ctx, key, delta).Result()
+	if err != nil {
+		return 0, errors.Wrapf(err, "incrementing counter of key '%s'", key)
+	}
+	return n, nil
+}
+
+// Decrement atomically decrements the counter stored at key by delta (DECRBY) and returns its new value.
+func (c *Cache) Decrement(ctx context.Context, key string, delta int64) (int64, error) {
+	if len(key) == 0 {
+		return 0, cache.ErrKeyInvalid
+	}
+
+	if c.closed.Load() {
+		return 0, cache.ErrClosed
+	}
+
+	n, err := c.client.DecrBy(ctx, key, delta).Result()
+	if err != nil {
+		return 0, errors.Wrapf(err, "decrementing counter of key '%s'", key)
+	}
+	return n, nil
+}
+
+// Expire sets the ttl of an existing key, cache.ErrNotFound is returned if the key doesn't exist.
+//
+// A typical rate limit sets the window after the first increment:
+//
+//	n, err := c.Increment(ctx, key, 1)
+//	if err == nil && n == 1 {
+//		err = c.Expire(ctx, key, time.Minute)
+//	}
+func (c *Cache) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	if len(key) == 0 {
+		return cache.ErrKeyInvalid
+	}
+
+	if c.closed.Load() {
+		return cache.ErrClosed
+	}
+
+	ok, err := c.client.Expire(ctx, key, ttl).Result()
+	if err != nil {
+		return errors.Wrapf(err, "setting expiration of key '%s'", key)
+	}
+	if !ok {
+		return cache.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/cache"
+	"github.com/anthonycorbacho/workspace/kit/id"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounter_InvalidKey(t *testing.T) {
+	c, err := New(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	_, err = c.Increment(ctx, "", 1)
+	assert.ErrorIs(t, err, cache.ErrKeyInvalid)
+	_, err = c.Decrement(ctx, "", 1)
+	assert.ErrorIs(t, err, cache.ErrKeyInvalid)
+	assert.ErrorIs(t, c.Expire(ctx, "", time.Minute), cache.ErrKeyInvalid)
+}
+
+func TestCounter(t *testing.T) {
+	if os.Getenv("TESTINGREDIS_URL") == "" {
+		t.Skip("Skipping, no testing redis setup via env variable TESTINGREDIS_URL")
+	}
+
+	c, err := New(&redis.Options{Addr: os.Getenv("TESTINGREDIS_URL")})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	key := fmt.Sprintf("counter_%s", id.New())
+	defer c.Delete(ctx, key)
+
+	// the window cannot be set before the first increment.
+	assert.ErrorIs(t, c.Expire(ctx, key, time.Minute), cache.ErrNotFound)
+
+	n, err := c.Increment(ctx, key, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	n, err = c.Increment(ctx, key, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), n)
+	n, err = c.Decrement(ctx, key, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+
+	assert.NoError(t, c.Expire(ctx, key, 100*time.Millisecond))
+	assert.Eventually(t, func() bool {
+		n, err := c.client.Exists(ctx, key).Result()
+		return err == nil && n == 0
+	}, 5*time.Second, 50*time.Millisecond)
+}
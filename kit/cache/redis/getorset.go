@@ -0,0 +1,107 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/cache"
+	"github.com/anthonycorbacho/workspace/kit/dlock"
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// WithLoadLock serializes the loads of GetOrSet for the same key across processes with the given distributed lock
+// (single-flight): on a miss, the lock of the key is acquired and the cache checked again before calling the loader,
+// the concurrent misses are then served by the value stored by the first load.
+func WithLoadLock(dl dlock.DistributedLock) Option {
+	return func(c *Cache) {
+		c.loadLock = dl
+	}
+}
+
+// GetOrSet gets the value of the key and unmarshall it to dest, on a miss the value returned by loader is stored
+// with the ttl duration and unmarshalled to dest.
+//
+// Cache failures are not fatal: an unreachable cache or a value that cannot be unmarshalled is treated as a miss,
+// and a value that cannot be stored is still returned.
+// Concurrent misses all call the loader unless WithLoadLock is used.
+func (c *Cache) GetOrSet(ctx context.Context, key string, dest interface{}, ttl time.Duration, loader func(ctx context.Context) (interface{}, error)) error {
+	if len(key) == 0 {
+		return cache.ErrKeyInvalid
+	}
+
+	if c.closed.Load() {
+		return cache.ErrClosed
+	}
+
+	ctx, span := otel.Tracer("kit/cache/redis").Start(ctx, "cache.GetOrSet")
+	span.SetAttributes(attribute.String("key", key))
+	defer span.End()
+
+	if c.getForLoad(ctx, key, dest) {
+		span.SetAttributes(attribute.Bool("cached", true))
+		return nil
+	}
+
+	if c.loadLock != nil {
+		lock, err := c.loadLock.Lock(ctx, "cache/"+key)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return errors.Wrapf(err, "lock load of key '%s'", key)
+		}
+		defer lock.Unlock(context.Background()) //nolint
+
+		// loaded by another holder of the lock in the meantime.
+		if c.getForLoad(ctx, key, dest) {
+			span.SetAttributes(attribute.Bool("cached", true))
+			return nil
+		}
+	}
+	span.SetAttributes(attribute.Bool("cached", false))
+
+	value, err := loader(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return errors.Wrapf(err, "load value of key '%s'", key)
+	}
+	if value == nil {
+		return cache.ErrValueInvalid
+	}
+
+	b, err := c.marshal(value)
+	if err != nil {
+		c.codecError(ctx, "marshal", key, err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return errors.Wrapf(err, "marshalling value for key '%s'", key)
+	}
+	if err := c.client.Set(ctx, key, b, ttl).Err(); err != nil {
+		span.RecordError(err)
+		c.logger.Warn(ctx, "cache value not stored", log.String("key", key), log.Error(err))
+	}
+
+	// dest is populated from the stored data, the same way a later Get would.
+	if err := c.unmarshal(b, dest); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return errors.Wrapf(err, "unmarshal value of key '%s'", key)
+	}
+	return nil
+}
+
+// getForLoad gets the value of the key into dest, reporting whether it has been found.
+func (c *Cache) getForLoad(ctx context.Context, key string, dest interface{}) bool {
+	err := c.Get(ctx, key, dest)
+	if err == nil {
+		return true
+	}
+	if !errors.Is(err, cache.ErrNotFound) {
+		c.logger.Warn(ctx, "cache value not read, loading it", log.String("key", key), log.Error(err))
+	}
+	return false
+}
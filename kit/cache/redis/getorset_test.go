@@ -0,0 +1,120 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/dlock"
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/id"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// mutexLock is an in-process dlock.DistributedLock.
+type mutexLock struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+	keys  []string
+}
+
+func (m *mutexLock) Lock(_ context.Context, key string) (dlock.Lock, error) {
+	m.mu.Lock()
+	if m.locks == nil {
+		m.locks = map[string]*sync.Mutex{}
+	}
+	l, ok := m.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		m.locks[key] = l
+	}
+	m.keys = append(m.keys, key)
+	m.mu.Unlock()
+
+	l.Lock()
+	return unlockFunc(l.Unlock), nil
+}
+
+type unlockFunc func()
+
+func (f unlockFunc) Unlock(context.Context) error {
+	f()
+	return nil
+}
+
+func (f unlockFunc) Token() int64 { return 0 }
+
+func TestGetOrSet_Unreachable(t *testing.T) {
+	dl := &mutexLock{}
+	c, err := New(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1}, WithLoadLock(dl))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+
+	// the cache failure is not fatal, the loaded value is returned.
+	var got string
+	err = c.GetOrSet(context.Background(), "key", &got, time.Minute, func(ctx context.Context) (interface{}, error) {
+		return "loaded", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "loaded", got)
+	assert.Equal(t, []string{"cache/key"}, dl.keys)
+
+	// loader errors are returned.
+	loadErr := errors.New("boom")
+	err = c.GetOrSet(context.Background(), "key", &got, time.Minute, func(ctx context.Context) (interface{}, error) {
+		return nil, loadErr
+	})
+	assert.ErrorIs(t, err, loadErr)
+}
+
+func TestGetOrSet(t *testing.T) {
+	if os.Getenv("TESTINGREDIS_URL") == "" {
+		t.Skip("Skipping, no testing redis setup via env variable TESTINGREDIS_URL")
+	}
+
+	c, err := New(&redis.Options{Addr: os.Getenv("TESTINGREDIS_URL")}, WithLoadLock(&mutexLock{}))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+
+	type user struct {
+		Name string
+	}
+	ctx := context.Background()
+	key := fmt.Sprintf("getorset_%s", id.New())
+	defer c.Delete(ctx, key) //nolint
+
+	var loads atomic.Int32
+	loader := func(ctx context.Context) (interface{}, error) {
+		loads.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return user{Name: "jean"}, nil
+	}
+
+	// concurrent misses are loaded once.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var u user
+			assert.NoError(t, c.GetOrSet(ctx, key, &u, time.Minute, loader))
+			assert.Equal(t, "jean", u.Name)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), loads.Load())
+
+	// the value is stored.
+	var u user
+	assert.NoError(t, c.Get(ctx, key, &u))
+	assert.Equal(t, "jean", u.Name)
+}
@@ -0,0 +1,49 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/anthonycorbacho/workspace/kit/cache"
+	"github.com/anthonycorbacho/workspace/kit/errors"
+)
+
+// MultiGetTyped gets the values of multiple keys like Cache.MultiGet, without reflection:
+// it is the fast path for large result sets.
+//
+// Missing keys and values that cannot be unmarshalled into T are skipped,
+// the values are returned in the order of the keys.
+func MultiGetTyped[T any](ctx context.Context, c *Cache, keys []string) ([]T, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	if c.closed.Load() {
+		return nil, cache.ErrClosed
+	}
+
+	results, err := c.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, errors.Wrapf(err, "redis MGet error, keys is %+v", keys)
+	}
+	return decodeTyped[T](ctx, c, keys, results), nil
+}
+
+// decodeTyped decodes the MGet results, skipping the missing and invalid values.
+func decodeTyped[T any](ctx context.Context, c *Cache, keys []string, results []interface{}) []T {
+	values := make([]T, 0, len(results))
+	for i, result := range results {
+		data, ok := result.(string)
+		if !ok {
+			continue
+		}
+
+		var v T
+		if err := c.unmarshal([]byte(data), &v); err != nil {
+			// skip the invalid value, but make it visible.
+			c.codecError(ctx, "unmarshal", keys[i], err)
+			continue
+		}
+		values = append(values, v)
+	}
+	return values
+}
@@ -0,0 +1,93 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/anthonycorbacho/workspace/kit/cache"
+	"github.com/anthonycorbacho/workspace/kit/log"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+type benchItem struct {
+	ID    string
+	Count int
+	Tags  []string
+}
+
+// mgetResults returns n keys and their MGet results, with a missing and an invalid value.
+func mgetResults(t testing.TB, n int) ([]string, []interface{}) {
+	t.Helper()
+	keys := make([]string, n)
+	results := make([]interface{}, n)
+	for i := 0; i < n; i++ {
+		keys[i] = fmt.Sprintf("key_%d", i)
+		b, err := cache.Marshal(benchItem{ID: keys[i], Count: i, Tags: []string{"a", "b"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		results[i] = string(b)
+	}
+	if n > 2 {
+		results[1] = nil
+		results[2] = "invalid"
+	}
+	return keys, results
+}
+
+func newDecodeCache(t testing.TB) *Cache {
+	t.Helper()
+	c, err := New(&redis.Options{Addr: "127.0.0.1:1"}, WithLogger(log.NewNop()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestDecodeTyped(t *testing.T) {
+	c := newDecodeCache(t)
+	defer c.Close()
+	keys, results := mgetResults(t, 100)
+
+	var reflective []benchItem
+	c.decodeResults(context.Background(), keys, results, reflect.ValueOf(&reflective).Elem())
+	typed := decodeTyped[benchItem](context.Background(), c, keys, results)
+
+	// the missing and invalid values are skipped by both.
+	assert.Len(t, typed, 98)
+	assert.Equal(t, reflective, typed)
+
+	// the typed path allocates less.
+	reflectiveAllocs := testing.AllocsPerRun(10, func() {
+		var values []benchItem
+		c.decodeResults(context.Background(), keys, results, reflect.ValueOf(&values).Elem())
+	})
+	typedAllocs := testing.AllocsPerRun(10, func() {
+		_ = decodeTyped[benchItem](context.Background(), c, keys, results)
+	})
+	assert.Less(t, typedAllocs, reflectiveAllocs)
+}
+
+func BenchmarkMultiGetDecode(b *testing.B) {
+	c := newDecodeCache(b)
+	defer c.Close()
+	keys, results := mgetResults(b, 10_000)
+	ctx := context.Background()
+
+	b.Run("reflect", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var values []benchItem
+			c.decodeResults(ctx, keys, results, reflect.ValueOf(&values).Elem())
+		}
+	})
+	b.Run("typed", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = decodeTyped[benchItem](ctx, c, keys, results)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/cache"
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/id"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// spanRecorder records the spans ended.
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []sdktrace.ReadOnlySpan
+}
+
+func (r *spanRecorder) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+func (r *spanRecorder) OnEnd(s sdktrace.ReadOnlySpan) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, s)
+}
+func (r *spanRecorder) Shutdown(context.Context) error   { return nil }
+func (r *spanRecorder) ForceFlush(context.Context) error { return nil }
+
+func TestMultiSet_MarshalErrors(t *testing.T) {
+	recorder := &spanRecorder{}
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	// unreachable, nothing is written when a value cannot be marshalled.
+	c, err := New(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+
+	err = c.MultiSet(context.Background(), map[string]interface{}{
+		"valid":   "value",
+		"invalid": make(chan int),
+		"nil":     nil,
+	}, time.Minute)
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, cache.ErrValueInvalid))
+	assert.Contains(t, err.Error(), "marshalling value for key 'invalid'")
+	assert.NotContains(t, err.Error(), "'valid'")
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if assert.Len(t, recorder.spans, 1) {
+		assert.Equal(t, "cache.MultiSet", recorder.spans[0].Name())
+		assert.Equal(t, codes.Error, recorder.spans[0].Status().Code)
+	}
+
+	assert.NoError(t, c.MultiSet(context.Background(), nil, time.Minute))
+}
+
+func TestMultiSet(t *testing.T) {
+	if os.Getenv("TESTINGREDIS_URL") == "" {
+		t.Skip("Skipping, no testing redis setup via env variable TESTINGREDIS_URL")
+	}
+
+	c, err := New(&redis.Options{Addr: os.Getenv("TESTINGREDIS_URL")})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	type item struct {
+		Name string
+	}
+	items := map[string]interface{}{}
+	keys := make([]string, 0, 10)
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("multiset_%d_%s", i, id.New())
+		items[key] = item{Name: key}
+		keys = append(keys, key)
+		defer c.Delete(ctx, key) //nolint
+	}
+
+	if !assert.NoError(t, c.MultiSet(ctx, items, time.Minute)) {
+		return
+	}
+
+	var got []item
+	assert.NoError(t, c.MultiGet(ctx, keys, &got))
+	assert.Len(t, got, 10)
+	for _, it := range got {
+		assert.Equal(t, items[it.Name], it)
+	}
+
+	ttl, err := c.client.TTL(ctx, keys[0]).Result()
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Minute.Seconds(), ttl.Seconds(), 5)
+}
@@ -3,12 +3,19 @@ package redis
 import (
 	"context"
 	"reflect"
+	"sync/atomic"
 	"time"
 
 	"github.com/anthonycorbacho/workspace/kit/cache"
+	"github.com/anthonycorbacho/workspace/kit/dlock"
 	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/log"
 	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
 )
 
 // enforce the Cache to implement the cache.Cache interface.
@@ -16,18 +23,55 @@ var _ cache.Cache = (*Cache)(nil)
 
 // Cache provides a cache based on Redis
 type Cache struct {
-	client *redis.Client
+	client               redis.UniversalClient
+	logger               *log.Logger
+	codecErrors          metric.Int64Counter
+	compressionThreshold int
+	// loadLock serializes the loads of GetOrSet, see WithLoadLock.
+	loadLock dlock.DistributedLock
+	// closed is set by CloseWithContext, the operations fail with cache.ErrClosed from then on.
+	closed atomic.Bool
+}
+
+// Option defines a Cache option.
+type Option func(*Cache)
+
+// WithLogger defines the logger used to report values that cannot be marshalled or unmarshalled.
+// By default, the global logger is used.
+func WithLogger(logger *log.Logger) Option {
+	return func(c *Cache) {
+		c.logger = logger
+	}
 }
 
 // New create a new Cache with the given redis configuration.
-func New(opt *redis.Options) (*Cache, error) {
+func New(opt *redis.Options, opts ...Option) (*Cache, error) {
 	// If there is no options, we should stop and return an error.
 	if opt == nil {
 		return nil, errors.New("redis option missing")
 	}
-	// create the client
-	rdb := redis.NewClient(opt)
+	return newCache(redis.NewClient(opt), opts...)
+}
+
+// NewCluster create a new Cache with the given redis cluster configuration.
+func NewCluster(opt *redis.ClusterOptions, opts ...Option) (*Cache, error) {
+	if opt == nil {
+		return nil, errors.New("redis cluster option missing")
+	}
+	return newCache(redis.NewClusterClient(opt), opts...)
+}
+
+// NewFailover create a new Cache with the given redis sentinel configuration,
+// the commands are sent to the master elected by the sentinels.
+func NewFailover(opt *redis.FailoverOptions, opts ...Option) (*Cache, error) {
+	if opt == nil {
+		return nil, errors.New("redis failover option missing")
+	}
+	return newCache(redis.NewFailoverClient(opt), opts...)
+}
 
+// newCache creates a new Cache using the given client, instrumented for tracing and metrics.
+func newCache(rdb redis.UniversalClient, opts ...Option) (*Cache, error) {
 	// Enable tracing instrumentation.
 	if err := redisotel.InstrumentTracing(rdb); err != nil {
 		return nil, errors.Wrap(err, "redis tracing")
@@ -38,22 +82,56 @@ func New(opt *redis.Options) (*Cache, error) {
 		return nil, errors.Wrap(err, "redis metrics")
 	}
 
-	return &Cache{
-		client: rdb,
-	}, nil
+	// Count the values that cannot be marshalled or unmarshalled,
+	// usually a sign of data corruption or schema mismatch.
+	codecErrors, err := otel.Meter("kit/cache/redis").Int64Counter("cache.codec.errors",
+		metric.WithDescription("Number of cache values that failed to be marshalled or unmarshalled"),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "redis codec errors metric")
+	}
+
+	c := &Cache{
+		client:      rdb,
+		logger:      log.L(),
+		codecErrors: codecErrors,
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c, nil
 }
 
-// Close closes the connection to redis.
+// closeTimeout is the maximum time Close waits for the connection to redis to be closed.
+const closeTimeout = 5 * time.Second
+
+// Close closes the connection to redis, waiting at most 5 seconds.
 func (c *Cache) Close() error {
-	if c.client == nil {
-		return errors.New("no redis client")
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	return c.CloseWithContext(ctx)
+}
+
+// CloseWithContext closes the connection to redis, the operations return cache.ErrClosed from then on.
+// If the connection is not closed before ctx is done, cache.ErrCloseTimeout is returned
+// and the connection keeps closing in the background.
+// Closing an already closed Cache returns cache.ErrClosed.
+func (c *Cache) CloseWithContext(ctx context.Context) error {
+	if !c.closed.CompareAndSwap(false, true) {
+		return cache.ErrClosed
 	}
 
-	defer func() {
-		c.client = nil
+	done := make(chan error, 1)
+	go func() {
+		done <- c.client.Close()
 	}()
 
-	return c.client.Close()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return cache.ErrCloseTimeout
+	}
 }
 
 func (c *Cache) Get(ctx context.Context, key string, value interface{}) error {
@@ -61,6 +139,10 @@ func (c *Cache) Get(ctx context.Context, key string, value interface{}) error {
 		return cache.ErrKeyInvalid
 	}
 
+	if c.closed.Load() {
+		return cache.ErrClosed
+	}
+
 	cmd := c.client.Get(ctx, key)
 	b, err := cmd.Bytes()
 	if err != nil {
@@ -72,7 +154,11 @@ func (c *Cache) Get(ctx context.Context, key string, value interface{}) error {
 		return errors.Wrapf(err, "unmarshal value of key '%s'", key)
 	}
 
-	return cache.Unmarshal(b, value)
+	if err := c.unmarshal(b, value); err != nil {
+		c.codecError(ctx, "unmarshal", key, err)
+		return errors.Wrapf(err, "unmarshal value of key '%s'", key)
+	}
+	return nil
 }
 
 func (c *Cache) MultiGet(ctx context.Context, keys []string, value interface{}) error {
@@ -80,6 +166,10 @@ func (c *Cache) MultiGet(ctx context.Context, keys []string, value interface{})
 		return nil
 	}
 
+	if c.closed.Load() {
+		return cache.ErrClosed
+	}
+
 	// Making sure that we are getting the correct interface
 	// we are expecting to get a &[]myType
 	typeOf := reflect.TypeOf(value)
@@ -103,24 +193,30 @@ func (c *Cache) MultiGet(ctx context.Context, keys []string, value interface{})
 		return nil
 	}
 
+	c.decodeResults(ctx, keys, results, valueOf)
+	return nil
+}
+
+// decodeResults appends the MGet results to the slice valueOf, skipping the missing and invalid values.
+func (c *Cache) decodeResults(ctx context.Context, keys []string, results []interface{}, valueOf reflect.Value) {
 	// type represent the type of the slice
-	typ := reflect.TypeOf(value).Elem().Elem()
-	for _, result := range results {
+	typ := valueOf.Type().Elem()
+	for i, result := range results {
 		if result == nil {
 			continue
 		}
 
 		// creating a new value of the slice type
 		object := reflect.New(typ).Interface()
-		err = cache.Unmarshal([]byte(result.(string)), object)
+		err := c.unmarshal([]byte(result.(string)), object)
 		if err != nil {
+			// skip the invalid value, but make it visible.
+			c.codecError(ctx, "unmarshal", keys[i], err)
 			continue
 		}
 		// Adding to the slice the value.
 		valueOf.Set(reflect.Append(valueOf, reflect.ValueOf(object).Elem()))
 	}
-
-	return nil
 }
 
 func (c *Cache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
@@ -128,12 +224,17 @@ func (c *Cache) Set(ctx context.Context, key string, value interface{}, expirati
 		return cache.ErrKeyInvalid
 	}
 
+	if c.closed.Load() {
+		return cache.ErrClosed
+	}
+
 	if value == nil {
 		return cache.ErrValueInvalid
 	}
 
-	b, err := cache.Marshal(value)
+	b, err := c.marshal(value)
 	if err != nil {
+		c.codecError(ctx, "marshal", key, err)
 		return errors.Wrapf(err, "marshalling value for key '%s'", key)
 	}
 
@@ -144,14 +245,112 @@ func (c *Cache) Set(ctx context.Context, key string, value interface{}, expirati
 	return nil
 }
 
+// SetNX sets the given value to the cache with the ttl duration only if the key doesn't exist (SET NX PX),
+// reporting whether the value has been set.
+func (c *Cache) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	if len(key) == 0 {
+		return false, cache.ErrKeyInvalid
+	}
+
+	if c.closed.Load() {
+		return false, cache.ErrClosed
+	}
+
+	if value == nil {
+		return false, cache.ErrValueInvalid
+	}
+
+	b, err := c.marshal(value)
+	if err != nil {
+		c.codecError(ctx, "marshal", key, err)
+		return false, errors.Wrapf(err, "marshalling value for key '%s'", key)
+	}
+
+	set, err := c.client.SetNX(ctx, key, b, ttl).Result()
+	if err != nil {
+		return false, errors.Wrapf(err, "saving value to cache for key '%s'", key)
+	}
+	return set, nil
+}
+
+// MultiSet sets all the given items to the cache with the same duration TTL in a single round trip (pipeline).
+// Values are marshalled like Set: if any of them cannot be marshalled, nothing is written
+// and the marshalling errors of every failing key are returned.
+func (c *Cache) MultiSet(ctx context.Context, items map[string]interface{}, expiration time.Duration) error {
+	if c.closed.Load() {
+		return cache.ErrClosed
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	ctx, span := otel.Tracer("kit/cache/redis").Start(ctx, "cache.MultiSet")
+	span.SetAttributes(attribute.Int("keys", len(items)))
+	defer span.End()
+
+	values := make(map[string][]byte, len(items))
+	var errs []error
+	for key, value := range items {
+		if len(key) == 0 {
+			errs = append(errs, cache.ErrKeyInvalid)
+			continue
+		}
+		if value == nil {
+			errs = append(errs, errors.Wrapf(cache.ErrValueInvalid, "key '%s'", key))
+			continue
+		}
+		b, err := c.marshal(value)
+		if err != nil {
+			c.codecError(ctx, "marshal", key, err)
+			errs = append(errs, errors.Wrapf(err, "marshalling value for key '%s'", key))
+			continue
+		}
+		values[key] = b
+	}
+	if err := errors.Join(errs...); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, b := range values {
+			pipe.Set(ctx, key, b, expiration)
+		}
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return errors.Wrapf(err, "saving %d values to cache", len(values))
+	}
+
+	return nil
+}
+
 func (c *Cache) Delete(ctx context.Context, key string) error {
 	if len(key) == 0 {
 		return cache.ErrKeyInvalid
 	}
 
+	if c.closed.Load() {
+		return cache.ErrClosed
+	}
+
 	if err := c.client.Del(ctx, key).Err(); err != nil {
 		return errors.Wrapf(err, "deleting value from cache for key '%s'", key)
 	}
 
 	return nil
 }
+
+// codecError reports a value of the given key that failed to be marshalled or unmarshalled.
+func (c *Cache) codecError(ctx context.Context, operation string, key string, err error) {
+	c.logger.Warn(ctx, "cache value "+operation+" failure",
+		log.String("key", key),
+		log.String("operation", operation),
+		log.Error(err),
+	)
+	c.codecErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("operation", operation)))
+}
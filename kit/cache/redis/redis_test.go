@@ -1,16 +1,23 @@
 package redis
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/anthonycorbacho/workspace/kit/cache"
 	"github.com/anthonycorbacho/workspace/kit/id"
+	"github.com/anthonycorbacho/workspace/kit/log"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 )
 
 type redisTestSuite struct {
@@ -136,6 +143,49 @@ func (r *redisTestSuite) TestSetAndMultiGet() {
 	r.Nil(err)
 }
 
+func (r *redisTestSuite) TestMultiGetTyped() {
+	ctx := context.TODO()
+	type myStruct struct {
+		Value string
+	}
+
+	key := fmt.Sprintf("key_%s", id.New())
+	key2 := fmt.Sprintf("key_%s", id.New())
+	defer r.cache.Delete(ctx, key)
+	defer r.cache.Delete(ctx, key2)
+	r.NoError(r.cache.Set(ctx, key, myStruct{Value: "first"}, 0))
+	r.NoError(r.cache.Set(ctx, key2, myStruct{Value: "second"}, 0))
+
+	// same results as the reflective MultiGet.
+	keys := []string{key, "not_exist", key2}
+	var reflective []myStruct
+	r.NoError(r.cache.MultiGet(ctx, keys, &reflective))
+	typed, err := MultiGetTyped[myStruct](ctx, r.cache, keys)
+	r.NoError(err)
+	r.Equal([]myStruct{{Value: "first"}, {Value: "second"}}, typed)
+	r.Equal(reflective, typed)
+}
+
+func (r *redisTestSuite) TestSetNX() {
+	ctx := context.TODO()
+	key := fmt.Sprintf("key_%s", id.New())
+	defer r.cache.Delete(ctx, key)
+
+	set, err := r.cache.SetNX(ctx, key, "first", time.Minute)
+	r.NoError(err)
+	r.True(set)
+	set, err = r.cache.SetNX(ctx, key, "second", time.Minute)
+	r.NoError(err)
+	r.False(set)
+
+	var value string
+	r.NoError(r.cache.Get(ctx, key, &value))
+	r.Equal("first", value)
+	ttl, err := r.cache.client.TTL(ctx, key).Result()
+	r.NoError(err)
+	r.Greater(ttl, time.Duration(0))
+}
+
 func (r *redisTestSuite) TestSetAndDelete() {
 	// Given
 	ctx := context.TODO()
@@ -157,3 +207,121 @@ func (r *redisTestSuite) TestSetAndDelete() {
 	assert.Empty(r.T(), noop)
 	assert.Equal(r.T(), err, cache.ErrNotFound)
 }
+
+func (r *redisTestSuite) TestCorruptEntries() {
+	// Given
+	ctx := context.TODO()
+	reader := sdkmetric.NewManualReader()
+	previous := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	r.T().Cleanup(func() { otel.SetMeterProvider(previous) })
+
+	// logger writing to a file instead of stderr
+	output := filepath.Join(r.T().TempDir(), "log")
+	logger, err := log.New(log.WithOutputPaths(output))
+	r.Require().NoError(err)
+
+	c, err := New(r.cache.client.(*redis.Client).Options(), WithLogger(logger))
+	r.Require().NoError(err)
+
+	good := fmt.Sprintf("key_%s", id.New())
+	corrupt := fmt.Sprintf("key_%s", id.New())
+	defer r.cache.Delete(ctx, good)
+	defer r.cache.Delete(ctx, corrupt)
+	r.Require().NoError(c.Set(ctx, good, 42, 0))
+	// 0xc1 is never used by msgpack.
+	r.Require().NoError(c.client.Set(ctx, corrupt, []byte{0xc1}, 0).Err())
+
+	// the corrupt entry is skipped, good entries are still returned.
+	values := []int{}
+	err = c.MultiGet(ctx, []string{good, corrupt}, &values)
+	r.NoError(err)
+	r.Equal([]int{42}, values)
+
+	var value int
+	err = c.Get(ctx, corrupt, &value)
+	r.Error(err)
+
+	// failures are logged with the key
+	logger.Close()
+	b, err := os.ReadFile(output)
+	r.Require().NoError(err)
+	r.Equal(2, bytes.Count(b, []byte(corrupt)))
+	r.Contains(string(b), `"Severity":"WARN"`)
+
+	// and counted
+	var rm metricdata.ResourceMetrics
+	r.Require().NoError(reader.Collect(ctx, &rm))
+	var count int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "cache.codec.errors" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				count += dp.Value
+			}
+		}
+	}
+	r.Equal(int64(2), count)
+}
+
+func TestCloseWithContext_Unreachable(t *testing.T) {
+	// nothing listens on this address, commands hang until they time out.
+	c, err := New(&redis.Options{
+		Addr:        "10.255.255.1:6379",
+		DialTimeout: time.Minute,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// keep a command in flight while closing.
+	client := c.client
+	go func() {
+		_ = client.Ping(context.Background()).Err()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = c.CloseWithContext(ctx)
+	if err != nil {
+		assert.ErrorIs(t, err, cache.ErrCloseTimeout)
+	}
+	assert.Less(t, time.Since(start), time.Second)
+
+	// the operations and closing again fail once closed.
+	var value string
+	assert.ErrorIs(t, c.Get(context.Background(), "key", &value), cache.ErrClosed)
+	assert.ErrorIs(t, c.Set(context.Background(), "key", "value", time.Minute), cache.ErrClosed)
+	_, err = c.Increment(context.Background(), "key", 1)
+	assert.ErrorIs(t, err, cache.ErrClosed)
+	assert.ErrorIs(t, c.Close(), cache.ErrClosed)
+}
+
+func TestNewClusterAndFailover(t *testing.T) {
+	_, err := New(nil)
+	assert.Error(t, err)
+	_, err = NewCluster(nil)
+	assert.Error(t, err)
+	_, err = NewFailover(nil)
+	assert.Error(t, err)
+
+	// nothing listens on those addresses, the commands fail but the caches are created.
+	cluster, err := NewCluster(&redis.ClusterOptions{Addrs: []string{"127.0.0.1:1"}, MaxRetries: -1})
+	if !assert.NoError(t, err) {
+		return
+	}
+	failover, err := NewFailover(&redis.FailoverOptions{MasterName: "master", SentinelAddrs: []string{"127.0.0.1:1"}, MaxRetries: -1})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ctx := context.Background()
+	for _, c := range []*Cache{cluster, failover} {
+		assert.Error(t, c.Set(ctx, "key", "value", time.Minute))
+		assert.NoError(t, c.Close())
+	}
+}
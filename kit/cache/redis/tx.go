@@ -0,0 +1,135 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/cache"
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisTx defines the operations available within a transaction, see Cache.Tx.
+//
+// Reads are executed immediately, writes are queued and executed atomically (MULTI/EXEC)
+// once the transaction function returns.
+type RedisTx interface {
+	// Get gets the current value of the key and unmarshall it to the given value.
+	// If the key doesn't exist, cache.ErrNotFound will be returned.
+	Get(ctx context.Context, key string, value interface{}) error
+
+	// Set queues the set of the key with the given value and duration TTL.
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+
+	// Delete queues the deletion of the key.
+	Delete(ctx context.Context, key string) error
+
+	// Incr queues the increment of the integer value of the key by one.
+	// Counters are stored as redis integers, they cannot be read with Get.
+	Incr(ctx context.Context, key string) error
+}
+
+// Tx runs fn within a transaction: the writes queued by fn are executed atomically once it returns successfully,
+// nothing is written if fn returns an error.
+//
+// The given keys are watched (optimistic locking) from the beginning of the transaction,
+// if any of them is modified before the writes are executed, the transaction is aborted
+// and cache.ErrTxAborted is returned; it is up to the caller to retry.
+func (c *Cache) Tx(ctx context.Context, fn func(tx RedisTx) error, watch ...string) error {
+	if c.closed.Load() {
+		return cache.ErrClosed
+	}
+
+	err := c.client.Watch(ctx, func(rtx *redis.Tx) error {
+		t := &redisTx{cache: c, tx: rtx}
+		if err := fn(t); err != nil {
+			return err
+		}
+		if len(t.ops) == 0 {
+			return nil
+		}
+
+		_, err := rtx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, op := range t.ops {
+				op(pipe)
+			}
+			return nil
+		})
+		return err
+	}, watch...)
+
+	if errors.Is(err, redis.TxFailedErr) {
+		return cache.ErrTxAborted
+	}
+	return err
+}
+
+// redisTx implements RedisTx on top of a watched redis connection.
+type redisTx struct {
+	cache *Cache
+	tx    *redis.Tx
+	ops   []func(pipe redis.Pipeliner)
+}
+
+func (t *redisTx) Get(ctx context.Context, key string, value interface{}) error {
+	if len(key) == 0 {
+		return cache.ErrKeyInvalid
+	}
+
+	b, err := t.tx.Get(ctx, key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return cache.ErrNotFound
+		}
+		return errors.Wrapf(err, "getting value of key '%s'", key)
+	}
+
+	if err := t.cache.unmarshal(b, value); err != nil {
+		t.cache.codecError(ctx, "unmarshal", key, err)
+		return errors.Wrapf(err, "unmarshal value of key '%s'", key)
+	}
+	return nil
+}
+
+func (t *redisTx) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	if len(key) == 0 {
+		return cache.ErrKeyInvalid
+	}
+
+	if value == nil {
+		return cache.ErrValueInvalid
+	}
+
+	b, err := t.cache.marshal(value)
+	if err != nil {
+		t.cache.codecError(ctx, "marshal", key, err)
+		return errors.Wrapf(err, "marshalling value for key '%s'", key)
+	}
+
+	t.ops = append(t.ops, func(pipe redis.Pipeliner) {
+		pipe.Set(ctx, key, b, expiration)
+	})
+	return nil
+}
+
+func (t *redisTx) Delete(ctx context.Context, key string) error {
+	if len(key) == 0 {
+		return cache.ErrKeyInvalid
+	}
+
+	t.ops = append(t.ops, func(pipe redis.Pipeliner) {
+		pipe.Del(ctx, key)
+	})
+	return nil
+}
+
+func (t *redisTx) Incr(ctx context.Context, key string) error {
+	if len(key) == 0 {
+		return cache.ErrKeyInvalid
+	}
+
+	t.ops = append(t.ops, func(pipe redis.Pipeliner) {
+		pipe.Incr(ctx, key)
+	})
+	return nil
+}
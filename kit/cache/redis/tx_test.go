@@ -0,0 +1,75 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anthonycorbacho/workspace/kit/cache"
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/id"
+)
+
+func (r *redisTestSuite) TestTx() {
+	ctx := context.TODO()
+	key := fmt.Sprintf("key_%s", id.New())
+	counter := fmt.Sprintf("counter_%s", id.New())
+	defer r.cache.Delete(ctx, key)
+	defer r.cache.Delete(ctx, counter)
+	r.Require().NoError(r.cache.Set(ctx, key, 41, 0))
+
+	// read-modify-write
+	err := r.cache.Tx(ctx, func(tx RedisTx) error {
+		var value int
+		if err := tx.Get(ctx, key, &value); err != nil {
+			return err
+		}
+		if err := tx.Incr(ctx, counter); err != nil {
+			return err
+		}
+		return tx.Set(ctx, key, value+1, 0)
+	}, key)
+	r.Require().NoError(err)
+
+	var value int
+	r.Require().NoError(r.cache.Get(ctx, key, &value))
+	r.Equal(42, value)
+	n, err := r.cache.client.Get(ctx, counter).Int()
+	r.Require().NoError(err)
+	r.Equal(1, n)
+
+	// nothing is written when the function fails.
+	err = r.cache.Tx(ctx, func(tx RedisTx) error {
+		if err := tx.Delete(ctx, key); err != nil {
+			return err
+		}
+		return errors.New("failure")
+	}, key)
+	r.Error(err)
+	r.Require().NoError(r.cache.Get(ctx, key, &value))
+	r.Equal(42, value)
+}
+
+func (r *redisTestSuite) TestTx_WatchAborted() {
+	ctx := context.TODO()
+	key := fmt.Sprintf("key_%s", id.New())
+	defer r.cache.Delete(ctx, key)
+	r.Require().NoError(r.cache.Set(ctx, key, 1, 0))
+
+	err := r.cache.Tx(ctx, func(tx RedisTx) error {
+		var value int
+		if err := tx.Get(ctx, key, &value); err != nil {
+			return err
+		}
+		// concurrent modification of the watched key on another connection.
+		if err := r.cache.Set(ctx, key, 100, 0); err != nil {
+			return err
+		}
+		return tx.Set(ctx, key, value+1, 0)
+	}, key)
+	r.ErrorIs(err, cache.ErrTxAborted)
+
+	// the concurrent write wins.
+	var value int
+	r.Require().NoError(r.cache.Get(ctx, key, &value))
+	r.Equal(100, value)
+}
@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// warmOptions provides a set of configurable options for warming a cache.
+type warmOptions struct {
+	concurrency   int
+	progressEvery int64
+	logger        *log.Logger
+}
+
+// WarmOption defines a Warm option.
+type WarmOption func(*warmOptions)
+
+// WithWarmConcurrency defines the maximum number of items set concurrently, 8 by default.
+func WithWarmConcurrency(n int) WarmOption {
+	return func(o *warmOptions) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// WithWarmProgress defines the number of items set between two progress logs, 1000 by default.
+func WithWarmProgress(every int) WarmOption {
+	return func(o *warmOptions) {
+		if every > 0 {
+			o.progressEvery = int64(every)
+		}
+	}
+}
+
+// WithWarmLogger defines the logger reporting the progress of the warming.
+// By default, the global logger is used.
+func WithWarmLogger(logger *log.Logger) WarmOption {
+	return func(o *warmOptions) {
+		o.logger = logger
+	}
+}
+
+// Warm preloads the items in the cache with the same duration TTL, eg: on startup to avoid cold cache latencies.
+// See WarmStream.
+func Warm(ctx context.Context, c Cache, items map[string]interface{}, ttl time.Duration, opts ...WarmOption) error {
+	return WarmStream(ctx, c, ttl, func(ctx context.Context, emit func(key string, value interface{}) error) error {
+		for key, value := range items {
+			if err := emit(key, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, opts...)
+}
+
+// WarmStream preloads the items pulled from source in the cache with the same duration TTL,
+// eg: rows read from a database without loading them all in memory.
+//
+// source calls emit for each item, emit blocks while the items are being set with bounded concurrency
+// (see WithWarmConcurrency) and returns the context error once ctx is done: source should then stop.
+// The progress is logged (see WithWarmProgress and WithWarmLogger).
+//
+// Items failing to be set don't stop the warming, their errors are returned once done.
+// If ctx is done, the warming stops early and the context error is returned.
+func WarmStream(ctx context.Context, c Cache, ttl time.Duration, source func(ctx context.Context, emit func(key string, value interface{}) error) error, opts ...WarmOption) error {
+	o := &warmOptions{concurrency: 8, progressEvery: 1000, logger: log.L()}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	ctx, span := otel.Tracer("kit/cache").Start(ctx, "cache.Warm")
+	defer span.End()
+
+	type item struct {
+		key   string
+		value interface{}
+	}
+	items := make(chan item)
+
+	var (
+		warmed atomic.Int64
+		mu     sync.Mutex
+		errs   []error
+		wg     sync.WaitGroup
+	)
+	for i := 0; i < o.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for it := range items {
+				if err := c.Set(ctx, it.key, it.value, ttl); err != nil {
+					mu.Lock()
+					errs = append(errs, errors.Wrapf(err, "warm key '%s'", it.key))
+					mu.Unlock()
+					continue
+				}
+				if n := warmed.Add(1); n%o.progressEvery == 0 {
+					o.logger.Info(ctx, "cache warming", log.Int64("warmed", n))
+				}
+			}
+		}()
+	}
+
+	emit := func(key string, value interface{}) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		select {
+		case items <- item{key: key, value: value}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	sourceErr := source(ctx, emit)
+	close(items)
+	wg.Wait()
+
+	span.SetAttributes(attribute.Int64("warmed", warmed.Load()), attribute.Int("failed", len(errs)))
+	o.logger.Info(ctx, "cache warmed", log.Int64("warmed", warmed.Load()), log.Int("failed", len(errs)))
+
+	var err error
+	switch {
+	case ctx.Err() != nil:
+		err = ctx.Err()
+	case sourceErr != nil:
+		err = errors.Wrap(sourceErr, "warm source")
+	default:
+		err = errors.Join(errs...)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
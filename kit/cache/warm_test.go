@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// setCache records the items set, the other Cache methods are not implemented.
+type setCache struct {
+	Cache
+	mu    sync.Mutex
+	items map[string]interface{}
+	ttls  map[string]time.Duration
+	// fail fails the Set of the key if it returns an error.
+	fail func(key string) error
+}
+
+func newSetCache() *setCache {
+	return &setCache{items: map[string]interface{}{}, ttls: map[string]time.Duration{}}
+}
+
+func (c *setCache) Set(_ context.Context, key string, value interface{}, expiration time.Duration) error {
+	if c.fail != nil {
+		if err := c.fail(key); err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items[key] = value
+	c.ttls[key] = expiration
+	return nil
+}
+
+func TestWarm(t *testing.T) {
+	c := newSetCache()
+	items := map[string]interface{}{}
+	for i := 0; i < 100; i++ {
+		items[fmt.Sprintf("key_%d", i)] = i
+	}
+
+	path := filepath.Join(t.TempDir(), "log")
+	logger, err := log.New(log.WithOutputPaths(path))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	err = Warm(context.Background(), c, items, time.Minute, WithWarmConcurrency(4), WithWarmProgress(50), WithWarmLogger(logger))
+	assert.NoError(t, err)
+	assert.Equal(t, items, c.items)
+	for key := range items {
+		assert.Equal(t, time.Minute, c.ttls[key])
+	}
+
+	// the progress is logged
+	logger.Close()
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"warmed":50`)
+	assert.Contains(t, string(b), `"cache warmed"`)
+}
+
+func TestWarm_Failures(t *testing.T) {
+	c := newSetCache()
+	errUnavailable := errors.New("unavailable")
+	c.fail = func(key string) error {
+		if key == "bad" {
+			return errUnavailable
+		}
+		return nil
+	}
+
+	// the other items are still warmed.
+	err := Warm(context.Background(), c, map[string]interface{}{"good": 1, "bad": 2}, 0, WithWarmLogger(log.NewNop()))
+	assert.True(t, errors.Is(err, errUnavailable))
+	assert.Equal(t, map[string]interface{}{"good": 1}, c.items)
+}
+
+func TestWarmStream_Cancel(t *testing.T) {
+	c := newSetCache()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// an endless source, stopped by the cancellation.
+	emitted := 0
+	err := WarmStream(ctx, c, 0, func(ctx context.Context, emit func(key string, value interface{}) error) error {
+		for {
+			if emitted == 10 {
+				cancel()
+			}
+			if err := emit(fmt.Sprintf("key_%d", emitted), emitted); err != nil {
+				return err
+			}
+			emitted++
+		}
+	}, WithWarmConcurrency(1), WithWarmLogger(log.NewNop()))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.LessOrEqual(t, len(c.items), emitted)
+	assert.Equal(t, 10, emitted)
+}
+
+func TestWarmStream_SourceError(t *testing.T) {
+	c := newSetCache()
+	errSource := errors.New("database unavailable")
+	err := WarmStream(context.Background(), c, 0, func(ctx context.Context, emit func(key string, value interface{}) error) error {
+		if err := emit("key", 1); err != nil {
+			return err
+		}
+		return errSource
+	}, WithWarmLogger(log.NewNop()))
+	assert.True(t, errors.Is(err, errSource))
+	assert.Equal(t, map[string]interface{}{"key": 1}, c.items)
+}
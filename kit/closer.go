@@ -0,0 +1,72 @@
+package kit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/log"
+)
+
+// CloserGroup closes a set of resources (logger, database, cache, pubsub, tracer...) in a single teardown path.
+//
+//	closers := kit.NewCloserGroup(logger)
+//	closers.Add("database", func(context.Context) error { return db.Close() })
+//	closers.Add("cache", cache.CloseWithContext)
+//	defer closers.Close(context.Background())
+type CloserGroup struct {
+	logger  *log.Logger
+	mu      sync.Mutex
+	closers []namedCloser
+}
+
+type namedCloser struct {
+	name    string
+	closeFn func(ctx context.Context) error
+}
+
+// NewCloserGroup creates a new CloserGroup logging the closed resources with the given logger.
+func NewCloserGroup(logger *log.Logger) *CloserGroup {
+	if logger == nil {
+		logger = log.NewNop()
+	}
+	return &CloserGroup{logger: logger}
+}
+
+// Add adds a resource to close.
+func (g *CloserGroup) Add(name string, closeFn func(ctx context.Context) error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.closers = append(g.closers, namedCloser{name: name, closeFn: closeFn})
+}
+
+// Len returns the number of resources to close.
+func (g *CloserGroup) Len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.closers)
+}
+
+// Close closes the resources in the reverse order they have been added (last added, first closed).
+// A failing resource does not prevent the others from being closed, the errors are joined.
+// The resources are closed once, subsequent calls close the resources added since.
+func (g *CloserGroup) Close(ctx context.Context) error {
+	g.mu.Lock()
+	closers := g.closers
+	g.closers = nil
+	g.mu.Unlock()
+
+	var errs []error
+	for i := len(closers) - 1; i >= 0; i-- {
+		c := closers[i]
+		start := time.Now()
+		if err := c.closeFn(ctx); err != nil {
+			g.logger.Error(ctx, "fail closing resource", log.String("resource", c.name), log.Error(err))
+			errs = append(errs, errors.Wrapf(err, "close %s", c.name))
+			continue
+		}
+		g.logger.Info(ctx, "resource closed", log.String("resource", c.name), log.Duration("duration", time.Since(start)))
+	}
+	return errors.Join(errs...)
+}
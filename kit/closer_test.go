@@ -0,0 +1,76 @@
+package kit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloserGroup(t *testing.T) {
+	g := NewCloserGroup(nil)
+	var order []string
+	closer := func(name string, err error) func(context.Context) error {
+		return func(context.Context) error {
+			order = append(order, name)
+			return err
+		}
+	}
+	errDB := errors.New("db failure")
+	errCache := errors.New("cache failure")
+	g.Add("logger", closer("logger", nil))
+	g.Add("db", closer("db", errDB))
+	g.Add("cache", closer("cache", errCache))
+	g.Add("pubsub", closer("pubsub", nil))
+
+	err := g.Close(context.Background())
+
+	// closed in LIFO order, failures do not stop the others.
+	assert.Equal(t, []string{"pubsub", "cache", "db", "logger"}, order)
+	assert.ErrorIs(t, err, errDB)
+	assert.ErrorIs(t, err, errCache)
+	assert.Contains(t, err.Error(), "close db")
+
+	// resources are closed once.
+	order = nil
+	assert.NoError(t, g.Close(context.Background()))
+	assert.Empty(t, order)
+}
+
+func TestFoundationClosesResources(t *testing.T) {
+	f, err := NewFoundation("test", WithGrpcAddr(freeAddr(t)), AllowEmpty(), withoutTelemetry())
+	if !assert.NoError(t, err) {
+		return
+	}
+	sub := &fakeSubscriber{}
+	f.RegisterSubscriber(sub)
+
+	var order []string
+	f.RegisterCloser("db", func(context.Context) error {
+		order = append(order, "db")
+		return nil
+	})
+	f.RegisterCloser("cache", func(context.Context) error {
+		// subscribers are closed before the resources.
+		assert.Equal(t, int32(1), sub.closed.Load())
+		order = append(order, "cache")
+		return nil
+	})
+
+	served := make(chan error, 1)
+	go func() {
+		served <- f.Serve()
+	}()
+	time.Sleep(100 * time.Millisecond)
+	f.Shutdown()
+
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		assert.Fail(t, "timeout waiting for foundation to stop")
+	}
+	assert.Equal(t, []string{"cache", "db"}, order)
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// flagEnvPrefix is the prefix of the env variables defining flags.
+const flagEnvPrefix = "FLAG_"
+
+// Flags is a concurrent-safe store of feature flags.
+//
+// Flags are loaded from the `flags` section of a YAML file and from the env variables
+// prefixed with FLAG_, env variables take precedence over the YAML values.
+// Flag names are case-insensitive, the env variable FLAG_NEW_CHECKOUT defines the flag new_checkout.
+//
+//	flags:
+//	  new_checkout: true
+//	  checkout_ratio: 10
+type Flags struct {
+	path   string
+	values map[string]string
+	mu     sync.RWMutex
+}
+
+// FlagsFrom loads the flags from the YAML file at the given path and from the env variables.
+// If path is empty, only the env variables are loaded.
+func FlagsFrom(path string) (*Flags, error) {
+	f := &Flags{path: path}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// FlagsFromConfigMap loads the flags from the configmap `/etc/app/config.yaml` and from the env variables.
+// If the configmap is not mounted, only the env variables are loaded.
+func FlagsFromConfigMap() (*Flags, error) {
+	path := "/etc/app/config.yaml"
+	if _, err := os.Stat(path); err != nil {
+		path = ""
+	}
+	return FlagsFrom(path)
+}
+
+// Reload reloads the flags from their sources, it can be called when the configuration changes.
+// In case of error, the current flags are kept.
+func (f *Flags) Reload() error {
+	values := map[string]string{}
+
+	if len(f.path) > 0 {
+		b, err := os.ReadFile(f.path)
+		if err != nil {
+			return err
+		}
+
+		c := struct {
+			Flags map[string]interface{} `yaml:"flags"`
+		}{}
+		// an empty file has no flags: io.EOF leaves the defaults in place.
+		if err := yaml.NewDecoder(bytes.NewBuffer(b)).Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		for k, v := range c.Flags {
+			values[strings.ToLower(k)] = fmt.Sprint(v)
+		}
+	}
+
+	for _, env := range os.Environ() {
+		k, v, ok := strings.Cut(env, "=")
+		if !ok || !strings.HasPrefix(k, flagEnvPrefix) {
+			continue
+		}
+		values[strings.ToLower(strings.TrimPrefix(k, flagEnvPrefix))] = v
+	}
+
+	f.mu.Lock()
+	f.values = values
+	f.mu.Unlock()
+	return nil
+}
+
+// Bool returns the value of the flag as a bool.
+// defaultValue is returned if the flag is not defined or is not a bool.
+func (f *Flags) Bool(name string, defaultValue bool) bool {
+	v, ok := f.lookup(name)
+	if !ok {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return defaultValue
+	}
+	return b
+}
+
+// String returns the value of the flag.
+// defaultValue is returned if the flag is not defined.
+func (f *Flags) String(name string, defaultValue string) string {
+	v, ok := f.lookup(name)
+	if !ok {
+		return defaultValue
+	}
+	return v
+}
+
+// Int returns the value of the flag as an int.
+// defaultValue is returned if the flag is not defined or is not an int.
+func (f *Flags) Int(name string, defaultValue int) int {
+	v, ok := f.lookup(name)
+	if !ok {
+		return defaultValue
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return defaultValue
+	}
+	return i
+}
+
+func (f *Flags) lookup(name string) (string, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	v, ok := f.values[strings.ToLower(name)]
+	return v, ok
+}
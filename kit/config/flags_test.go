@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlagsFromEnv(t *testing.T) {
+	t.Setenv("FLAG_NEW_CHECKOUT", "true")
+	t.Setenv("FLAG_CHECKOUT_RATIO", "42")
+	t.Setenv("FLAG_THEME", "dark")
+
+	f, err := FlagsFrom("")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.True(t, f.Bool("new_checkout", false))
+	assert.Equal(t, 42, f.Int("checkout_ratio", 0))
+	assert.Equal(t, "dark", f.String("theme", "light"))
+}
+
+func TestFlagsFromYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+name: myApplication
+flags:
+  new_checkout: true
+  checkout_ratio: 10
+  theme: dark
+`), 0o600)
+	if !assert.NoError(t, err) {
+		return
+	}
+	// env takes precedence over YAML
+	t.Setenv("FLAG_THEME", "blue")
+
+	f, err := FlagsFrom(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.True(t, f.Bool("new_checkout", false))
+	assert.Equal(t, 10, f.Int("checkout_ratio", 0))
+	assert.Equal(t, "blue", f.String("theme", "light"))
+}
+
+func TestFlagsFromEmptyYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, nil, 0o600)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	f, err := FlagsFrom(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, f.Bool("new_checkout", true))
+	assert.Equal(t, "light", f.String("theme", "light"))
+}
+
+func TestFlagsDefaultValues(t *testing.T) {
+	t.Setenv("FLAG_NOT_A_BOOL", "maybe")
+
+	f, err := FlagsFrom("")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.True(t, f.Bool("unknown", true))
+	assert.True(t, f.Bool("not_a_bool", true))
+	assert.Equal(t, 7, f.Int("not_a_bool", 7))
+	assert.Equal(t, "light", f.String("unknown", "light"))
+}
+
+func TestFlagsReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("flags:\n  new_checkout: false\n"), 0o600)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	f, err := FlagsFrom(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.False(t, f.Bool("new_checkout", true))
+	assert.Equal(t, 0, f.Int("checkout_ratio", 0))
+
+	err = os.WriteFile(path, []byte("flags:\n  new_checkout: true\n"), 0o600)
+	assert.NoError(t, err)
+	t.Setenv("FLAG_CHECKOUT_RATIO", "50")
+
+	// values are kept until reloaded
+	assert.False(t, f.Bool("new_checkout", true))
+
+	err = f.Reload()
+	assert.NoError(t, err)
+	assert.True(t, f.Bool("new_checkout", false))
+	assert.Equal(t, 50, f.Int("checkout_ratio", 0))
+}
@@ -0,0 +1,40 @@
+package dlock
+
+import "context"
+
+// DistributedLock provides named locks shared across processes.
+type DistributedLock interface {
+	// Lock acquires the lock identified by key.
+	// It blocks until the lock is acquired or the context is done.
+	Lock(ctx context.Context, key string) (Lock, error)
+}
+
+// Lock is an acquired lock.
+type Lock interface {
+	// Unlock releases the lock.
+	// Calling Unlock on an already released lock returns dlock.ErrReleased.
+	Unlock(ctx context.Context) error
+
+	// Token returns the fencing token of the lock, increasing on each acquisition of the same key.
+	// It can be passed along to downstream storage to reject the writes of stale holders,
+	// ie: holders with a token lower than the last one seen.
+	Token() int64
+}
+
+// Monitored is implemented by the locks detecting their loss while being held (eg: the connection holding it is lost).
+type Monitored interface {
+	// Context returns a context derived from ctx, cancelled once the lock is released or lost.
+	// context.Cause tells them apart: dlock.ErrReleased once released, dlock.ErrLost once lost,
+	// or the cause of ctx when the holder cancelled it.
+	Context(ctx context.Context) context.Context
+}
+
+// Marker persists markers shared across processes, eg: the completion of one-time startup tasks (see kit.RunOnce).
+// It is implemented by the distributed locks having a persistent storage.
+type Marker interface {
+	// Marked reports whether the marker identified by key is set.
+	Marked(ctx context.Context, key string) (bool, error)
+
+	// Mark sets the marker identified by key, setting an already set marker is a no-op.
+	Mark(ctx context.Context, key string) error
+}
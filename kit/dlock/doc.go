@@ -0,0 +1,16 @@
+// Package dlock contains interfaces for distributed locks,
+// used to coordinate work (eg: migrations, startup tasks) between the replicas of a service.
+//
+//	lock, err := dl.Lock(ctx, "my-task")
+//	if err != nil {
+//		return err
+//	}
+//	defer lock.Unlock(context.Background())
+//
+// The locks implementing Monitored hand out a context cancelled once the lock is lost while held,
+// context.Cause tells a lost lock (ErrLost) from a released one (ErrReleased) or a cancellation by the holder.
+//
+//	if m, ok := lock.(dlock.Monitored); ok {
+//		ctx = m.Context(ctx)
+//	}
+package dlock
@@ -0,0 +1,17 @@
+package dlock
+
+// Lock errors.
+const (
+	ErrReleased   = Error("lock already released")
+	ErrStaleToken = Error("lock token is stale")
+	ErrClosed     = Error("distributed lock is closed")
+	ErrLost       = Error("lock lost")
+)
+
+// Error represents a lock error.
+type Error string
+
+// Error returns the error message.
+func (e Error) Error() string {
+	return string(e)
+}
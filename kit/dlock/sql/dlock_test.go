@@ -0,0 +1,335 @@
+package sql
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/dlock"
+	kitsql "github.com/anthonycorbacho/workspace/kit/sql"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestDistributedLock(t *testing.T) {
+	if os.Getenv("TESTINGDB_URL") == "" {
+		t.Skip("Skipping, no testing database setup via env variable TESTINGDB_URL")
+	}
+
+	// Creating a testing DB
+	var tdb kitsql.TestingDB
+	err := tdb.Open()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer tdb.Close()
+
+	dl, err := NewDistributedLock(tdb.DSN)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ctx := context.Background()
+	lock, err := dl.Lock(ctx, "a-lock")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// the lock is held, another acquisition waits until the context is done.
+	timeoutCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	_, err = dl.Lock(timeoutCtx, "a-lock")
+	assert.Error(t, err)
+
+	// other keys are not affected
+	other, err := dl.Lock(ctx, "another-lock")
+	if assert.NoError(t, err) {
+		assert.NoError(t, other.Unlock(ctx))
+	}
+
+	// once released, the lock can be acquired again.
+	acquired := make(chan dlock.Lock)
+	go func() {
+		l, err := dl.Lock(ctx, "a-lock")
+		assert.NoError(t, err)
+		acquired <- l
+	}()
+	assert.NoError(t, lock.Unlock(ctx))
+	assert.Equal(t, dlock.ErrReleased, lock.Unlock(ctx))
+
+	select {
+	case l := <-acquired:
+		if l != nil {
+			assert.NoError(t, l.Unlock(ctx))
+		}
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "timeout waiting for lock")
+	}
+}
+
+func TestDistributedLock_Lost(t *testing.T) {
+	if os.Getenv("TESTINGDB_URL") == "" {
+		t.Skip("Skipping, no testing database setup via env variable TESTINGDB_URL")
+	}
+
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	// Creating a testing DB
+	var tdb kitsql.TestingDB
+	err := tdb.Open()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer tdb.Close()
+
+	dl, err := NewDistributedLock(tdb.DSN)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ctx := context.Background()
+	lock, err := dl.Lock(ctx, "lost-lock")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// the lock is externally released by terminating the session holding it.
+	const q = `SELECT pg_terminate_backend(pid) FROM pg_locks WHERE locktype = 'advisory' AND granted AND pid <> pg_backend_pid()`
+	_, err = tdb.DB.ExecContext(ctx, q)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Error(t, lock.Unlock(ctx))
+
+	// the loss has been recorded.
+	var rm metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(ctx, &rm))
+	var lost int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "dlock.lost" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				lost += dp.Value
+			}
+		}
+	}
+	assert.Equal(t, int64(1), lost)
+}
+
+func TestDistributedLock_Token(t *testing.T) {
+	if os.Getenv("TESTINGDB_URL") == "" {
+		t.Skip("Skipping, no testing database setup via env variable TESTINGDB_URL")
+	}
+
+	// Creating a testing DB
+	var tdb kitsql.TestingDB
+	err := tdb.Open()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer tdb.Close()
+
+	dl, err := NewDistributedLock(tdb.DSN)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ctx := context.Background()
+	first, err := dl.Lock(ctx, "token-lock")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, dl.CheckToken(ctx, "token-lock", first.Token()))
+	assert.NoError(t, first.Unlock(ctx))
+
+	// the token increases on each acquisition.
+	second, err := dl.Lock(ctx, "token-lock")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer second.Unlock(ctx)
+	assert.Greater(t, second.Token(), first.Token())
+
+	// the first holder is now stale.
+	assert.ErrorIs(t, dl.CheckToken(ctx, "token-lock", first.Token()), dlock.ErrStaleToken)
+	assert.NoError(t, dl.CheckToken(ctx, "token-lock", second.Token()))
+}
+
+func TestDistributedLock_Marker(t *testing.T) {
+	if os.Getenv("TESTINGDB_URL") == "" {
+		t.Skip("Skipping, no testing database setup via env variable TESTINGDB_URL")
+	}
+
+	// Creating a testing DB
+	var tdb kitsql.TestingDB
+	err := tdb.Open()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer tdb.Close()
+
+	dl, err := NewDistributedLock(tdb.DSN)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer dl.Close()
+
+	ctx := context.Background()
+	marked, err := dl.Marked(ctx, "a-marker")
+	assert.NoError(t, err)
+	assert.False(t, marked)
+
+	// marking twice is a no-op.
+	assert.NoError(t, dl.Mark(ctx, "a-marker"))
+	assert.NoError(t, dl.Mark(ctx, "a-marker"))
+	marked, err = dl.Marked(ctx, "a-marker")
+	assert.NoError(t, err)
+	assert.True(t, marked)
+
+	// other keys are not affected
+	marked, err = dl.Marked(ctx, "another-marker")
+	assert.NoError(t, err)
+	assert.False(t, marked)
+}
+
+func TestDistributedLock_Close(t *testing.T) {
+	if os.Getenv("TESTINGDB_URL") == "" {
+		t.Skip("Skipping, no testing database setup via env variable TESTINGDB_URL")
+	}
+
+	// Creating a testing DB
+	var tdb kitsql.TestingDB
+	err := tdb.Open()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer tdb.Close()
+
+	dl, err := NewDistributedLock(tdb.DSN)
+	if !assert.NoError(t, err) {
+		return
+	}
+	other, err := NewDistributedLock(tdb.DSN)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer other.Close()
+
+	ctx := context.Background()
+	lock, err := dl.Lock(ctx, "a-lock")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// closing releases the held locks and the connections.
+	assert.NoError(t, dl.Close())
+	assert.Zero(t, dl.db.Stats().OpenConnections)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	acquired, err := other.Lock(timeoutCtx, "a-lock")
+	if assert.NoError(t, err) {
+		assert.NoError(t, acquired.Unlock(ctx))
+	}
+
+	// the outstanding lock and the DistributedLock cannot be used anymore.
+	assert.ErrorIs(t, lock.Unlock(ctx), dlock.ErrReleased)
+	_, err = dl.Lock(ctx, "a-lock")
+	assert.ErrorIs(t, err, dlock.ErrClosed)
+	assert.ErrorIs(t, dl.Close(), dlock.ErrClosed)
+}
+
+func TestDistributedLock_Context(t *testing.T) {
+	if os.Getenv("TESTINGDB_URL") == "" {
+		t.Skip("Skipping, no testing database setup via env variable TESTINGDB_URL")
+	}
+
+	reader := sdkmetric.NewManualReader()
+	previous := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	t.Cleanup(func() { otel.SetMeterProvider(previous) })
+
+	// Creating a testing DB
+	var tdb kitsql.TestingDB
+	err := tdb.Open()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer tdb.Close()
+
+	dl, err := NewDistributedLock(tdb.DSN)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer dl.Close()
+	dl.monitorInterval = 50 * time.Millisecond
+
+	ctx := context.Background()
+	waitDone := func(ctx context.Context) bool {
+		select {
+		case <-ctx.Done():
+			return true
+		case <-time.After(2 * time.Second):
+			return assert.Fail(t, "holder context not cancelled")
+		}
+	}
+
+	// the holder cancels its own context, the lock is still held.
+	lock, err := dl.Lock(ctx, "context-lock")
+	if !assert.NoError(t, err) {
+		return
+	}
+	parent, cancel := context.WithCancel(ctx)
+	holder := lock.(dlock.Monitored).Context(parent)
+	cancel()
+	if waitDone(holder) {
+		assert.Equal(t, context.Canceled, context.Cause(holder))
+	}
+
+	// the lock is released.
+	holder = lock.(dlock.Monitored).Context(ctx)
+	assert.NoError(t, lock.Unlock(ctx))
+	if waitDone(holder) {
+		assert.Equal(t, dlock.ErrReleased, context.Cause(holder))
+	}
+
+	// the lock is lost: the session holding it is terminated, the monitor cancels the holder.
+	lock, err = dl.Lock(ctx, "context-lock")
+	if !assert.NoError(t, err) {
+		return
+	}
+	holder = lock.(dlock.Monitored).Context(ctx)
+	const q = `SELECT pg_terminate_backend(pid) FROM pg_locks WHERE locktype = 'advisory' AND granted AND pid <> pg_backend_pid()`
+	_, err = tdb.DB.ExecContext(ctx, q)
+	if !assert.NoError(t, err) {
+		return
+	}
+	if waitDone(holder) {
+		assert.Equal(t, dlock.ErrLost, context.Cause(holder))
+	}
+	assert.ErrorIs(t, lock.Unlock(ctx), dlock.ErrLost)
+
+	// the holds are recorded by cause.
+	var rm metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(ctx, &rm))
+	causes := map[string]uint64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "dlock.held.duration" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Histogram[float64]).DataPoints {
+				cause, _ := dp.Attributes.Value("cause")
+				causes[cause.AsString()] += dp.Count
+			}
+		}
+	}
+	assert.Equal(t, map[string]uint64{"released": 1, "lost": 1}, causes)
+}
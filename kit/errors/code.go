@@ -0,0 +1,55 @@
+package errors
+
+import (
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Code returns the gRPC status code of the error.
+// If err is or wraps a gRPC status (see Status), the code of the status is returned,
+// codes.OK is returned for a nil error, codes.Unknown otherwise.
+func Code(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+
+	var st interface{ GRPCStatus() *status.Status }
+	if As(err, &st) {
+		return st.GRPCStatus().Code()
+	}
+	return codes.Unknown
+}
+
+// HTTPStatus returns the HTTP status code of the error
+// following the gRPC to HTTP mapping documented on Status.
+func HTTPStatus(err error) int {
+	switch Code(err) {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.Aborted, codes.AlreadyExists:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Canceled:
+		return 499 // Client Closed Request
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		// DataLoss, Unknown, Internal
+		return http.StatusInternalServerError
+	}
+}
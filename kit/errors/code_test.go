@@ -0,0 +1,56 @@
+package errors
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCode(t *testing.T) {
+	var cases = []struct {
+		name       string
+		err        error
+		code       codes.Code
+		httpStatus int
+	}{
+		{
+			name:       "nil error",
+			err:        nil,
+			code:       codes.OK,
+			httpStatus: http.StatusOK,
+		},
+		{
+			name:       "plain error",
+			err:        New("boom"),
+			code:       codes.Unknown,
+			httpStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "status error",
+			err:        Status(codes.NotFound, "user not found"),
+			code:       codes.NotFound,
+			httpStatus: http.StatusNotFound,
+		},
+		{
+			name:       "wrapped status error",
+			err:        Wrapf(Wrap(Status(codes.ResourceExhausted, "slow down"), "calling api"), "user %s", "42"),
+			code:       codes.ResourceExhausted,
+			httpStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:       "cancelled status error",
+			err:        Status(codes.Canceled, "cancelled"),
+			code:       codes.Canceled,
+			httpStatus: 499,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.code, Code(tc.err))
+			assert.Equal(t, tc.httpStatus, HTTPStatus(tc.err))
+		})
+	}
+}
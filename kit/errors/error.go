@@ -81,3 +81,9 @@ func As(err error, target interface{}) bool {
 	// call to the standard errors so we keep the errors to the same package.
 	return stdErrors.As(err, target)
 }
+
+// Join returns an error that wraps the given errors, nil errors are discarded.
+// Join returns nil if every value in errs is nil.
+func Join(errs ...error) error {
+	return stdErrors.Join(errs...)
+}
@@ -0,0 +1,52 @@
+package errors
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anthonycorbacho/workspace/api/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrNotFound is matched by the errors created with NotFound, eg:
+//
+//	if errors.Is(err, errors.ErrNotFound) { ... }
+var ErrNotFound = New("not found")
+
+// notFoundError is a missing resource, it is a NotFound status.
+type notFoundError struct {
+	resource string
+	id       string
+}
+
+func (e *notFoundError) Error() string {
+	return fmt.Sprintf("%s '%s' not found", e.resource, e.id)
+}
+
+func (e *notFoundError) Is(target error) bool { return target == ErrNotFound }
+
+// GRPCStatus returns the NotFound status of the error, it carries an ErrorInfo detail
+// with the reason <RESOURCE>_NOT_FOUND and the resource and id as metadata.
+func (e *notFoundError) GRPCStatus() *status.Status {
+	st, err := status.New(codes.NotFound, e.Error()).WithDetails(&errdetails.ErrorInfo{
+		Reason: strings.ToUpper(strings.ReplaceAll(e.resource, " ", "_")) + "_NOT_FOUND",
+		Metadata: map[string]string{
+			"resource": e.resource,
+			"id":       e.id,
+		},
+	})
+	if err != nil {
+		return status.New(codes.NotFound, e.Error())
+	}
+	return st
+}
+
+// NotFound returns an error telling the resource with the given id does not exist (eg: NotFound("user", "42")),
+// to be returned by the storage layers.
+//
+// The error, and the errors wrapping it, match ErrNotFound and are mapped to the NotFound code by Code (404 by HTTPStatus):
+// a gRPC handler can return them as is.
+func NotFound(resource, id string) error {
+	return &notFoundError{resource: resource, id: id}
+}
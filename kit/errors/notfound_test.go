@@ -0,0 +1,34 @@
+package errors
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/anthonycorbacho/workspace/api/errdetails"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNotFound(t *testing.T) {
+	err := Wrap(NotFound("user", "42"), "user.fetch")
+
+	assert.True(t, Is(err, ErrNotFound))
+	assert.False(t, Is(New("boom"), ErrNotFound))
+	assert.Equal(t, "user.fetch: user '42' not found", err.Error())
+	assert.Equal(t, codes.NotFound, Code(err))
+	assert.Equal(t, http.StatusNotFound, HTTPStatus(err))
+
+	// the status sent by a gRPC server carries the resource and the id.
+	st := status.Convert(err)
+	assert.Equal(t, codes.NotFound, st.Code())
+	if !assert.Len(t, st.Details(), 1) {
+		return
+	}
+	info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, "USER_NOT_FOUND", info.Reason)
+	assert.Equal(t, map[string]string{"resource": "user", "id": "42"}, info.Metadata)
+}
@@ -0,0 +1,33 @@
+package errors
+
+// retryableError marks an error as being transient, the operation can be retried.
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+
+func (e *retryableError) Unwrap() error { return e.err }
+
+func (e *retryableError) Retryable() bool { return true }
+
+// Retryable marks err as retryable: the failure is transient and the operation
+// can be retried (eg: a timeout or a dependency temporarily unavailable).
+// If err is nil, Retryable returns nil.
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &retryableError{err: err}
+}
+
+// IsRetryable reports whether any error in err's chain has been marked as retryable,
+// either with Retryable or by implementing a method Retryable() bool returning true.
+func IsRetryable(err error) bool {
+	var r interface{ Retryable() bool }
+	if As(err, &r) {
+		return r.Retryable()
+	}
+	return false
+}
@@ -0,0 +1,23 @@
+package errors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryable(t *testing.T) {
+	base := New("connection reset")
+
+	assert.Nil(t, Retryable(nil))
+	assert.False(t, IsRetryable(nil))
+	assert.False(t, IsRetryable(base))
+
+	err := Retryable(base)
+	assert.True(t, IsRetryable(err))
+	assert.True(t, Is(err, base))
+	assert.Equal(t, "connection reset", err.Error())
+
+	// marked errors stay retryable once wrapped.
+	assert.True(t, IsRetryable(Wrap(err, "publish")))
+}
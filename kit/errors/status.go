@@ -2,11 +2,14 @@ package errors
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/anthonycorbacho/workspace/api/errdetails"
 	"github.com/golang/protobuf/proto" //nolint - required by st.WithDetails
+	rpcerrdetails "google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 // Status represents an RPC status code, message, and details. It is immutable.
@@ -34,15 +37,11 @@ import (
 // | 504  | DEADLINE_EXCEEDED   | Request deadline exceeded. This will happen only if the caller sets a deadline that is shorter than the method's default deadline.                                   |
 //
 // details provide details error information appended to the status. It is optional but always good to add detail when applicable.
-func Status(code codes.Code, message string, details ...*errdetails.ErrorInfo) error {
+// Any detail message can be used, eg: *errdetails.ErrorInfo, or the standard details from google.golang.org/genproto/googleapis/rpc/errdetails.
+func Status(code codes.Code, message string, details ...proto.Message) error {
 	st := status.New(code, message)
 
-	dd := make([]proto.Message, 0, len(details))
-	for _, d := range details {
-		dd = append(dd, d)
-	}
-
-	st, err := st.WithDetails(dd...)
+	st, err := st.WithDetails(details...)
 	if err != nil {
 		// If this errored, it will always error here, better panic,
 		// so we can figure out why than have this silently passing.
@@ -51,3 +50,77 @@ func Status(code codes.Code, message string, details ...*errdetails.ErrorInfo) e
 
 	return st.Err()
 }
+
+// StatusWithErrorInfo represents an RPC status like Status, with ErrorInfo details only.
+// It keeps the former signature of Status for the callers spreading a []*errdetails.ErrorInfo.
+func StatusWithErrorInfo(code codes.Code, message string, details ...*errdetails.ErrorInfo) error {
+	dd := make([]proto.Message, 0, len(details))
+	for _, d := range details {
+		dd = append(dd, d)
+	}
+	return Status(code, message, dd...)
+}
+
+// StatusWithRetry represents an RPC status like Status, with a RetryInfo detail telling
+// the client to wait retryAfter before retrying the request (eg: rate limiting).
+func StatusWithRetry(code codes.Code, message string, retryAfter time.Duration, details ...proto.Message) error {
+	dd := make([]proto.Message, 0, len(details)+1)
+	dd = append(dd, &rpcerrdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	dd = append(dd, details...)
+	return Status(code, message, dd...)
+}
+
+// QuotaFailure returns a QuotaFailure detail describing the quota check that failed for the given subject.
+func QuotaFailure(subject, description string) *rpcerrdetails.QuotaFailure {
+	return &rpcerrdetails.QuotaFailure{
+		Violations: []*rpcerrdetails.QuotaFailure_Violation{
+			{Subject: subject, Description: description},
+		},
+	}
+}
+
+// RetryAfter returns the retry delay of the RetryInfo detail of an error created by StatusWithRetry.
+// It returns false if the error does not carry a RetryInfo detail.
+func RetryAfter(err error) (time.Duration, bool) {
+	var st interface{ GRPCStatus() *status.Status }
+	if !As(err, &st) {
+		return 0, false
+	}
+	for _, d := range st.GRPCStatus().Details() {
+		if ri, ok := d.(*rpcerrdetails.RetryInfo); ok {
+			return ri.GetRetryDelay().AsDuration(), true
+		}
+	}
+	return 0, false
+}
+
+// statusError annotates an error carrying a gRPC status with a message, keeping the status as is.
+type statusError struct {
+	err error
+	msg string
+	st  *status.Status
+}
+
+func (e *statusError) Error() string { return e.msg + ": " + e.err.Error() }
+
+func (e *statusError) Unwrap() error { return e.err }
+
+func (e *statusError) GRPCStatus() *status.Status { return e.st }
+
+// WrapStatus returns an error annotating err with the supplied message, like Wrap,
+// while the gRPC status of err (see Status) is kept intact: the clients get its code, message and details,
+// the annotated message is only part of Error (eg: in the logs).
+//
+// Unlike Wrap, the status message is not replaced by the annotated message once converted by gRPC (see status.FromError).
+// If err does not carry a gRPC status, WrapStatus behaves like Wrap. If err is nil, WrapStatus returns nil.
+func WrapStatus(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+
+	var st interface{ GRPCStatus() *status.Status }
+	if !As(err, &st) {
+		return Wrap(err, message)
+	}
+	return &statusError{err: err, msg: message, st: st.GRPCStatus()}
+}
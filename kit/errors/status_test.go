@@ -2,11 +2,15 @@ package errors
 
 import (
 	"testing"
+	"time"
 
 	"github.com/anthonycorbacho/workspace/api/errdetails"
 	"github.com/stretchr/testify/assert"
+	rpcerrdetails "google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestStatus(t *testing.T) {
@@ -51,3 +55,90 @@ func TestStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestStatusWithErrorInfo(t *testing.T) {
+	details := []*errdetails.ErrorInfo{
+		{Reason: "MISSING_ARGUMENT"},
+		{Reason: "INVALID_FORMAT"},
+	}
+	err := StatusWithErrorInfo(codes.InvalidArgument, "Bad argument", details...)
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, "Bad argument", st.Message())
+	if assert.Len(t, st.Details(), 2) {
+		for i, d := range st.Details() {
+			info, ok := d.(*errdetails.ErrorInfo)
+			if assert.True(t, ok) {
+				assert.Equal(t, details[i].Reason, info.Reason)
+			}
+		}
+	}
+}
+
+func TestStatusWithRetry(t *testing.T) {
+	err := StatusWithRetry(codes.ResourceExhausted, "slow down", 30*time.Second,
+		QuotaFailure("user:42", "daily quota exceeded"),
+		&errdetails.ErrorInfo{Reason: "RATE_LIMITED"},
+	)
+	assert.Equal(t, codes.ResourceExhausted, Code(err))
+
+	// Simulate the status going over the wire.
+	b, marshalErr := proto.Marshal(status.Convert(err).Proto())
+	if !assert.NoError(t, marshalErr) {
+		return
+	}
+	var sp spb.Status
+	if !assert.NoError(t, proto.Unmarshal(b, &sp)) {
+		return
+	}
+	received := status.FromProto(&sp).Err()
+
+	retryAfter, ok := RetryAfter(Wrap(received, "calling service"))
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, retryAfter)
+
+	details := status.Convert(received).Details()
+	if !assert.Len(t, details, 3) {
+		return
+	}
+	qf, ok := details[1].(*rpcerrdetails.QuotaFailure)
+	assert.True(t, ok)
+	assert.Equal(t, "user:42", qf.GetViolations()[0].GetSubject())
+	_, ok = details[2].(*errdetails.ErrorInfo)
+	assert.True(t, ok)
+
+	_, ok = RetryAfter(Status(codes.Internal, "boom"))
+	assert.False(t, ok)
+	_, ok = RetryAfter(New("boom"))
+	assert.False(t, ok)
+}
+
+func TestWrapStatus(t *testing.T) {
+	err := Status(codes.NotFound, "user not found", &errdetails.ErrorInfo{Reason: "USER_NOT_FOUND"})
+
+	wrapped := WrapStatus(WrapStatus(err, "loading user 42"), "get profile")
+	assert.Equal(t, "get profile: loading user 42: rpc error: code = NotFound desc = user not found", wrapped.Error())
+	assert.Equal(t, codes.NotFound, Code(wrapped))
+	assert.True(t, Is(wrapped, err))
+
+	// the clients get the original status.
+	st, ok := status.FromError(wrapped)
+	assert.True(t, ok)
+	assert.Equal(t, codes.NotFound, st.Code())
+	assert.Equal(t, "user not found", st.Message())
+	if assert.Len(t, st.Details(), 1) {
+		_, ok = st.Details()[0].(*errdetails.ErrorInfo)
+		assert.True(t, ok)
+	}
+
+	// a status wrapped by Wrap keeps its code only.
+	assert.Equal(t, codes.NotFound, Code(Wrap(err, "loading user 42")))
+	assert.Equal(t, "loading user 42: rpc error: code = NotFound desc = user not found", status.Convert(Wrap(err, "loading user 42")).Message())
+
+	plain := WrapStatus(New("boom"), "loading user 42")
+	assert.Equal(t, "loading user 42: boom", plain.Error())
+	assert.Equal(t, codes.Unknown, Code(plain))
+
+	assert.Nil(t, WrapStatus(nil, "loading user 42"))
+}
@@ -0,0 +1,20 @@
+// Package eventbus provides typed topics on top of the pubsub publisher and subscriber.
+//
+// A Topic knows its subject and how to encode and decode its events,
+// removing stringly-typed topics and manual marshalling across services.
+//
+//	type UserCreated struct {
+//		ID   string `json:"id"`
+//		Name string `json:"name"`
+//	}
+//
+//	var UserCreatedTopic = eventbus.NewTopic("user.created", eventbus.JSON[UserCreated]())
+//
+//	// Publishing an event
+//	err := eventbus.Publish(ctx, publisher, UserCreatedTopic, UserCreated{ID: "42", Name: "toto"})
+//
+//	// Subscribing to the topic
+//	err := eventbus.Subscribe(ctx, subscriber, UserCreatedTopic, func(ctx context.Context, event UserCreated) error {
+//		return nil
+//	})
+package eventbus
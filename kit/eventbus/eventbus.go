@@ -0,0 +1,103 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/pubsub"
+)
+
+// Codec defines how an event is encoded to and decoded from a pubsub message.
+type Codec[T any] interface {
+	Marshal(event T) ([]byte, error)
+	Unmarshal(data []byte, event *T) error
+}
+
+// JSON returns a Codec encoding events as JSON.
+func JSON[T any]() Codec[T] {
+	return jsonCodec[T]{}
+}
+
+type jsonCodec[T any] struct{}
+
+func (jsonCodec[T]) Marshal(event T) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+func (jsonCodec[T]) Unmarshal(data []byte, event *T) error {
+	return json.Unmarshal(data, event)
+}
+
+// Topic is a typed pubsub topic, it knows its subject and the codec of its events.
+type Topic[T any] struct {
+	subject string
+	codec   Codec[T]
+}
+
+// NewTopic creates a new typed topic.
+func NewTopic[T any](subject string, codec Codec[T]) Topic[T] {
+	return Topic[T]{
+		subject: subject,
+		codec:   codec,
+	}
+}
+
+// Subject returns the subject of the topic.
+func (t Topic[T]) Subject() string {
+	return t.subject
+}
+
+// Handler is the handler invoked with the decoded event.
+type Handler[T any] func(ctx context.Context, event T) error
+
+// SubscribeOption defines a Subscribe option.
+type SubscribeOption func(*subscribeOptions)
+
+type subscribeOptions struct {
+	subscription string
+}
+
+// WithSubscription defines the subscription to consume from,
+// by default the topic subject is used as subscription.
+// It is required for providers where a subscription differs from its topic (eg: GCP).
+func WithSubscription(subscription string) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.subscription = subscription
+	}
+}
+
+// Publish encodes the event with the topic codec and publishes it on the topic subject.
+func Publish[T any](ctx context.Context, publisher pubsub.Publisher, topic Topic[T], event T) error {
+	if topic.codec == nil {
+		return errors.New("topic codec is missing")
+	}
+
+	data, err := topic.codec.Marshal(event)
+	if err != nil {
+		return errors.Wrapf(err, "encoding event for topic '%s'", topic.subject)
+	}
+
+	return publisher.Publish(ctx, topic.subject, data)
+}
+
+// Subscribe subscribes to the topic and invokes handler with each decoded event.
+// A message that cannot be decoded is rejected with an error and never reaches the handler.
+func Subscribe[T any](ctx context.Context, subscriber pubsub.Subscriber, topic Topic[T], handler Handler[T], opts ...SubscribeOption) error {
+	if topic.codec == nil {
+		return errors.New("topic codec is missing")
+	}
+
+	o := &subscribeOptions{subscription: topic.subject}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return subscriber.Subscribe(ctx, o.subscription, func(ctx context.Context, msg pubsub.Message) error {
+		var event T
+		if err := topic.codec.Unmarshal(msg, &event); err != nil {
+			return errors.Wrapf(err, "decoding event from topic '%s'", topic.subject)
+		}
+		return handler(ctx, event)
+	})
+}
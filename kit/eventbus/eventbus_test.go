@@ -0,0 +1,77 @@
+package eventbus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/pubsub/inmem"
+	"github.com/stretchr/testify/assert"
+)
+
+type userCreated struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+var userCreatedTopic = NewTopic("user.created", JSON[userCreated]())
+
+func TestPublishAndSubscribe(t *testing.T) {
+	bus := inmem.New()
+	defer bus.Close()
+
+	ctx := context.Background()
+	events := make(chan userCreated, 1)
+	err := Subscribe(ctx, bus, userCreatedTopic, func(ctx context.Context, event userCreated) error {
+		events <- event
+		return nil
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	err = Publish(ctx, bus, userCreatedTopic, userCreated{ID: "42", Name: "toto"})
+	assert.NoError(t, err)
+
+	select {
+	case event := <-events:
+		assert.Equal(t, userCreated{ID: "42", Name: "toto"}, event)
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout waiting for event")
+	}
+}
+
+func TestSubscribeMalformedPayload(t *testing.T) {
+	errs := make(chan error, 1)
+	bus := inmem.New(inmem.WithErrorHandler(func(topic string, err error) {
+		errs <- err
+	}))
+	defer bus.Close()
+
+	ctx := context.Background()
+	called := make(chan struct{}, 1)
+	err := Subscribe(ctx, bus, userCreatedTopic, func(ctx context.Context, event userCreated) error {
+		called <- struct{}{}
+		return nil
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// publishing a raw payload that is not a valid event.
+	err = bus.Publish(ctx, userCreatedTopic.Subject(), []byte("not a json"))
+	assert.NoError(t, err)
+
+	select {
+	case err := <-errs:
+		assert.Contains(t, err.Error(), "decoding event from topic 'user.created'")
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout waiting for decoding error")
+	}
+
+	select {
+	case <-called:
+		assert.Fail(t, "handler should not be called with a malformed payload")
+	default:
+	}
+}
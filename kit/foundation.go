@@ -11,6 +11,7 @@ import (
 	"os/signal"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -18,28 +19,40 @@ import (
 	"github.com/anthonycorbacho/workspace/kit/errors"
 	grpckit "github.com/anthonycorbacho/workspace/kit/grpc"
 	"github.com/anthonycorbacho/workspace/kit/log"
+	"github.com/anthonycorbacho/workspace/kit/pubsub"
 	"github.com/anthonycorbacho/workspace/kit/telemetry"
+	"github.com/anthonycorbacho/workspace/kit/telemetry/metric"
 	handlers "github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	grpcprometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/rs/cors"
+	httpmetrics "github.com/slok/go-http-metrics/metrics"
 	metrics "github.com/slok/go-http-metrics/metrics/prometheus"
 	"github.com/slok/go-http-metrics/middleware"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 	"go.uber.org/automaxprocs/maxprocs"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/encoding/protojson"
 )
 
-// defaultHealthHandler provides a default health function.
-var _defaultHealthHandler = func(writer http.ResponseWriter, _ *http.Request) {
-	writer.WriteHeader(http.StatusOK)
-	writer.Header().Set("Content-Type", "text/plain")
-	fmt.Fprintln(writer, "ok") //nolint
-}
+// shutdownTimeout is the maximum time given to the servers and subscribers to terminate.
+const shutdownTimeout = 15 * time.Second
+
+// httpInFlightMetric is the name of the gauge metric tracking the in-flight HTTP requests.
+const httpInFlightMetric = "http_requests_in_flight"
+
+var (
+	httpMetrics     = metric.New()
+	httpRecorder    httpmetrics.Recorder
+	httpMetricsOnce sync.Once
+)
+
+// accessLogSkipPaths are the health and internal paths not logged by WithAccessLog.
+var accessLogSkipPaths = []string{"/healthz", "/readyz", "/metrics", "/version", "/debug/"}
 
 // Foundation provides a convenient way to build new services.
 //
@@ -66,6 +79,18 @@ type Foundation struct {
 	// Healths checks
 	livenessProbe  http.HandlerFunc
 	readinessProbe http.HandlerFunc
+	readiness      func() (string, error)
+	started        time.Time
+	// pubsub subscribers
+	subscribers []pubsub.Subscriber
+	// resources closed on shutdown
+	closers *CloserGroup
+	// in-flight requests (gRPC and HTTP)
+	inflight atomic.Int64
+	// shutdown
+	shutdown     chan os.Signal
+	draining     chan struct{}
+	drainingOnce sync.Once
 }
 
 // NewFoundation creates a new foundation service.
@@ -96,19 +121,29 @@ func NewFoundation(name string, options ...Option) (*Foundation, error) {
 		httpWriteTimeout: 15 * time.Second,
 		httpReadTimeout:  15 * time.Second,
 		logger:           log.NewNop(),
+		gatewayMarshal: protojson.MarshalOptions{
+			UseProtoNames:   true,
+			EmitUnpopulated: true,
+		},
 	}
 	for _, o := range options {
 		o(opts)
 	}
 
 	// Create the Foundation service
-	return &Foundation{
-		name:           name,
-		opts:           opts,
-		logger:         opts.logger,
-		readinessProbe: _defaultHealthHandler,
-		livenessProbe:  _defaultHealthHandler,
-	}, nil
+	f := &Foundation{
+		name:      name,
+		opts:      opts,
+		logger:    opts.logger,
+		readiness: func() (string, error) { return "ok", nil },
+		started:   time.Now(),
+		shutdown:  make(chan os.Signal, 1),
+		draining:  make(chan struct{}),
+		closers:   NewCloserGroup(opts.logger),
+	}
+	f.livenessProbe = f.healthHandler("liveness", func() (string, error) { return "ok", nil })
+	f.readinessProbe = f.healthHandler("readiness", f.ready)
+	return f, nil
 }
 
 // RegisterServiceFunc represents a function for registering a grpc service handler.
@@ -116,11 +151,26 @@ type RegisterServiceFunc func(s *grpc.Server)
 
 // RegisterService registers a grpc service handler.
 func (f *Foundation) RegisterService(fn RegisterServiceFunc) {
-	// Create GRPC server only once
+	f.initGRPCServerOnce()
+	fn(f.grpcServer)
+}
+
+// initGRPCServerOnce will initialize the gRPC server once, with the standard gRPC health service.
+func (f *Foundation) initGRPCServerOnce() {
 	f.grpcOnce.Do(func() {
-		f.grpcServer = grpckit.NewServer(f.opts.grpcServerOpts...)
+		serverOpts := append([]grpc.ServerOption{
+			grpc.ChainUnaryInterceptor(f.unaryInflightInterceptor),
+			grpc.ChainStreamInterceptor(f.streamInflightInterceptor),
+		}, f.opts.grpcServerOpts...)
+		f.grpcServer = grpckit.NewServer(serverOpts...)
+
+		// Register the standard gRPC health service, following the foundation readiness.
+		healthpb.RegisterHealthServer(f.grpcServer, &healthServer{
+			server: f.grpcServer,
+			status: f.servingStatus,
+			done:   f.draining,
+		})
 	})
-	fn(f.grpcServer)
 }
 
 // initHTTPServerOnce will initialize the HTTP server once.
@@ -138,14 +188,34 @@ func (f *Foundation) initHTTPServerOnce() {
 			return fmt.Sprintf("[%s] %s", r.Method, r.RequestURI)
 		})))
 
+		// Provide access log, once the request context carries the trace
+		if opts.accessLogger != nil {
+			r.Use(telemetry.AccessLogMiddleware(opts.accessLogger, accessLogSkipPaths...))
+		}
+
+		// The HTTP metrics are registered once per process
+		// since several foundations can live in the same process (eg: tests).
+		httpMetricsOnce.Do(func() {
+			httpRecorder = metrics.NewRecorder(metrics.Config{})
+			err := httpMetrics.Register(httpInFlightMetric, "Number of HTTP requests being served",
+				metric.Gauge(), metric.Labels("method", "route"))
+			if err != nil {
+				log.L().Warn(context.Background(), "registering http in-flight metric", log.Error(err))
+			}
+		})
+
 		// Provide Prometheus metric
 		// The metrics measured are based on RED and/or Four golden signals,
 		// follow standards and try to be measured in an efficient way.
 		r.Use(telemetry.Middleware(middleware.New(middleware.Config{
 			Service:  name,
-			Recorder: metrics.NewRecorder(metrics.Config{}),
+			Recorder: httpRecorder,
 		})))
 
+		// Provide in-flight requests gauge.
+		r.Use(telemetry.InFlightMiddleware(httpMetrics, httpInFlightMetric))
+		r.Use(f.inflightMiddleware)
+
 		r.StrictSlash(true)
 
 		// If cors is enabled, we should set it depending on the options
@@ -193,10 +263,7 @@ func (f *Foundation) RegisterServiceHandler(fn RegisterServiceHandlerFunc, muxOp
 			}),
 			runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.HTTPBodyMarshaler{
 				Marshaler: &runtime.JSONPb{
-					MarshalOptions: protojson.MarshalOptions{
-						UseProtoNames:   true,
-						EmitUnpopulated: true,
-					},
+					MarshalOptions: f.opts.gatewayMarshal,
 				},
 			}),
 			runtime.WithMetadata(func(ctx context.Context, req *http.Request) metadata.MD {
@@ -228,41 +295,73 @@ func (f *Foundation) RegisterHTTPHandler(path string, fn http.HandlerFunc, metho
 	f.httpRouter.HandleFunc(path, fn).Methods(methods...)
 }
 
+// RegisterSubscriber registers a pubsub subscriber.
+//
+// The subscriber is closed when the foundation shuts down, after the servers are stopped,
+// letting in-flight messages be handled.
+func (f *Foundation) RegisterSubscriber(sub pubsub.Subscriber) {
+	f.subscribers = append(f.subscribers, sub)
+}
+
+// RegisterCloser registers a resource (eg: database, cache) to close when the foundation shuts down,
+// once the servers and the subscribers are stopped. Resources are closed in the reverse order they are registered.
+func (f *Foundation) RegisterCloser(name string, closeFn func(ctx context.Context) error) {
+	f.closers.Add(name, closeFn)
+}
+
 // RegisterLiveness register a liveness function for /healthz
+// (see HealthStatus for the JSON body served on `Accept: application/json`).
 //
 // Many applications running for long periods of time eventually transition to broken states,
 // and cannot recover except by being restarted.
 // Kubernetes provides liveness probes to detect and remedy such situations.
 func (f *Foundation) RegisterLiveness(fn func() (string, error)) {
-	f.livenessProbe = handlerClosure(fn)
+	f.livenessProbe = f.healthHandler("liveness", fn)
 }
 
 // RegisterReadiness register a readiness function for /readyz
+// (see HealthStatus for the JSON body served on `Accept: application/json`).
 //
 // Sometimes, applications are temporarily unable to serve traffic.
 // For example, an application might need to load a large amount of data or
 // a large number of configuration files during startup.
 // In such instances, we don’t want to kill the application, but we don’t want to send it requests either.
+//
+// The readiness is also exposed via the standard gRPC health service.
 func (f *Foundation) RegisterReadiness(fn func() (string, error)) {
-	f.readinessProbe = handlerClosure(fn)
+	f.readiness = fn
 }
 
-func handlerClosure(fn func() (string, error)) http.HandlerFunc {
-	return func(writer http.ResponseWriter, request *http.Request) {
-		status := http.StatusOK
-		msg, err := fn()
-		if err != nil {
-			status = http.StatusInternalServerError
-			msg = err.Error()
-		}
-		writer.WriteHeader(status)
-		writer.Header().Set("Content-Type", "text/plain")
-		fmt.Fprintln(writer, msg)
+// ready returns the readiness of the foundation.
+// Once the foundation is shutting down, it is not ready anymore.
+func (f *Foundation) ready() (string, error) {
+	select {
+	case <-f.draining:
+		return "", errors.New("shutting down")
+	default:
+	}
+	return f.readiness()
+}
+
+// servingStatus returns the gRPC health serving status of the foundation.
+func (f *Foundation) servingStatus() healthpb.HealthCheckResponse_ServingStatus {
+	if _, err := f.ready(); err != nil {
+		return healthpb.HealthCheckResponse_NOT_SERVING
 	}
+	return healthpb.HealthCheckResponse_SERVING
 }
 
 // Serve configure and start serving request for the foundation service.
+//
+// Serve returns an error if no gRPC service or HTTP handler has been registered,
+// unless the Foundation has been created with the AllowEmpty option.
 func (f *Foundation) Serve() error {
+	if f.grpcServer == nil && f.httpServer == nil && !f.opts.allowEmpty {
+		return errors.New("no services registered: register a gRPC service or an HTTP handler before serving")
+	}
+	// the gRPC health service is served even without gRPC services.
+	f.initGRPCServerOnce()
+
 	_, err := maxprocs.Set(maxprocs.Logger(func(s string, i ...interface{}) {
 		f.logger.Info(context.Background(), fmt.Sprintf(s, i))
 	}))
@@ -271,48 +370,71 @@ func (f *Foundation) Serve() error {
 	}
 
 	// Setup telemetry
-	tracer, err := telemetry.NewTracer(f.name)
-	if err != nil {
-		return errors.Wrap(err, "creating new tracer")
-	}
-	defer func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		_ = tracer.Shutdown(ctx) //nolint
-	}()
+	if !f.opts.noTelemetry {
+		tracer, err := telemetry.NewTracer(f.name)
+		if err != nil {
+			return errors.Wrap(err, "creating new tracer")
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = tracer.Shutdown(ctx) //nolint
+		}()
 
-	_, err = telemetry.NewMeter(f.name)
-	if err != nil {
-		return errors.Wrap(err, "creating new meter")
+		_, err = telemetry.NewMeter(f.name)
+		if err != nil {
+			return errors.Wrap(err, "creating new meter")
+		}
 	}
 
 	// register health probes and profiling
 	internalHTTP(f.logger, f.readinessProbe, f.livenessProbe)
 
 	// shutdown channel to listen for an interrupt or terminate signal from the OS.
-	shutdown := make(chan os.Signal, 1)
-	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
+	signal.Notify(f.shutdown, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(f.shutdown)
 
 	// Make a channel to listen for errors coming from the listener. Use a
 	// buffered channel so the goroutine can exit if we don't collect this error.
 	serverError := make(chan error, 1)
 
-	// start the grpc server
-	go func(serverError chan error) {
-		// No GRPC server set up.
-		if f.grpcServer == nil {
-			return
+	// with a single port, the connections are split between the servers.
+	var grpcListener, httpListener net.Listener
+	if f.opts.singlePort {
+		var h2c func(conn net.Conn)
+		if f.httpServer != nil {
+			var err error
+			if h2c, err = serveH2C(f.httpServer); err != nil {
+				return errors.Wrap(err, "init single port h2c server")
+			}
+		}
+		pm, err := newPortMux(f.opts.grpcAddr, h2c)
+		if err != nil {
+			return errors.Wrap(err, "init single port listener")
 		}
+		grpcListener, httpListener = pm.grpc, pm.http
+		// a server not set up never closes its listener.
+		if f.httpServer == nil {
+			_ = pm.http.Close() //nolint
+		}
+	}
 
+	// start the grpc server
+	go func(serverError chan error) {
 		// enable grpc metrics
 		// This operation needs to be done after user register the proto to the server.
 		grpcprometheus.EnableHandlingTimeHistogram()
 		grpcprometheus.Register(f.grpcServer)
 
 		// Create listener for the grpc server
-		listen, err := net.Listen("tcp", f.opts.grpcAddr)
-		if err != nil {
-			serverError <- errors.Wrap(err, "init net listener")
+		listen := grpcListener
+		if listen == nil {
+			l, err := net.Listen("tcp", f.opts.grpcAddr)
+			if err != nil {
+				serverError <- errors.Wrap(err, "init net listener")
+				return
+			}
+			listen = l
 		}
 		serverError <- f.grpcServer.Serve(listen)
 		_ = listen.Close() //nolint
@@ -329,6 +451,10 @@ func (f *Foundation) Serve() error {
 		if f.gw != nil {
 			f.httpRouter.PathPrefix("/").Handler(f.gw)
 		}
+		if httpListener != nil {
+			serverError <- f.httpServer.Serve(httpListener)
+			return
+		}
 		serverError <- f.httpServer.ListenAndServe()
 	}(serverError)
 
@@ -337,22 +463,141 @@ func (f *Foundation) Serve() error {
 	select {
 	case err := <-serverError:
 		return errors.Wrap(err, "server error")
-	case <-shutdown:
+	case <-f.shutdown:
+		f.gracefulShutdown()
+	}
 
-		// Terminate GRPC server if started
-		if f.grpcServer != nil {
-			f.grpcServer.GracefulStop()
-		}
+	return nil
+}
 
-		// terminate the HTTP server if started.
-		if f.httpServer != nil {
-			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-			defer cancel()
-			_ = f.httpServer.Shutdown(ctx) //nolint
+// Shutdown triggers the graceful shutdown of a serving foundation, as receiving a SIGTERM would.
+func (f *Foundation) Shutdown() {
+	select {
+	case f.shutdown <- syscall.SIGTERM:
+	default:
+		// shutdown already requested.
+	}
+}
+
+// gracefulShutdown drains and stops the servers and the subscribers,
+// then logs a report summarizing the shutdown.
+func (f *Foundation) gracefulShutdown() {
+	inflight := f.inflight.Load()
+	var errs []string
+
+	// Mark the foundation as not ready (readiness probe and gRPC health),
+	// and give some time to the load balancers to stop sending traffic.
+	f.drainingOnce.Do(func() { close(f.draining) })
+	if f.opts.drainDelay > 0 {
+		time.Sleep(f.opts.drainDelay)
+	}
+
+	// Terminate GRPC server if started
+	var grpcStop time.Duration
+	if f.grpcServer != nil {
+		start := time.Now()
+		f.grpcServer.GracefulStop()
+		grpcStop = time.Since(start)
+	}
+
+	// terminate the HTTP server if started.
+	httpShutdown := "not started"
+	if f.httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		httpShutdown = "ok"
+		if err := f.httpServer.Shutdown(ctx); err != nil {
+			httpShutdown = err.Error()
+			errs = append(errs, errors.Wrap(err, "http shutdown").Error())
 		}
 	}
 
-	return nil
+	// terminate the subscribers once no more requests are served.
+	closed, err := f.closeSubscribers(shutdownTimeout)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	// close the resources once nothing uses them anymore.
+	resources := f.closers.Len()
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := f.closers.Close(ctx); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	f.logger.Info(context.Background(), "shutdown report",
+		log.String("service-name", f.name),
+		log.Int64("inflight_requests", inflight),
+		log.Duration("grpc_graceful_stop", grpcStop),
+		log.String("http_shutdown", httpShutdown),
+		log.Int("subscribers_closed", closed),
+		log.Int("subscribers", len(f.subscribers)),
+		log.Int("resources", resources),
+		log.Strings("errors", errs),
+	)
+}
+
+// closeSubscribers closes all the registered subscribers, waiting at most timeout for them to terminate.
+// It returns the number of subscribers closed successfully.
+func (f *Foundation) closeSubscribers(timeout time.Duration) (int, error) {
+	if len(f.subscribers) == 0 {
+		return 0, nil
+	}
+
+	var closed atomic.Int64
+	var wg sync.WaitGroup
+	for _, sub := range f.subscribers {
+		wg.Add(1)
+		go func(sub pubsub.Subscriber) {
+			defer wg.Done()
+			if err := sub.Close(); err != nil {
+				f.logger.Error(context.Background(), "fail closing subscriber", log.Error(err))
+				return
+			}
+			closed.Add(1)
+		}(sub)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		f.logger.Warn(context.Background(), "timeout closing subscribers", log.Duration("timeout", timeout))
+		return int(closed.Load()), errors.Newf("timeout closing subscribers after %s", timeout)
+	}
+	if n := int(closed.Load()); n != len(f.subscribers) {
+		return n, errors.Newf("%d subscribers failed to close", len(f.subscribers)-n)
+	}
+	return len(f.subscribers), nil
+}
+
+// inflightMiddleware counts the HTTP requests being served.
+func (f *Foundation) inflightMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.inflight.Add(1)
+		defer f.inflight.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// unaryInflightInterceptor counts the gRPC requests being served.
+func (f *Foundation) unaryInflightInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	f.inflight.Add(1)
+	defer f.inflight.Add(-1)
+	return handler(ctx, req)
+}
+
+// streamInflightInterceptor counts the gRPC streams being served.
+func (f *Foundation) streamInflightInterceptor(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	f.inflight.Add(1)
+	defer f.inflight.Add(-1)
+	return handler(srv, ss)
 }
 
 // internalHTTP start a new http server for health checks and profiling.
@@ -365,6 +610,12 @@ func internalHTTP(l *log.Logger, readiness http.HandlerFunc, liveliness http.Han
 	r.HandleFunc("/healthz", liveliness).Name("healthz").Methods("GET")
 	r.HandleFunc("/readyz", readiness).Name("readyz").Methods("GET")
 
+	// build information
+	r.HandleFunc("/version", versionHandler).Name("version").Methods("GET")
+
+	// runtime log level
+	r.Handle("/debug/log/level", l.LevelHandler()).Methods("GET", "PUT")
+
 	// pprof
 	r.HandleFunc("/debug/pprof/", pprof.Index)
 	r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
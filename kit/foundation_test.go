@@ -0,0 +1,447 @@
+package kit
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/log"
+	"github.com/anthonycorbacho/workspace/kit/pubsub"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestServeWithoutServices(t *testing.T) {
+	f, err := NewFoundation("test")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	err = f.Serve()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no services registered")
+}
+
+func TestGrpcHealth(t *testing.T) {
+	addr := freeAddr(t)
+	f, err := NewFoundation("test", WithGrpcAddr(addr), WithDrainDelay(2*time.Second), withoutTelemetry())
+	if !assert.NoError(t, err) {
+		return
+	}
+	f.RegisterService(func(s *grpc.Server) {})
+
+	served := make(chan error, 1)
+	go func() {
+		served <- f.Serve()
+	}()
+
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	// Serving while up
+	assert.Eventually(t, func() bool {
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		return err == nil && resp.Status == healthpb.HealthCheckResponse_SERVING
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// Unknown service
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// Not serving while draining
+	f.shutdown <- syscall.SIGTERM
+	assert.Eventually(t, func() bool {
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		return err == nil && resp.Status == healthpb.HealthCheckResponse_NOT_SERVING
+	}, time.Second, 50*time.Millisecond)
+
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		assert.Fail(t, "timeout waiting for foundation to stop")
+	}
+}
+
+func TestGrpcHealth_NoServices(t *testing.T) {
+	addr := freeAddr(t)
+	f, err := NewFoundation("test", WithGrpcAddr(addr), AllowEmpty(), withoutTelemetry())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- f.Serve()
+	}()
+
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	// Serving without any registered service
+	assert.Eventually(t, func() bool {
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		return err == nil && resp.Status == healthpb.HealthCheckResponse_SERVING
+	}, 5*time.Second, 50*time.Millisecond)
+
+	f.shutdown <- syscall.SIGTERM
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		assert.Fail(t, "timeout waiting for foundation to stop")
+	}
+}
+
+func TestShutdownClosesSubscribers(t *testing.T) {
+	f, err := NewFoundation("test", WithGrpcAddr(freeAddr(t)), AllowEmpty(), withoutTelemetry())
+	if !assert.NoError(t, err) {
+		return
+	}
+	sub := &fakeSubscriber{}
+	f.RegisterSubscriber(sub)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- f.Serve()
+	}()
+
+	f.shutdown <- syscall.SIGTERM
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		assert.Fail(t, "timeout waiting for foundation to stop")
+	}
+	assert.Equal(t, int32(1), sub.closed.Load())
+}
+
+func TestGracefulShutdownTwice(t *testing.T) {
+	f, err := NewFoundation("test", WithGrpcAddr(freeAddr(t)), AllowEmpty(), withoutTelemetry())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// draining is closed only once.
+	assert.NotPanics(t, func() {
+		f.gracefulShutdown()
+		f.gracefulShutdown()
+	})
+}
+
+func TestShutdownReport(t *testing.T) {
+	// logger writing to a file instead of stderr
+	output := filepath.Join(t.TempDir(), "log")
+	logger, err := log.New(log.WithOutputPaths(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := NewFoundation("test", WithGrpcAddr(freeAddr(t)), AllowEmpty(), WithLogger(logger), withoutTelemetry())
+	if !assert.NoError(t, err) {
+		return
+	}
+	f.RegisterSubscriber(&fakeSubscriber{})
+	f.RegisterSubscriber(&fakeSubscriber{})
+
+	served := make(chan error, 1)
+	go func() {
+		served <- f.Serve()
+	}()
+
+	f.Shutdown()
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		assert.Fail(t, "timeout waiting for foundation to stop")
+	}
+	logger.Close()
+
+	b, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(b), []byte("\n"))
+	var entry struct {
+		Body       string
+		Attributes map[string]interface{}
+	}
+	if err := json.Unmarshal(lines[len(lines)-1], &entry); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "shutdown report", entry.Body)
+	assert.Equal(t, float64(0), entry.Attributes["inflight_requests"])
+	assert.Equal(t, "not started", entry.Attributes["http_shutdown"])
+	assert.Equal(t, float64(2), entry.Attributes["subscribers_closed"])
+	assert.Contains(t, entry.Attributes, "grpc_graceful_stop")
+	assert.Empty(t, entry.Attributes["errors"])
+}
+
+// fakeSubscriber is a pubsub.Subscriber recording calls to Close.
+type fakeSubscriber struct {
+	closed atomic.Int32
+}
+
+func (s *fakeSubscriber) Subscribe(context.Context, string, pubsub.Handler) error {
+	return nil
+}
+
+func (s *fakeSubscriber) SubscribeWithAck(context.Context, string, pubsub.HandlerWithAck) error {
+	return nil
+}
+
+func (s *fakeSubscriber) SubscribeChan(context.Context, string) (<-chan pubsub.ReceivedMessage, error) {
+	return nil, nil
+}
+
+func (s *fakeSubscriber) Unsubscribe(string) error {
+	return nil
+}
+
+func (s *fakeSubscriber) Close() error {
+	s.closed.Add(1)
+	return nil
+}
+
+// freeAddr returns a free local address to listen on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestSinglePort(t *testing.T) {
+	addr := freeAddr(t)
+	f, err := NewFoundation("test", WithSinglePort(addr), withoutTelemetry())
+	if !assert.NoError(t, err) {
+		return
+	}
+	f.RegisterService(func(s *grpc.Server) {})
+	f.RegisterHTTPHandler("/hello", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}, http.MethodGet)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- f.Serve()
+	}()
+
+	// gRPC call
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+	assert.Eventually(t, func() bool {
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		return err == nil && resp.Status == healthpb.HealthCheckResponse_SERVING
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// HTTP request on the same port
+	resp, err := http.Get("http://" + addr + "/hello")
+	if assert.NoError(t, err) {
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "hello", string(body))
+	}
+
+	// h2c requests that are not gRPC are served by the HTTP handlers.
+	h2c := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+	for i := 0; i < 2; i++ {
+		resp, err = h2c.Get("http://" + addr + "/hello")
+		if assert.NoError(t, err) {
+			body, err := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			assert.NoError(t, err)
+			assert.Equal(t, 2, resp.ProtoMajor)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "hello", string(body))
+		}
+	}
+
+	f.Shutdown()
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		assert.Fail(t, "timeout waiting for foundation to stop")
+	}
+}
+
+func TestWithAccessLog(t *testing.T) {
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(previous)
+
+	path := filepath.Join(t.TempDir(), "access.log")
+	logger, err := log.New(log.WithOutputPaths(path), log.WithoutSampling())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	addr := freeAddr(t)
+	f, err := NewFoundation("test", WithHTTPAddr(addr), WithGrpcAddr(freeAddr(t)), WithAccessLog(logger), withoutTelemetry())
+	if !assert.NoError(t, err) {
+		return
+	}
+	f.RegisterHTTPHandler("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}, http.MethodGet)
+	f.RegisterHTTPHandler("/healthz", func(w http.ResponseWriter, r *http.Request) {}, http.MethodGet)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- f.Serve()
+	}()
+	defer func() {
+		f.Shutdown()
+		<-served
+	}()
+
+	get := func(path string, header http.Header) {
+		req, err := http.NewRequest(http.MethodGet, "http://"+addr+path, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		req.Header = header
+		var resp *http.Response
+		assert.Eventually(t, func() bool {
+			resp, err = http.DefaultClient.Do(req)
+			return err == nil
+		}, 5*time.Second, 50*time.Millisecond)
+		if resp != nil {
+			_ = resp.Body.Close()
+		}
+	}
+	get("/healthz", http.Header{})
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	get("/users/42", http.Header{"Traceparent": []string{"00-" + traceID + "-00f067aa0ba902b7-01"}})
+
+	logger.Close()
+	b, err := os.ReadFile(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	// the health check is not logged.
+	if !assert.Len(t, lines, 1) {
+		return
+	}
+	var entry struct {
+		Body       string
+		TraceID    string `json:"TraceId"`
+		Attributes map[string]interface{}
+	}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "http request", entry.Body)
+	assert.Equal(t, traceID, entry.TraceID)
+	assert.Equal(t, "/users/{id}", entry.Attributes["http.route"])
+	assert.Equal(t, float64(http.StatusNotFound), entry.Attributes["http.status_code"])
+	assert.Equal(t, "127.0.0.1", entry.Attributes["http.client_ip"])
+}
+
+func TestWithGatewayMarshaler(t *testing.T) {
+	tests := map[string]struct {
+		options  []Option
+		expected string
+		missing  string
+	}{
+		"default uses the proto names": {
+			expected: `"type_name":"user"`,
+			missing:  `"typeName"`,
+		},
+		"camel case": {
+			options:  []Option{WithGatewayMarshaler(protojson.MarshalOptions{})},
+			expected: `"typeName":"user"`,
+			missing:  `"type_name"`,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			addr := freeAddr(t)
+			options := append([]Option{WithHTTPAddr(addr), WithGrpcAddr(freeAddr(t)), AllowEmpty(), withoutTelemetry()}, tc.options...)
+			f, err := NewFoundation("test", options...)
+			if !assert.NoError(t, err) {
+				return
+			}
+			f.RegisterServiceHandler(func(gw *runtime.ServeMux, conn *grpc.ClientConn) {
+				err := gw.HandlePath(http.MethodGet, "/v1/field", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+					_, outbound := runtime.MarshalerForRequest(gw, r)
+					runtime.ForwardResponseMessage(r.Context(), gw, outbound, w, r, &descriptorpb.FieldDescriptorProto{
+						Name:     proto.String("id"),
+						TypeName: proto.String("user"),
+					})
+				})
+				assert.NoError(t, err)
+			})
+
+			served := make(chan error, 1)
+			go func() {
+				served <- f.Serve()
+			}()
+			defer func() {
+				f.Shutdown()
+				<-served
+			}()
+
+			var resp *http.Response
+			assert.Eventually(t, func() bool {
+				resp, err = http.Get("http://" + addr + "/v1/field")
+				return err == nil
+			}, 5*time.Second, 50*time.Millisecond)
+			if resp == nil {
+				return
+			}
+			defer resp.Body.Close()
+			b, err := io.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			body := strings.ReplaceAll(string(b), " ", "")
+			assert.Contains(t, body, tc.expected)
+			assert.NotContains(t, body, tc.missing)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package grpc
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// BudgetMetadataKey is the metadata key carrying the remaining deadline budget, in milliseconds, across services.
+const BudgetMetadataKey = "x-deadline-budget-ms"
+
+type budgetCtxKeyType string
+
+const budgetCtxKey budgetCtxKeyType = "budget"
+
+// WithBudget returns a copy of ctx with a total time budget for the request chain.
+// The budget is enforced on the calls made with the budget client interceptors
+// and decremented at each hop by the time spent in the previous ones.
+func WithBudget(ctx context.Context, budget time.Duration) context.Context {
+	return context.WithValue(ctx, budgetCtxKey, time.Now().Add(budget))
+}
+
+// BudgetFromContext returns the remaining budget of the request chain, if any.
+func BudgetFromContext(ctx context.Context) (time.Duration, bool) {
+	expiry, ok := ctx.Value(budgetCtxKey).(time.Time)
+	if !ok {
+		return 0, false
+	}
+	return time.Until(expiry), true
+}
+
+// UnaryClientBudgetInterceptor returns a client interceptor enforcing the remaining budget of the context:
+// the call deadline is set to min(caller deadline, budget) and the remaining budget is sent to the
+// server via the BudgetMetadataKey metadata. A call made with an exhausted budget fails with DeadlineExceeded.
+//
+//	grpckit.NewClient(addr, grpc.WithChainUnaryInterceptor(grpckit.UnaryClientBudgetInterceptor()))
+func UnaryClientBudgetInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, cancel, err := budgetOutgoingContext(ctx)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientBudgetInterceptor returns a stream client interceptor enforcing the remaining budget of the context,
+// see UnaryClientBudgetInterceptor.
+func StreamClientBudgetInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, cancel, err := budgetOutgoingContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		// the stream outlives the interceptor, the deadline is released once the stream is done.
+		go func() {
+			<-stream.Context().Done()
+			cancel()
+		}()
+		return stream, nil
+	}
+}
+
+// UnaryServerBudgetInterceptor returns a server interceptor reading the remaining budget sent by the client
+// (see BudgetMetadataKey) into the handler context, bounding the handler deadline by it.
+// Calls made by the handler with the budget client interceptors carry the budget further down the chain.
+func UnaryServerBudgetInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, cancel := budgetIncomingContext(ctx)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerBudgetInterceptor returns a stream server interceptor reading the remaining budget sent by the client
+// into the stream context, see UnaryServerBudgetInterceptor.
+func StreamServerBudgetInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel := budgetIncomingContext(ss.Context())
+		defer cancel()
+		return handler(srv, &serverStream{
+			ServerStream: ss,
+			ctx:          ctx,
+		})
+	}
+}
+
+// budgetOutgoingContext bounds the context deadline by the remaining budget and adds it to the outgoing metadata.
+func budgetOutgoingContext(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	budget, ok := BudgetFromContext(ctx)
+	if !ok {
+		return ctx, func() {}, nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < budget {
+		budget = time.Until(deadline)
+	}
+	if budget <= 0 {
+		return ctx, func() {}, status.Error(codes.DeadlineExceeded, "deadline budget exhausted")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, budget)
+	ctx = metadata.AppendToOutgoingContext(ctx, BudgetMetadataKey, strconv.FormatInt(budget.Milliseconds(), 10))
+	return ctx, cancel, nil
+}
+
+// budgetIncomingContext reads the budget from the incoming metadata into the context.
+func budgetIncomingContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx, func() {}
+	}
+	values := md.Get(BudgetMetadataKey)
+	if len(values) == 0 {
+		return ctx, func() {}
+	}
+	ms, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil {
+		return ctx, func() {}
+	}
+
+	budget := time.Duration(ms) * time.Millisecond
+	ctx = WithBudget(ctx, budget)
+	return context.WithTimeout(ctx, budget)
+}
@@ -0,0 +1,98 @@
+package grpc
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// call is a call captured by the fake invoker.
+type call struct {
+	budget   time.Duration
+	deadline time.Time
+	md       metadata.MD
+}
+
+// captureInvoker records the context of the calls going through the client interceptor.
+func captureInvoker(calls chan<- call) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		ms, _ := strconv.ParseInt(md.Get(BudgetMetadataKey)[0], 10, 64)
+		deadline, _ := ctx.Deadline()
+		calls <- call{budget: time.Duration(ms) * time.Millisecond, deadline: deadline, md: md}
+		return nil
+	}
+}
+
+func TestBudgetTwoHops(t *testing.T) {
+	client := UnaryClientBudgetInterceptor()
+	server := UnaryServerBudgetInterceptor()
+	calls := make(chan call, 2)
+
+	// caller -> service A
+	ctx := WithBudget(context.Background(), time.Second)
+	err := client(ctx, "/a.Service/Get", nil, nil, nil, captureInvoker(calls))
+	assert.NoError(t, err)
+	upstream := <-calls
+	assert.LessOrEqual(t, upstream.budget, time.Second)
+	assert.WithinDuration(t, time.Now().Add(time.Second), upstream.deadline, 50*time.Millisecond)
+
+	// service A -> service B, after some work.
+	incoming := metadata.NewIncomingContext(context.Background(), upstream.md)
+	_, err = server(incoming, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		deadline, ok := ctx.Deadline()
+		assert.True(t, ok)
+		assert.False(t, deadline.After(upstream.deadline.Add(5*time.Millisecond)))
+
+		time.Sleep(20 * time.Millisecond)
+		return nil, client(ctx, "/b.Service/Get", nil, nil, nil, captureInvoker(calls))
+	})
+	assert.NoError(t, err)
+	downstream := <-calls
+
+	assert.Less(t, downstream.budget, upstream.budget)
+	assert.True(t, downstream.deadline.Before(upstream.deadline))
+}
+
+func TestBudgetCallerDeadline(t *testing.T) {
+	client := UnaryClientBudgetInterceptor()
+	calls := make(chan call, 1)
+
+	// the caller deadline is shorter than the budget.
+	ctx, cancel := context.WithTimeout(WithBudget(context.Background(), time.Minute), 100*time.Millisecond)
+	defer cancel()
+	err := client(ctx, "/a.Service/Get", nil, nil, nil, captureInvoker(calls))
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, (<-calls).budget, 100*time.Millisecond)
+}
+
+func TestBudgetExhausted(t *testing.T) {
+	client := UnaryClientBudgetInterceptor()
+
+	ctx := WithBudget(context.Background(), -time.Millisecond)
+	err := client(ctx, "/a.Service/Get", nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		t.Fatal("call should not be made with an exhausted budget")
+		return nil
+	})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
+
+func TestNoBudget(t *testing.T) {
+	client := UnaryClientBudgetInterceptor()
+
+	err := client(context.Background(), "/a.Service/Get", nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		_, ok := ctx.Deadline()
+		assert.False(t, ok)
+		md, _ := metadata.FromOutgoingContext(ctx)
+		assert.Empty(t, md.Get(BudgetMetadataKey))
+		return nil
+	})
+	assert.NoError(t, err)
+}
@@ -17,26 +17,54 @@ import (
 
 // NewServer creates a gRPC server that will be by default
 // recover from panic and setup for observability.
+//
+// The error statuses returned to the clients carry the trace id of the request (see TraceIDFromError).
+// The response sent when recovering from a panic can be customized with WithRecoveryHandler,
+// and the payloads logged with WithPayloadLogging.
 func NewServer(opts ...grpc.ServerOption) *grpc.Server {
+	recovery := recoverFrom(log.L())
+	var payloadLogging *payloadLoggingOption
+	for _, o := range opts {
+		switch o := o.(type) {
+		case recoveryOption:
+			recovery = grpcrecovery.RecoveryHandlerFuncContext(o.fn)
+		case payloadLoggingOption:
+			payloadLogging = &o
+		}
+	}
+
 	// Create a default server opts and set our default chain of interceptor
 	// if user decide to pass a custom interceptor via `grpc.ChainXXXInterceptor` or grpc.XXXInterceptor,
 	// it should be added at the end of the call chain since
 	// interpreter call chain is from left to right.
+	inFlight := defaultInFlightMetrics()
 	serverOpts := []grpc.ServerOption{
 		grpc.ChainStreamInterceptor(
 			otelgrpc.StreamServerInterceptor(),
-			grpcrecovery.StreamServerInterceptor(grpcrecovery.WithRecoveryHandlerContext(recoverFrom(log.L()))),
+			StreamServerTraceIDInterceptor(),
+			StreamServerInFlightInterceptor(inFlight, InFlightMetric),
+			grpcrecovery.StreamServerInterceptor(grpcrecovery.WithRecoveryHandlerContext(recovery)),
 			grpcprometheus.StreamServerInterceptor,
 			grpcvalidator.StreamServerInterceptor(),
 		),
 		grpc.ChainUnaryInterceptor(
 			otelgrpc.UnaryServerInterceptor(),
-			grpcrecovery.UnaryServerInterceptor(grpcrecovery.WithRecoveryHandlerContext(recoverFrom(log.L()))),
+			UnaryServerTraceIDInterceptor(),
+			UnaryServerInFlightInterceptor(inFlight, InFlightMetric),
+			grpcrecovery.UnaryServerInterceptor(grpcrecovery.WithRecoveryHandlerContext(recovery)),
 			grpcprometheus.UnaryServerInterceptor,
 			grpcvalidator.UnaryServerInterceptor(),
 		),
 	}
 
+	// payloads are logged after validation, right before the handler.
+	if payloadLogging != nil {
+		serverOpts = append(serverOpts,
+			grpc.ChainStreamInterceptor(payloadLogging.streamInterceptor),
+			grpc.ChainUnaryInterceptor(payloadLogging.unaryInterceptor),
+		)
+	}
+
 	serverOpts = append(serverOpts, opts...)
 	srv := grpc.NewServer(serverOpts...)
 	return srv
@@ -105,6 +133,21 @@ func WithCodes(retryCodes ...codes.Code) grpcretry.CallOption {
 	return grpcretry.WithCodes(retryCodes...)
 }
 
+// recoveryOption is a grpc.ServerOption defining the recovery handler used by NewServer.
+type recoveryOption struct {
+	grpc.EmptyServerOption
+	fn func(ctx context.Context, p interface{}) error
+}
+
+// WithRecoveryHandler defines the function called with the panic value when a handler panics,
+// the returned error is sent to the client (eg: a status with a reason, or a translation of known panics).
+// By default, the panic is logged and an Internal status is returned.
+//
+// It is only supported by NewServer.
+func WithRecoveryHandler(fn func(ctx context.Context, p interface{}) error) grpc.ServerOption {
+	return recoveryOption{fn: fn}
+}
+
 func recoverFrom(l *log.Logger) grpcrecovery.RecoveryHandlerFuncContext {
 	return func(ctx context.Context, p interface{}) error {
 		l.Error(ctx, "grpc recover panic", log.Any("panic", p))
@@ -0,0 +1,75 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// panicService is a service with a single method panicking with "boom".
+var panicService = grpc.ServiceDesc{
+	ServiceName: "test.Panic",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Do",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(emptypb.Empty)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					panic("boom")
+				}
+				return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/test.Panic/Do"}, handler)
+			},
+		},
+	},
+}
+
+// invokePanic serves the panic service with the given server and calls it.
+func invokePanic(t *testing.T, srv *grpc.Server) error {
+	t.Helper()
+	srv.RegisterService(&panicService, struct{}{})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(lis) //nolint
+	defer srv.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	return conn.Invoke(context.Background(), "/test.Panic/Do", &emptypb.Empty{}, &emptypb.Empty{})
+}
+
+func TestDefaultRecovery(t *testing.T) {
+	err := invokePanic(t, NewServer())
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, "boom", status.Convert(err).Message())
+}
+
+func TestWithRecoveryHandler(t *testing.T) {
+	var recovered interface{}
+	srv := NewServer(WithRecoveryHandler(func(ctx context.Context, p interface{}) error {
+		recovered = p
+		return status.Error(codes.Unavailable, "try again later")
+	}))
+
+	err := invokePanic(t, srv)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, "try again later", status.Convert(err).Message())
+	assert.Equal(t, "boom", recovered)
+}
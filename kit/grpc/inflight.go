@@ -0,0 +1,59 @@
+package grpc
+
+import (
+	"context"
+	"sync"
+
+	"github.com/anthonycorbacho/workspace/kit/log"
+	"github.com/anthonycorbacho/workspace/kit/telemetry/metric"
+	"google.golang.org/grpc"
+)
+
+// InFlightMetric is the name of the gauge metric tracking the in-flight requests of the servers created with NewServer.
+const InFlightMetric = "grpc_server_requests_in_flight"
+
+var (
+	inFlightMetrics = metric.New()
+	inFlightOnce    sync.Once
+)
+
+// defaultInFlightMetrics registers the in-flight gauge used by NewServer once per process.
+func defaultInFlightMetrics() *metric.Metrics {
+	inFlightOnce.Do(func() {
+		err := inFlightMetrics.Register(InFlightMetric, "Number of gRPC requests being served", metric.Gauge(), metric.Labels("method"))
+		if err != nil {
+			log.L().Warn(context.Background(), "registering grpc in-flight metric", log.Error(err))
+		}
+	})
+	return inFlightMetrics
+}
+
+// UnaryServerInFlightInterceptor returns a new unary server interceptor keeping track of
+// the number of requests being served in the given gauge metric, labeled by method.
+//
+// The metric must be defined as a gauge with the label "method".
+// The gauge is decremented even if the handler panics.
+func UnaryServerInFlightInterceptor(m *metric.Metrics, name string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		done, err := m.InFlight(name, info.FullMethod)
+		if err == nil {
+			defer done()
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInFlightInterceptor returns a new stream server interceptor keeping track of
+// the number of streams being served in the given gauge metric, labeled by method.
+//
+// The metric must be defined as a gauge with the label "method".
+// The gauge is decremented even if the handler panics.
+func StreamServerInFlightInterceptor(m *metric.Metrics, name string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		done, err := m.InFlight(name, info.FullMethod)
+		if err == nil {
+			defer done()
+		}
+		return handler(srv, stream)
+	}
+}
@@ -0,0 +1,93 @@
+package grpc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/telemetry/metric"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+func TestUnaryServerInFlightInterceptor(t *testing.T) {
+	const name = "test_grpc_unary_requests_in_flight"
+	m := metric.New()
+	err := m.Register(name, "in-flight requests", metric.Gauge(), metric.Labels("method"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	interceptor := UnaryServerInFlightInterceptor(m, name)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Get"}
+
+	// holding concurrent requests
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				<-release
+				return nil, nil
+			})
+		}()
+	}
+	assert.Eventually(t, func() bool {
+		return gaugeValue(t, name, info.FullMethod) == 3
+	}, time.Second, 10*time.Millisecond)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, float64(0), gaugeValue(t, name, info.FullMethod))
+
+	// panicking handler
+	assert.Panics(t, func() {
+		_, _ = interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic("handler panic")
+		})
+	})
+	assert.Equal(t, float64(0), gaugeValue(t, name, info.FullMethod))
+}
+
+func TestStreamServerInFlightInterceptor(t *testing.T) {
+	const name = "test_grpc_stream_requests_in_flight"
+	m := metric.New()
+	err := m.Register(name, "in-flight requests", metric.Gauge(), metric.Labels("method"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	interceptor := StreamServerInFlightInterceptor(m, name)
+	info := &grpc.StreamServerInfo{FullMethod: "/test.Service/Watch"}
+
+	err = interceptor(nil, &fakeServerStream{ctx: context.Background()}, info, func(srv interface{}, stream grpc.ServerStream) error {
+		assert.Equal(t, float64(1), gaugeValue(t, name, info.FullMethod))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, float64(0), gaugeValue(t, name, info.FullMethod))
+}
+
+// gaugeValue returns the value of the gauge with the given method from the default prometheus registry.
+func gaugeValue(t *testing.T, name, method string) float64 {
+	t.Helper()
+	families, err := prom.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, mtr := range family.GetMetric() {
+			for _, l := range mtr.GetLabel() {
+				if l.GetName() == "method" && l.GetValue() == method {
+					return mtr.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	return -1
+}
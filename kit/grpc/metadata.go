@@ -0,0 +1,62 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/anthonycorbacho/workspace/kit/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// UnaryServerLogFieldsInterceptor returns a server interceptor extracting the given keys
+// from the incoming metadata (eg: x-request-id, x-tenant-id) into the request context,
+// every log written with the handler context includes them as fields.
+//
+//	grpckit.NewServer(grpc.ChainUnaryInterceptor(grpckit.UnaryServerLogFieldsInterceptor("x-request-id")))
+func UnaryServerLogFieldsInterceptor(keys ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(contextWithMetadataFields(ctx, keys), req)
+	}
+}
+
+// StreamServerLogFieldsInterceptor returns a stream server interceptor extracting the given keys
+// from the incoming metadata into the stream context, every log written with the stream context includes them as fields.
+func StreamServerLogFieldsInterceptor(keys ...string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{
+			ServerStream: ss,
+			ctx:          contextWithMetadataFields(ss.Context(), keys),
+		})
+	}
+}
+
+// contextWithMetadataFields adds the values of the given incoming metadata keys to the context log fields.
+func contextWithMetadataFields(ctx context.Context, keys []string) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	fields := make([]log.Field, 0, len(keys))
+	for _, k := range keys {
+		k = strings.ToLower(k)
+		values := md.Get(k)
+		if len(values) == 0 {
+			continue
+		}
+		fields = append(fields, log.String(k, strings.Join(values, ",")))
+	}
+	return log.ContextWithFields(ctx, fields...)
+}
+
+// serverStream wraps a grpc.ServerStream to override its context.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context of the stream.
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
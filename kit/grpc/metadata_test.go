@@ -0,0 +1,96 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anthonycorbacho/workspace/kit/log"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestUnaryServerLogFieldsInterceptor(t *testing.T) {
+	logger, output := fileLogger(t)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"x-request-id", "42",
+		"x-tenant-id", "acme",
+		"x-ignored", "ignored",
+	))
+	interceptor := UnaryServerLogFieldsInterceptor("x-request-id", "X-Tenant-Id", "x-missing")
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		logger.Info(ctx, "handling request")
+		return nil, nil
+	})
+	assert.NoError(t, err)
+	logger.Close()
+
+	attributes := lastLogAttributes(t, output)
+	assert.Equal(t, "42", attributes["x-request-id"])
+	assert.Equal(t, "acme", attributes["x-tenant-id"])
+	assert.NotContains(t, attributes, "x-ignored")
+	assert.NotContains(t, attributes, "x-missing")
+}
+
+func TestStreamServerLogFieldsInterceptor(t *testing.T) {
+	logger, output := fileLogger(t)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "42"))
+	interceptor := StreamServerLogFieldsInterceptor("x-request-id")
+	err := interceptor(nil, &fakeServerStream{ctx: ctx}, &grpc.StreamServerInfo{}, func(srv interface{}, stream grpc.ServerStream) error {
+		logger.Info(stream.Context(), "handling stream")
+		return nil
+	})
+	assert.NoError(t, err)
+	logger.Close()
+
+	attributes := lastLogAttributes(t, output)
+	assert.Equal(t, "42", attributes["x-request-id"])
+}
+
+// fileLogger returns a logger writing to a temporary file, and the path of the file.
+func fileLogger(t *testing.T) (*log.Logger, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "log")
+	logger, err := log.New(log.WithOutputPaths(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return logger, path
+}
+
+// lastLogAttributes returns the attributes of the last log line written in the given file.
+func lastLogAttributes(t *testing.T, path string) map[string]interface{} {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		t.Fatal("no log written")
+	}
+	lines := bytes.Split(b, []byte("\n"))
+
+	var entry struct {
+		Attributes map[string]interface{}
+	}
+	if err := json.Unmarshal(lines[len(lines)-1], &entry); err != nil {
+		t.Fatal(err)
+	}
+	return entry.Attributes
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
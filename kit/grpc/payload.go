@@ -0,0 +1,94 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/anthonycorbacho/workspace/kit/log"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// payloadLoggingOption is a grpc.ServerOption enabling the payload logging in NewServer.
+type payloadLoggingOption struct {
+	grpc.EmptyServerOption
+	logger   *log.Logger
+	maxBytes int
+	redact   func(proto.Message) proto.Message
+}
+
+// WithPayloadLogging logs, at debug level, the request and response payloads of the calls.
+// Payloads are marshalled to JSON and truncated to maxBytes (0 means no limit),
+// redact is called with a copy of each message to hide sensitive values before logging (can be nil).
+//
+// It is meant for debugging, payloads are not logged by default. It is only supported by NewServer.
+func WithPayloadLogging(l *log.Logger, maxBytes int, redact func(proto.Message) proto.Message) grpc.ServerOption {
+	return payloadLoggingOption{logger: l, maxBytes: maxBytes, redact: redact}
+}
+
+// unaryInterceptor logs the request and response payloads of unary calls.
+func (o payloadLoggingOption) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	o.log(ctx, "grpc request payload", info.FullMethod, req)
+	resp, err := handler(ctx, req)
+	if err == nil {
+		o.log(ctx, "grpc response payload", info.FullMethod, resp)
+	}
+	return resp, err
+}
+
+// streamInterceptor logs the messages received and sent on streams.
+func (o payloadLoggingOption) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &payloadServerStream{ServerStream: ss, opt: o, method: info.FullMethod})
+}
+
+// log logs the payload if it is a proto message.
+// Nothing is done above debug level, the payloads are not cloned nor marshalled.
+func (o payloadLoggingOption) log(ctx context.Context, message string, method string, payload interface{}) {
+	if o.logger.Level() > log.DebugLevel {
+		return
+	}
+	msg, ok := payload.(proto.Message)
+	if !ok {
+		return
+	}
+	if o.redact != nil {
+		msg = o.redact(proto.Clone(msg))
+	}
+
+	b, err := protojson.Marshal(msg)
+	if err != nil {
+		o.logger.Debug(ctx, message, log.String("grpc.method", method), log.Error(err))
+		return
+	}
+	size := len(b)
+	truncated := o.maxBytes > 0 && size > o.maxBytes
+	if truncated {
+		b = b[:o.maxBytes]
+	}
+	o.logger.Debug(ctx, message,
+		log.String("grpc.method", method),
+		log.ByteString("payload", b),
+		log.Int("payload.size", size),
+		log.Bool("payload.truncated", truncated),
+	)
+}
+
+// payloadServerStream wraps a grpc.ServerStream to log its messages.
+type payloadServerStream struct {
+	grpc.ServerStream
+	opt    payloadLoggingOption
+	method string
+}
+
+func (s *payloadServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	s.opt.log(s.Context(), "grpc request payload", s.method, m)
+	return nil
+}
+
+func (s *payloadServerStream) SendMsg(m interface{}) error {
+	s.opt.log(s.Context(), "grpc response payload", s.method, m)
+	return s.ServerStream.SendMsg(m)
+}
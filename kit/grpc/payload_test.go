@@ -0,0 +1,111 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anthonycorbacho/workspace/kit/log"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/proto"
+)
+
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+}
+
+func (healthServer) Check(_ context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+// checkHealth serves a health service with the given server options and calls it,
+// it returns the logs written at debug level.
+func checkHealth(t *testing.T, service string, opts func(l *log.Logger) []grpc.ServerOption) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "log")
+	logger, err := log.New(log.WithOutputPaths(path), log.WithLevel(log.DebugLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer(opts(logger)...)
+	healthpb.RegisterHealthServer(srv, healthServer{})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(lis) //nolint
+	defer srv.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	assert.NoError(t, err)
+
+	logger.Close()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestPayloadLogging(t *testing.T) {
+	redact := func(m proto.Message) proto.Message {
+		if req, ok := m.(*healthpb.HealthCheckRequest); ok {
+			req.Service = "redacted"
+		}
+		return m
+	}
+	logs := checkHealth(t, "secret-service", func(l *log.Logger) []grpc.ServerOption {
+		return []grpc.ServerOption{WithPayloadLogging(l, 0, redact)}
+	})
+
+	assert.Contains(t, logs, "grpc request payload")
+	assert.Contains(t, logs, "grpc response payload")
+	assert.Contains(t, logs, "SERVING")
+	assert.Contains(t, logs, "redacted")
+	assert.NotContains(t, logs, "secret-service")
+}
+
+func TestPayloadLogging_MaxBytes(t *testing.T) {
+	logs := checkHealth(t, strings.Repeat("a", 100), func(l *log.Logger) []grpc.ServerOption {
+		return []grpc.ServerOption{WithPayloadLogging(l, 10, nil)}
+	})
+
+	assert.Contains(t, logs, `"payload.truncated":true`)
+	assert.NotContains(t, logs, strings.Repeat("a", 20))
+}
+
+func TestPayloadLogging_InfoLevel(t *testing.T) {
+	redacted := 0
+	redact := func(m proto.Message) proto.Message {
+		redacted++
+		return m
+	}
+	logs := checkHealth(t, "secret-service", func(l *log.Logger) []grpc.ServerOption {
+		l.SetLevel(log.InfoLevel)
+		return []grpc.ServerOption{WithPayloadLogging(l, 0, redact)}
+	})
+
+	assert.Zero(t, redacted)
+	assert.NotContains(t, logs, "payload")
+}
+
+func TestPayloadLogging_Disabled(t *testing.T) {
+	logs := checkHealth(t, "secret-service", func(l *log.Logger) []grpc.ServerOption {
+		return nil
+	})
+
+	assert.NotContains(t, logs, "payload")
+	assert.NotContains(t, logs, "secret-service")
+}
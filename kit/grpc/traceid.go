@@ -0,0 +1,74 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"go.opentelemetry.io/otel/trace"
+	rpcerrdetails "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerTraceIDInterceptor returns a server interceptor attaching the trace id of the request
+// to the returned error statuses as a RequestInfo detail, so support can look up a failed request from
+// the error received by the client (see TraceIDFromError).
+//
+// It is part of the default chain of NewServer.
+func UnaryServerTraceIDInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		return resp, withTraceID(ctx, err)
+	}
+}
+
+// StreamServerTraceIDInterceptor returns a stream server interceptor attaching the trace id of the stream
+// to the returned error statuses as a RequestInfo detail.
+//
+// It is part of the default chain of NewServer.
+func StreamServerTraceIDInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return withTraceID(ss.Context(), handler(srv, ss))
+	}
+}
+
+// TraceIDFromError returns the trace id attached to an error status by the server interceptors.
+// It returns false if the error does not carry a RequestInfo detail.
+func TraceIDFromError(err error) (string, bool) {
+	var st interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &st) {
+		return "", false
+	}
+	for _, d := range st.GRPCStatus().Details() {
+		if ri, ok := d.(*rpcerrdetails.RequestInfo); ok {
+			return ri.GetRequestId(), true
+		}
+	}
+	return "", false
+}
+
+// withTraceID adds the trace id of the context to the error status,
+// errors already carrying a RequestInfo detail are returned untouched.
+func withTraceID(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return err
+	}
+	st := status.Convert(err)
+	if st.Code() == codes.OK {
+		return err
+	}
+	if _, ok := TraceIDFromError(st.Err()); ok {
+		return err
+	}
+
+	withDetails, detailsErr := st.WithDetails(&rpcerrdetails.RequestInfo{RequestId: sc.TraceID().String()})
+	if detailsErr != nil {
+		return err
+	}
+	return withDetails.Err()
+}
@@ -0,0 +1,91 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerTraceIDInterceptor(t *testing.T) {
+	tp := sdktrace.NewTracerProvider()
+	defer tp.Shutdown(context.Background()) //nolint
+	ctx, span := tp.Tracer("test").Start(context.Background(), "server")
+	defer span.End()
+	traceID := span.SpanContext().TraceID().String()
+
+	interceptor := UnaryServerTraceIDInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Get"}
+	call := func(ctx context.Context, err error) error {
+		_, got := interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, err
+		})
+		return got
+	}
+
+	// error status carries the trace id.
+	err := call(ctx, status.Error(codes.Internal, "boom"))
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, "boom", status.Convert(err).Message())
+	id, ok := TraceIDFromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, traceID, id)
+
+	// plain errors are converted to an Unknown status.
+	err = call(ctx, assert.AnError)
+	assert.Equal(t, codes.Unknown, status.Code(err))
+	id, ok = TraceIDFromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, traceID, id)
+
+	// trace id is only attached once.
+	err = call(ctx, err)
+	assert.Len(t, status.Convert(err).Details(), 1)
+
+	// no error, no trace.
+	assert.NoError(t, call(ctx, nil))
+	err = call(context.Background(), status.Error(codes.Internal, "boom"))
+	_, ok = TraceIDFromError(err)
+	assert.False(t, ok)
+}
+
+func TestStreamServerTraceIDInterceptor(t *testing.T) {
+	tp := sdktrace.NewTracerProvider()
+	defer tp.Shutdown(context.Background()) //nolint
+	ctx, span := tp.Tracer("test").Start(context.Background(), "server")
+	defer span.End()
+
+	interceptor := StreamServerTraceIDInterceptor()
+	err := interceptor(nil, &fakeServerStream{ctx: ctx}, &grpc.StreamServerInfo{}, func(srv interface{}, stream grpc.ServerStream) error {
+		return status.Error(codes.Unavailable, "unavailable")
+	})
+	id, ok := TraceIDFromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, span.SpanContext().TraceID().String(), id)
+}
+
+func TestNewServer_TraceID(t *testing.T) {
+	tp := sdktrace.NewTracerProvider()
+	defer tp.Shutdown(context.Background()) //nolint
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	// capturing the server span from the recovery handler.
+	var serverSpan trace.SpanContext
+	err := invokePanic(t, NewServer(WithRecoveryHandler(func(ctx context.Context, p interface{}) error {
+		serverSpan = trace.SpanContextFromContext(ctx)
+		return status.Errorf(codes.Internal, "%v", p)
+	})))
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	id, ok := TraceIDFromError(err)
+	assert.True(t, ok)
+	assert.True(t, serverSpan.HasTraceID())
+	assert.Equal(t, serverSpan.TraceID().String(), id)
+}
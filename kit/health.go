@@ -0,0 +1,126 @@
+package kit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// Health statuses of HealthStatus.
+const (
+	HealthOK      = "ok"
+	HealthFailing = "failing"
+)
+
+// HealthStatus is the JSON body of the /healthz and /readyz probes,
+// served when the client sends `Accept: application/json` (plain text "ok" or the error otherwise).
+type HealthStatus struct {
+	// Status is HealthOK or HealthFailing.
+	Status string `json:"status"`
+	// Checks are the results of the probe checks, by name (eg: liveness, readiness),
+	// the message returned by the check or its error.
+	Checks map[string]string `json:"checks"`
+	// Version of the running binary, see ReadBuildInfo.
+	Version string `json:"version"`
+	// Uptime of the foundation, eg: 1h2m3s.
+	Uptime string `json:"uptime"`
+}
+
+// healthHandler serves the result of the probe fn, as HealthStatus when JSON is accepted.
+func (f *Foundation) healthHandler(check string, fn func() (string, error)) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		status := http.StatusOK
+		msg, err := fn()
+		if err != nil {
+			status = http.StatusInternalServerError
+			msg = err.Error()
+		}
+
+		if !strings.Contains(request.Header.Get("Accept"), "application/json") {
+			writer.Header().Set("Content-Type", "text/plain")
+			writer.WriteHeader(status)
+			fmt.Fprintln(writer, msg) //nolint
+			return
+		}
+
+		body := HealthStatus{
+			Status:  HealthOK,
+			Checks:  map[string]string{check: msg},
+			Version: ReadBuildInfo().Version,
+			Uptime:  time.Since(f.started).Truncate(time.Second).String(),
+		}
+		if err != nil {
+			body.Status = HealthFailing
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(status)
+		_ = json.NewEncoder(writer).Encode(body) //nolint
+	}
+}
+
+// healthServer implements the standard gRPC health checking protocol.
+// See https://github.com/grpc/grpc/blob/master/doc/health-checking.md
+//
+// The serving status follows the Foundation readiness (same as /readyz),
+// and transitions to NOT_SERVING once the Foundation starts shutting down.
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+	server *grpc.Server
+	// status returns the current serving status of the foundation.
+	status func() healthpb.HealthCheckResponse_ServingStatus
+	// done is closed when the foundation is shutting down.
+	done <-chan struct{}
+}
+
+// Check returns the serving status of the given service.
+// An empty service name represents the overall server health.
+func (h *healthServer) Check(_ context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	st := h.serviceStatus(req.GetService())
+	if st == healthpb.HealthCheckResponse_SERVICE_UNKNOWN {
+		return nil, status.Errorf(codes.NotFound, "unknown service %s", req.GetService())
+	}
+	return &healthpb.HealthCheckResponse{Status: st}, nil
+}
+
+// Watch streams the serving status of the given service each time it changes.
+// The stream ends once the foundation is shutting down, so it doesn't block the graceful stop.
+func (h *healthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_ServingStatus(-1)
+	for {
+		st := h.serviceStatus(req.GetService())
+		if st != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: st}); err != nil {
+				return err
+			}
+			last = st
+		}
+
+		select {
+		case <-h.done:
+			return stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING})
+		case <-stream.Context().Done():
+			return status.Error(codes.Canceled, "stream has ended")
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *healthServer) serviceStatus(service string) healthpb.HealthCheckResponse_ServingStatus {
+	if len(service) > 0 {
+		if _, ok := h.server.GetServiceInfo()[service]; !ok {
+			return healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+	}
+	return h.status()
+}
@@ -0,0 +1,67 @@
+package kit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthHandler(t *testing.T) {
+	Version = "v1.2.3"
+	defer func() { Version = "" }()
+
+	f, err := NewFoundation("test")
+	if !assert.NoError(t, err) {
+		return
+	}
+	f.started = time.Now().Add(-90 * time.Second)
+
+	probe := func(handler http.HandlerFunc, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	// plain text by default.
+	rec := probe(f.livenessProbe, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/plain", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "ok\n", rec.Body.String())
+
+	// structured body when JSON is accepted.
+	rec = probe(f.livenessProbe, "application/json, text/plain;q=0.9")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var status HealthStatus
+	if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status)) {
+		assert.Equal(t, HealthStatus{
+			Status:  HealthOK,
+			Checks:  map[string]string{"liveness": "ok"},
+			Version: "v1.2.3",
+			Uptime:  "1m30s",
+		}, status)
+	}
+
+	// failing checks report the error.
+	f.RegisterReadiness(func() (string, error) { return "", errors.New("database unavailable") })
+	rec = probe(f.readinessProbe, "")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "database unavailable\n", rec.Body.String())
+
+	rec = probe(f.readinessProbe, "application/json")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	status = HealthStatus{}
+	if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status)) {
+		assert.Equal(t, HealthFailing, status.Status)
+		assert.Equal(t, map[string]string{"readiness": "database unavailable"}, status.Checks)
+	}
+}
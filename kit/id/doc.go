@@ -2,10 +2,19 @@
 // Features:
 //
 //   - Size: 12 bytes (96 bits), smaller than UUID, larger than snowflake
-//   - Base32 hex encoded by default (16 bytes storage when transported as printable string)
+//   - Base32 hex encoded by default (16 bytes storage when transported as printable string),
+//     Encode and Decode convert the raw id from/to base64url, base62 and hex
 //   - K-ordered
 //   - Embedded time with 1 second precision
-//   - Unicity guaranteed for 16,777,216 (24 bits) unique ids per second and per host/process
+//   - Unicity guaranteed for 16,777,216 (24 bits) unique ids per second and per host/process,
+//     with EnableOverflowCheck the ids generated over that capacity roll into the next second instead of being duplicated,
+//     NewChecked returns ErrCounterOverflow instead
+//
+// Parse reads back the fields embedded in an id (time, machine, process and counter),
+// the parsed ID can be stored in and scanned from a database column (sql.Scanner and driver.Valuer).
+//
+// The generation can be observed with EnableMetrics (ids generated and counter overflows)
+// and EnableDebug (warning logged on each counter overflow), the overflows are detected with EnableOverflowCheck.
 //
 // example:
 //
@@ -21,4 +30,14 @@
 // 		//Output:
 //			user/9m4e2mr0ui3e8a215n4g
 //
+//		// Changing the delimiter and the encoding, the embedded time can be read back with Time.
+//		generator := id.NewGenerator("user", id.WithDelimiter("_"), id.WithEncoding(id.Base62))
+//		ID := generator.Generate()
+//		//Output:
+//			user_0VCs04xTJMQCMA3B3
+//		t, err := generator.Time(ID)
+//
+//		// Generating reproducible ids in tests, from a fixed time and a deterministic reader.
+//		generator := id.NewGenerator("user", id.WithTimeSource(fixedNow), id.WithRandReader(reader))
+//
 package id
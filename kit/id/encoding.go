@@ -0,0 +1,132 @@
+package id
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+	"strings"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/rs/xid"
+)
+
+// size is the number of raw bytes of an id.
+const size = len(xid.ID{})
+
+// Encoding defines how the raw bytes of an id are represented as a string.
+type Encoding int
+
+const (
+	// Base32Hex is the lowercase base32 extended hex alphabet without padding, used by New.
+	Base32Hex Encoding = iota
+	// Base64URL is the URL safe base64 alphabet without padding.
+	Base64URL
+	// Hex is the lowercase hexadecimal representation.
+	Hex
+	// Base62 is the alphanumeric representation (0-9A-Za-z), zero padded to 17 characters.
+	Base62
+)
+
+// base32Hex matches the id string format: lowercase and without padding.
+var base32Hex = base32.NewEncoding(base32HexAlphabet).WithPadding(base32.NoPadding)
+
+const base32HexAlphabet = "0123456789abcdefghijklmnopqrstuv"
+
+// base62Alphabet is sorted in byte order and the base62 ids have a fixed length,
+// so that they are k-ordered like the base32hex and hex ids.
+const (
+	base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	base62Len      = 17
+)
+
+// String returns the name of the encoding.
+func (e Encoding) String() string {
+	switch e {
+	case Base32Hex:
+		return "base32hex"
+	case Base64URL:
+		return "base64url"
+	case Hex:
+		return "hex"
+	case Base62:
+		return "base62"
+	default:
+		return "unknown"
+	}
+}
+
+// Encode encodes the raw bytes of an id with the given encoding.
+// Unknown encodings fall back to Base32Hex.
+func Encode(raw []byte, enc Encoding) string {
+	switch enc {
+	case Base64URL:
+		return base64.RawURLEncoding.EncodeToString(raw)
+	case Hex:
+		return hex.EncodeToString(raw)
+	case Base62:
+		return encodeBase62(raw)
+	default:
+		return base32Hex.EncodeToString(raw)
+	}
+}
+
+// Decode decodes the id string s, encoded with the given encoding, to its raw bytes.
+// Strings that do not decode to an id (12 bytes), such as an id encoded with another encoding, are rejected.
+func Decode(s string, enc Encoding) ([]byte, error) {
+	var (
+		raw []byte
+		err error
+	)
+	switch enc {
+	case Base32Hex:
+		raw, err = base32Hex.DecodeString(s)
+	case Base64URL:
+		raw, err = base64.RawURLEncoding.DecodeString(s)
+	case Hex:
+		raw, err = hex.DecodeString(s)
+	case Base62:
+		raw, err = decodeBase62(s)
+	default:
+		return nil, errors.Newf("unknown encoding %d", enc)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "decode %s id '%s'", enc, s)
+	}
+	if len(raw) != size {
+		return nil, errors.Newf("decode %s id '%s': invalid length %d", enc, s, len(raw))
+	}
+	return raw, nil
+}
+
+func encodeBase62(raw []byte) string {
+	n := new(big.Int).SetBytes(raw)
+	base := big.NewInt(int64(len(base62Alphabet)))
+	mod := new(big.Int)
+
+	b := []byte(strings.Repeat("0", base62Len))
+	for i := len(b) - 1; i >= 0 && n.Sign() > 0; i-- {
+		n.DivMod(n, base, mod)
+		b[i] = base62Alphabet[mod.Int64()]
+	}
+	return string(b)
+}
+
+func decodeBase62(s string) ([]byte, error) {
+	if len(s) != base62Len {
+		return nil, errors.Newf("invalid length %d", len(s))
+	}
+	n := new(big.Int)
+	base := big.NewInt(int64(len(base62Alphabet)))
+	for i := 0; i < len(s); i++ {
+		v := strings.IndexByte(base62Alphabet, s[i])
+		if v < 0 {
+			return nil, errors.Newf("invalid character %q", s[i])
+		}
+		n.Mul(n, base).Add(n, big.NewInt(int64(v)))
+	}
+	if n.BitLen() > size*8 {
+		return nil, errors.New("value overflows an id")
+	}
+	return n.FillBytes(make([]byte, size)), nil
+}
@@ -0,0 +1,75 @@
+package id
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	raw := xid.New().Bytes()
+
+	for _, enc := range []Encoding{Base32Hex, Base64URL, Hex, Base62} {
+		t.Run(enc.String(), func(t *testing.T) {
+			s := Encode(raw, enc)
+			decoded, err := Decode(s, enc)
+			require.NoError(t, err)
+			assert.Equal(t, raw, decoded)
+		})
+	}
+}
+
+func TestEncode_Default(t *testing.T) {
+	id := xid.New()
+	// the default encoding matches the string format of New.
+	assert.Equal(t, id.String(), Encode(id.Bytes(), Base32Hex))
+	assert.Equal(t, id.String(), Encode(id.Bytes(), Encoding(42)))
+}
+
+func TestDecode_Mismatch(t *testing.T) {
+	id, err := xid.FromString("9m4e2mr0ui3e8a215n4g")
+	require.NoError(t, err)
+
+	encodings := []Encoding{Base32Hex, Base64URL, Hex, Base62}
+	for _, from := range encodings {
+		for _, to := range encodings {
+			if from == to {
+				continue
+			}
+			_, err := Decode(Encode(id.Bytes(), from), to)
+			assert.Error(t, err, "%s decoded as %s", from, to)
+		}
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	_, err := Decode("not an id", Hex)
+	assert.Error(t, err)
+
+	_, err = Decode("9m4e2mr0ui3e8a215n4g", Encoding(42))
+	assert.Error(t, err)
+}
+
+func TestBase62_Ordered(t *testing.T) {
+	earlier := xid.NewWithTime(time.Unix(1600000000, 0))
+	later := xid.NewWithTime(time.Unix(1600000001, 0))
+
+	a, b := Encode(earlier.Bytes(), Base62), Encode(later.Bytes(), Base62)
+	assert.Len(t, a, base62Len)
+	assert.Less(t, a, b)
+
+	// zero and max ids keep the fixed length.
+	assert.Equal(t, "00000000000000000", Encode(make([]byte, size), Base62))
+	max := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	s := Encode(max, Base62)
+	assert.Len(t, s, base62Len)
+	decoded, err := Decode(s, Base62)
+	require.NoError(t, err)
+	assert.Equal(t, max, decoded)
+
+	_, err = Decode("zzzzzzzzzzzzzzzzz", Base62)
+	assert.Error(t, err)
+}
@@ -1,33 +1,281 @@
 package id
 
 import (
-	"fmt"
+	"context"
+	"encoding/binary"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/log"
 	"github.com/rs/xid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
 )
 
+// maxPerSecond is the number of unique ids that can be generated per second,
+// bounded by the 24 bits counter embedded in the id.
+var maxPerSecond uint32 = 1 << 24
+
+// window tracks the ids generated for the current timestamp to detect counter overflows,
+// only when the overflow check is enabled (see EnableOverflowCheck) or with NewChecked.
+var window struct {
+	sync.Mutex
+	second int64
+	count  uint32
+}
+
+// observability of the generation, see EnableOverflowCheck, EnableMetrics and EnableDebug.
+var (
+	overflowCheck    atomic.Bool
+	generatedCounter atomic.Pointer[metric.Int64Counter]
+	overflowCounter  atomic.Pointer[metric.Int64Counter]
+	debugLogger      atomic.Pointer[log.Logger]
+)
+
+// ErrCounterOverflow is returned by NewChecked when the ids capacity of the current second is exhausted.
+var ErrCounterOverflow = errors.New("id counter overflow")
+
 // New generates a globally unique ID
+//
+// When more ids than the counter capacity (16,777,216) are generated within the same second,
+// the counter overflows and generates duplicates. With the overflow check enabled (see EnableOverflowCheck),
+// the ids are instead generated for the next second.
 func New() string {
-	return xid.NewWithTime(time.Now().UTC()).String()
+	return newID().String()
+}
+
+// NewChecked generates a globally unique ID like New, but returns ErrCounterOverflow instead of
+// generating the id for the next second when the counter capacity of the current second is exhausted.
+// The embedded time of the ids it returns is never ahead of the current time, unless New already rolled into the next second.
+//
+// NewChecked always checks the counter capacity, the ids generated with New are only accounted with the overflow check enabled.
+func NewChecked() (string, error) {
+	id, err := generate(time.Now().UTC(), false)
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// EnableOverflowCheck tracks the ids generated within the current second by New and the generators,
+// to roll into the next second instead of overflowing the counter. Each id then goes through a process wide lock:
+// it is disabled by default to keep the generation lock free.
+//
+// The counter overflows are reported (see EnableMetrics and EnableDebug) only with the check enabled.
+func EnableOverflowCheck() {
+	overflowCheck.Store(true)
+}
+
+// newID generates an id for the current time, through the overflow check when enabled.
+func newID() xid.ID {
+	if overflowCheck.Load() {
+		id, _ := generate(time.Now().UTC(), true)
+		return id
+	}
+	id := xid.New()
+	countGenerated()
+	return id
+}
+
+// countGenerated counts a generated id when the metrics are enabled.
+func countGenerated() {
+	if c := generatedCounter.Load(); c != nil {
+		(*c).Add(context.Background(), 1)
+	}
+}
+
+// generate generates an id for the given time.
+// On counter overflow, it rolls into the next second when roll is true, or returns ErrCounterOverflow.
+func generate(now time.Time, roll bool) (xid.ID, error) {
+	window.Lock()
+	second := now.Unix()
+	if second > window.second {
+		window.second = second
+		window.count = 0
+	}
+	overflow := window.count >= maxPerSecond
+	if overflow && !roll {
+		window.Unlock()
+		countOverflow(second, second)
+		return xid.ID{}, ErrCounterOverflow
+	}
+	if overflow {
+		window.second++
+		window.count = 0
+	}
+	window.count++
+	// the id is generated with the lock held so ids of the same second get consecutive counters.
+	id := xid.NewWithTime(time.Unix(window.second, 0))
+	window.Unlock()
+
+	countGenerated()
+	if overflow {
+		countOverflow(second, id.Time().Unix())
+	}
+	return id, nil
+}
+
+// countOverflow reports a counter overflow of the given second to the metrics and the debug logger.
+func countOverflow(second, rolledTo int64) {
+	ctx := context.Background()
+	if c := overflowCounter.Load(); c != nil {
+		(*c).Add(ctx, 1)
+	}
+	if l := debugLogger.Load(); l != nil {
+		if rolledTo == second {
+			l.Warn(ctx, "id counter overflow", log.Int64("second", second))
+			return
+		}
+		l.Warn(ctx, "id counter overflow, rolling into next second",
+			log.Int64("second", second), log.Int64("rolled_to", rolledTo))
+	}
+}
+
+// EnableMetrics records the number of ids generated (id.generated) and the number of
+// counter overflows (id.overflows, see EnableOverflowCheck) using the global meter provider.
+func EnableMetrics() error {
+	meter := otel.Meter("kit/id")
+	generated, err := meter.Int64Counter("id.generated",
+		metric.WithDescription("Number of ids generated"))
+	if err != nil {
+		return errors.Wrap(err, "id generated metric")
+	}
+	overflows, err := meter.Int64Counter("id.overflows",
+		metric.WithDescription("Number of times more ids than the counter capacity were generated within a second"))
+	if err != nil {
+		return errors.Wrap(err, "id overflows metric")
+	}
+	generatedCounter.Store(&generated)
+	overflowCounter.Store(&overflows)
+	return nil
 }
 
+// EnableDebug logs a warning with the given logger each time the counter overflows (see EnableOverflowCheck),
+// a nil logger disables it.
+func EnableDebug(logger *log.Logger) {
+	debugLogger.Store(logger)
+}
+
+// defaultDelimiter separates the prefix from the id, see WithDelimiter.
+const defaultDelimiter = "/"
+
 // Generator will generate prefixed ID.
 type Generator struct {
-	prefix string
+	prefix    string
+	delimiter string
+	encoding  Encoding
+	// sources of the generated ids, see WithTimeSource and WithRandReader.
+	now  func() time.Time
+	rand io.Reader
+}
+
+// GeneratorOption defines a Generator option.
+type GeneratorOption func(*Generator)
+
+// WithEncoding defines the encoding of the generated ids, Base32Hex by default.
+// Base32Hex, Base62 and Hex ids are k-ordered, Base64URL ids are not.
+func WithEncoding(enc Encoding) GeneratorOption {
+	return func(g *Generator) {
+		g.encoding = enc
+	}
+}
+
+// WithDelimiter defines the separator between the prefix and the id, "/" by default
+// (eg: "_" for datastore keys that cannot contain slashes).
+func WithDelimiter(delimiter string) GeneratorOption {
+	return func(g *Generator) {
+		g.delimiter = delimiter
+	}
+}
+
+// WithTimeSource defines the function returning the time embedded in the generated ids, time.Now by default
+// (eg: a fixed time in tests). The ids generated with a time source are counted by the metrics (see EnableMetrics)
+// but are left out of the overflow check: it tracks the current second only.
+func WithTimeSource(now func() time.Time) GeneratorOption {
+	return func(g *Generator) {
+		g.now = now
+	}
+}
+
+// WithRandReader defines the reader providing the bytes following the embedded time
+// (machine, process and counter, see Parse) instead of the host, the process and the global counter,
+// eg: a deterministic reader in tests. The unicity of the ids is then up to the reader, they are left out of
+// the overflow check (see EnableOverflowCheck). If the reader fails the id is generated as usual.
+func WithRandReader(r io.Reader) GeneratorOption {
+	return func(g *Generator) {
+		g.rand = r
+	}
 }
 
 // NewGenerator creates a new ID generator with prefix.
-// the prefix format will follow the partition convention as follows: <PREFIX>/<GLOBALLY_UNIQUE_ID>
-func NewGenerator(prefix string) *Generator {
-	return &Generator{prefix: prefix}
+// the prefix format will follow the partition convention as follows: <PREFIX>/<GLOBALLY_UNIQUE_ID>,
+// the delimiter and the encoding of the id can be changed with WithDelimiter and WithEncoding.
+func NewGenerator(prefix string, opts ...GeneratorOption) *Generator {
+	g := &Generator{
+		prefix:    prefix,
+		delimiter: defaultDelimiter,
+		encoding:  Base32Hex,
+	}
+	for _, o := range opts {
+		o(g)
+	}
+	return g
 }
 
 // Generate generates a prefixed globally unique ID.
 func (g *Generator) Generate() string {
-	id := New()
+	id := g.generate()
+	encoded := Encode(id.Bytes(), g.encoding)
 	if len(g.prefix) == 0 {
-		return id
+		return encoded
+	}
+	return g.prefix + g.delimiter + encoded
+}
+
+// generate generates an id from the sources of the generator.
+func (g *Generator) generate() xid.ID {
+	if g.now == nil && g.rand == nil {
+		return newID()
+	}
+
+	// the ids of the sources are counted, but not checked for overflows.
+	defer countGenerated()
+	now := time.Now
+	if g.now != nil {
+		now = g.now
+	}
+	if g.rand == nil {
+		return xid.NewWithTime(now())
+	}
+
+	var id xid.ID
+	binary.BigEndian.PutUint32(id[:4], uint32(now().Unix()))
+	if _, err := io.ReadFull(g.rand, id[4:]); err != nil {
+		return xid.NewWithTime(now())
+	}
+	return id
+}
+
+// Time returns the time embedded in an id generated by the generator, with 1 second precision.
+func (g *Generator) Time(id string) (time.Time, error) {
+	if len(g.prefix) > 0 {
+		trimmed := strings.TrimPrefix(id, g.prefix+g.delimiter)
+		if trimmed == id {
+			return time.Time{}, errors.Newf("id '%s' does not have the prefix '%s%s'", id, g.prefix, g.delimiter)
+		}
+		id = trimmed
+	}
+	raw, err := Decode(id, g.encoding)
+	if err != nil {
+		return time.Time{}, err
+	}
+	xID, err := xid.FromBytes(raw)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "invalid id '%s'", id)
 	}
-	return fmt.Sprintf("%s/%s", g.prefix, id)
+	return xID.Time(), nil
 }
@@ -1,10 +1,19 @@
 package id
 
 import (
+	"bytes"
+	"context"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/rs/xid"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 )
 
 func TestNew(t *testing.T) {
@@ -16,4 +25,265 @@ func TestGenerate(t *testing.T) {
 	generator := NewGenerator("test")
 	id := generator.Generate()
 	assert.True(t, strings.HasPrefix(id, "test/"))
+	// default output is unchanged.
+	_, err := xid.FromString(strings.TrimPrefix(id, "test/"))
+	assert.NoError(t, err)
+}
+
+func TestGenerator_Options(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	for _, enc := range []Encoding{Base32Hex, Base64URL, Hex, Base62} {
+		t.Run(enc.String(), func(t *testing.T) {
+			generator := NewGenerator("user", WithDelimiter("_"), WithEncoding(enc))
+			id := generator.Generate()
+			assert.True(t, strings.HasPrefix(id, "user_"), id)
+			assert.NotContains(t, id, "/")
+
+			ts, err := generator.Time(id)
+			assert.NoError(t, err)
+			assert.WithinDuration(t, now, ts, time.Second)
+		})
+	}
+
+	_, err := NewGenerator("user").Time("account/9m4e2mr0ui3e8a215n4g")
+	assert.Error(t, err)
+	ts, err := NewGenerator("").Time("9m4e2mr0ui3e8a215n4g")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1300816219), ts.Unix())
+}
+
+func TestGenerator_Ordered(t *testing.T) {
+	for _, enc := range []Encoding{Base32Hex, Hex, Base62} {
+		generator := NewGenerator("user", WithDelimiter(":"), WithEncoding(enc))
+		previous := generator.Generate()
+		for i := 0; i < 100; i++ {
+			id := generator.Generate()
+			assert.Less(t, previous, id, enc.String())
+			previous = id
+		}
+	}
+}
+
+func TestGenerator_Sources(t *testing.T) {
+	fixed := func() time.Time { return time.Unix(1600000000, 0) }
+	reader := func() *bytes.Reader { return bytes.NewReader([]byte{1, 2, 3, 4, 5, 6, 7, 8}) }
+
+	// fixed time and deterministic reader produce a known id.
+	generator := NewGenerator("user", WithTimeSource(fixed), WithRandReader(reader()))
+	assert.Equal(t, "user/btf10001081g81860s40", generator.Generate())
+	assert.Equal(t, "user/btf10001081g81860s40", NewGenerator("user", WithTimeSource(fixed), WithRandReader(reader())).Generate())
+	ts, err := generator.Time("user/btf10001081g81860s40")
+	assert.NoError(t, err)
+	assert.Equal(t, fixed(), ts)
+
+	// the reader is exhausted, the id is generated as usual with the fixed time.
+	id := generator.Generate()
+	assert.NotEqual(t, "user/btf10001081g81860s40", id)
+	ts, err = generator.Time(id)
+	assert.NoError(t, err)
+	assert.Equal(t, fixed(), ts)
+
+	// the time source alone fixes the embedded time.
+	ts, err = NewGenerator("", WithTimeSource(fixed)).Time(NewGenerator("", WithTimeSource(fixed)).Generate())
+	assert.NoError(t, err)
+	assert.Equal(t, fixed(), ts)
+}
+
+// resetWindow lowers the per second capacity for the duration of the test.
+func resetWindow(t *testing.T, capacity uint32) {
+	t.Helper()
+	previous := maxPerSecond
+	maxPerSecond = capacity
+	window.second, window.count = 0, 0
+	t.Cleanup(func() {
+		maxPerSecond = previous
+		window.second, window.count = 0, 0
+	})
+}
+
+func TestGenerate_Overflow(t *testing.T) {
+	resetWindow(t, 1000)
+
+	reader := sdkmetric.NewManualReader()
+	previous := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	defer otel.SetMeterProvider(previous)
+	assert.NoError(t, EnableMetrics())
+	defer func() {
+		generatedCounter.Store(nil)
+		overflowCounter.Store(nil)
+	}()
+
+	now := time.Unix(time.Now().Unix(), 0)
+	const workers, perWorker = 8, 500
+
+	var mu sync.Mutex
+	ids := map[xid.ID]struct{}{}
+	perSecond := map[int64]int{}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				id, _ := generate(now, true)
+				mu.Lock()
+				ids[id] = struct{}{}
+				perSecond[id.Time().Unix()]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, ids, workers*perWorker)
+	// 4000 ids with a capacity of 1000 per second rolled over the next 3 seconds.
+	assert.Equal(t, map[int64]int{
+		now.Unix():     1000,
+		now.Unix() + 1: 1000,
+		now.Unix() + 2: 1000,
+		now.Unix() + 3: 1000,
+	}, perSecond)
+
+	var rm metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &rm))
+	sums := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok {
+				for _, dp := range sum.DataPoints {
+					sums[m.Name] += dp.Value
+				}
+			}
+		}
+	}
+	assert.Equal(t, int64(workers*perWorker), sums["id.generated"])
+	assert.Equal(t, int64(3), sums["id.overflows"])
+}
+
+func TestEnableOverflowCheck(t *testing.T) {
+	resetWindow(t, 1000)
+	t.Cleanup(func() { overflowCheck.Store(false) })
+
+	// by default, the ids are not accounted.
+	New()
+	NewGenerator("user").Generate()
+	assert.Zero(t, window.count)
+
+	EnableOverflowCheck()
+	New()
+	NewGenerator("user").Generate()
+	assert.Equal(t, uint32(2), window.count)
+
+	// the ids of the sources stay out of the window.
+	NewGenerator("user", WithTimeSource(time.Now)).Generate()
+	assert.Equal(t, uint32(2), window.count)
+}
+
+func TestGenerator_SourcesCounted(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	previous := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	defer otel.SetMeterProvider(previous)
+	assert.NoError(t, EnableMetrics())
+	defer func() {
+		generatedCounter.Store(nil)
+		overflowCounter.Store(nil)
+	}()
+
+	New()
+	NewGenerator("user", WithTimeSource(time.Now)).Generate()
+	NewGenerator("user", WithRandReader(bytes.NewReader(make([]byte, 8)))).Generate()
+
+	var rm metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &rm))
+	var generated int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok && m.Name == "id.generated" {
+				for _, dp := range sum.DataPoints {
+					generated += dp.Value
+				}
+			}
+		}
+	}
+	assert.Equal(t, int64(3), generated)
+}
+
+func TestGenerate_Checked(t *testing.T) {
+	resetWindow(t, 1000)
+
+	now := time.Unix(time.Now().Unix(), 0)
+	const workers, perWorker = 16, 100
+
+	var mu sync.Mutex
+	ids := map[xid.ID]struct{}{}
+	overflows := 0
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				id, err := generate(now, false)
+				mu.Lock()
+				if errors.Is(err, ErrCounterOverflow) {
+					overflows++
+				} else if assert.NoError(t, err) {
+					ids[id] = struct{}{}
+					assert.Equal(t, now.Unix(), id.Time().Unix())
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the capacity of the second is used without collision, the rest is rejected.
+	assert.Len(t, ids, 1000)
+	assert.Equal(t, workers*perWorker-1000, overflows)
+
+	// the next second has a fresh capacity.
+	_, err := generate(now.Add(time.Second), false)
+	assert.NoError(t, err)
+}
+
+func TestNewChecked(t *testing.T) {
+	id, err := NewChecked()
+	assert.NoError(t, err)
+	assert.True(t, IsValid(id))
+}
+
+func TestGenerate_Stress(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping stress test in short mode")
+	}
+	resetWindow(t, maxPerSecond)
+
+	// generate slightly more ids than the counter capacity within the same second,
+	// the counter is tracked with a bitset per second to keep the memory usage low.
+	now := time.Unix(time.Now().Unix(), 0)
+	total := int(maxPerSecond) + 1000
+	seen := map[int64][]uint64{}
+	duplicates := 0
+	for i := 0; i < total; i++ {
+		id, _ := generate(now, true)
+		second := id.Time().Unix()
+		bits, ok := seen[second]
+		if !ok {
+			bits = make([]uint64, (1<<24)/64)
+			seen[second] = bits
+		}
+		counter := id.Counter()
+		if bits[counter/64]&(1<<(counter%64)) != 0 {
+			duplicates++
+		}
+		bits[counter/64] |= 1 << (counter % 64)
+	}
+
+	assert.Zero(t, duplicates)
+	assert.Len(t, seen, 2)
+	assert.Contains(t, seen, now.Unix()+1)
 }
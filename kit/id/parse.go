@@ -0,0 +1,114 @@
+package id
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/rs/xid"
+)
+
+var (
+	_ sql.Scanner   = (*ID)(nil)
+	_ driver.Valuer = ID{}
+)
+
+// encodedLen is the length of an id string generated by New.
+const encodedLen = 20
+
+// ID is a parsed globally unique id, exposing the fields embedded in it.
+type ID struct {
+	id xid.ID
+}
+
+// Parse parses an id string generated by New (base32hex encoded, without prefix).
+// Strings of the wrong length or with characters outside of the base32hex alphabet are rejected.
+func Parse(s string) (ID, error) {
+	if len(s) != encodedLen {
+		return ID{}, errors.Newf("parse id '%s': invalid length %d, expected %d", s, len(s), encodedLen)
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(base32HexAlphabet, s[i]) < 0 {
+			return ID{}, errors.Newf("parse id '%s': invalid character %q at position %d", s, s[i], i)
+		}
+	}
+	raw, err := Decode(s, Base32Hex)
+	if err != nil {
+		return ID{}, errors.Wrap(err, "parse id")
+	}
+	id, err := xid.FromBytes(raw)
+	if err != nil {
+		return ID{}, errors.Wrapf(err, "parse id '%s'", s)
+	}
+	return ID{id: id}, nil
+}
+
+// IsValid reports whether s is an id string generated by New.
+func IsValid(s string) bool {
+	_, err := Parse(s)
+	return err == nil
+}
+
+// Time returns the time the id has been generated at, with 1 second precision.
+func (id ID) Time() time.Time {
+	return id.id.Time()
+}
+
+// Machine returns the 3 bytes identifying the host that generated the id.
+func (id ID) Machine() []byte {
+	return id.id.Machine()
+}
+
+// Pid returns the id of the process that generated the id.
+func (id ID) Pid() uint16 {
+	return id.id.Pid()
+}
+
+// Counter returns the counter of the id, incremented for each id generated by the process.
+func (id ID) Counter() uint32 {
+	return uint32(id.id.Counter())
+}
+
+// String returns the id string, as generated by New.
+func (id ID) String() string {
+	return id.id.String()
+}
+
+// IsZero reports whether the id is the zero value, eg: scanned from a NULL column.
+func (id ID) IsZero() bool {
+	return id.id.IsNil()
+}
+
+// Value implements the driver.Valuer interface, the id is stored as its string (NULL for the zero id).
+func (id ID) Value() (driver.Value, error) {
+	if id.IsZero() {
+		return nil, nil
+	}
+	return id.String(), nil
+}
+
+// Scan implements the sql.Scanner interface, it accepts the string and []byte forms of an id.
+// NULL is scanned as the zero id.
+func (id *ID) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		*id = ID{}
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return errors.Newf("scan id: unsupported type %T", value)
+	}
+
+	parsed, err := Parse(s)
+	if err != nil {
+		return errors.Wrap(err, "scan id")
+	}
+	*id = parsed
+	return nil
+}
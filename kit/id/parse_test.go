@@ -0,0 +1,93 @@
+package id
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	s := New()
+	id, err := Parse(s)
+	require.NoError(t, err)
+
+	assert.Equal(t, s, id.String())
+	assert.WithinDuration(t, time.Now(), id.Time(), 2*time.Second)
+
+	expected, err := xid.FromString(s)
+	require.NoError(t, err)
+	assert.Equal(t, expected.Machine(), id.Machine())
+	assert.Equal(t, expected.Pid(), id.Pid())
+	assert.Equal(t, uint32(expected.Counter()), id.Counter())
+
+	// ids generated in a row have consecutive counters.
+	next, err := Parse(New())
+	require.NoError(t, err)
+	assert.Equal(t, (id.Counter()+1)&0xffffff, next.Counter())
+}
+
+func TestParse_Known(t *testing.T) {
+	id, err := Parse("9m4e2mr0ui3e8a215n4g")
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1300816219, 0), id.Time())
+	assert.Equal(t, []byte{0x60, 0xf4, 0x86}, id.Machine())
+	assert.Equal(t, uint16(0xe428), id.Pid())
+	assert.Equal(t, uint32(4271561), id.Counter())
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for name, s := range map[string]string{
+		"empty":     "",
+		"too short": "9m4e2mr0ui3e8a215n4",
+		"too long":  "9m4e2mr0ui3e8a215n4gg",
+		"prefixed":  "user/9m4e2mr0ui3e8a215n4g",
+		"uppercase": "9M4E2MR0UI3E8A215N4G",
+		"alphabet":  "9m4e2mr0ui3e8a215n4z",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse(s)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestIsValid(t *testing.T) {
+	assert.True(t, IsValid(New()))
+	assert.True(t, IsValid("9m4e2mr0ui3e8a215n4g"))
+	assert.False(t, IsValid(""))
+	assert.False(t, IsValid("user/9m4e2mr0ui3e8a215n4g"))
+}
+
+func TestID_ScanValue(t *testing.T) {
+	const s = "9m4e2mr0ui3e8a215n4g"
+
+	for name, value := range map[string]interface{}{
+		"string": s,
+		"bytes":  []byte(s),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var id ID
+			require.NoError(t, id.Scan(value))
+			assert.Equal(t, s, id.String())
+
+			v, err := id.Value()
+			require.NoError(t, err)
+			assert.Equal(t, s, v)
+		})
+	}
+
+	// NULL is the zero id.
+	id, err := Parse(s)
+	require.NoError(t, err)
+	require.NoError(t, id.Scan(nil))
+	assert.True(t, id.IsZero())
+	v, err := id.Value()
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+
+	assert.Error(t, id.Scan(42))
+	assert.Error(t, id.Scan("invalid"))
+}
@@ -0,0 +1,60 @@
+package log
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// callerSkip is the number of frames between the call site and the zap logger:
+// the public Logger method and log.
+const callerSkip = 2
+
+// encodings registered with the caller encoder, by name of the zap encoding.
+var callerEncodings = map[string]string{
+	"json":    "kit-json",
+	"console": "kit-console",
+}
+
+var registerCallerOnce sync.Once
+
+// registerCallerEncoders registers the json and console encoders adding the caller computed by zap
+// to the Attributes of the entries.
+func registerCallerEncoders() error {
+	var err error
+	registerCallerOnce.Do(func() {
+		err = zap.RegisterEncoder(callerEncodings["json"], func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+			return &callerEncoder{Encoder: zapcore.NewJSONEncoder(cfg)}, nil
+		})
+		if err != nil {
+			return
+		}
+		err = zap.RegisterEncoder(callerEncodings["console"], func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+			return &callerEncoder{Encoder: zapcore.NewConsoleEncoder(cfg)}, nil
+		})
+	})
+	return err
+}
+
+// callerEncoder is an encoder adding the caller of the entry to its Attributes as caller.full_path.
+type callerEncoder struct {
+	zapcore.Encoder
+}
+
+func (e *callerEncoder) Clone() zapcore.Encoder {
+	return &callerEncoder{Encoder: e.Encoder.Clone()}
+}
+
+func (e *callerEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	if entry.Caller.Defined {
+		for _, f := range fields {
+			if atts, ok := f.Interface.(*attributes); ok && f.Key == "Attributes" {
+				atts.Add(zap.String("caller.full_path", entry.Caller.FullPath()))
+				break
+			}
+		}
+	}
+	return e.Encoder.EncodeEntry(entry, fields)
+}
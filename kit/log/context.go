@@ -0,0 +1,52 @@
+package log
+
+import "context"
+
+// Context type for log fields
+type fieldsCtxKeyType string
+
+const fieldsCtxKey fieldsCtxKeyType = "fields"
+
+// ContextWithFields returns a copy of ctx carrying the given fields.
+// Fields carried by the context are added to every log written with it,
+// fields passed at the log site take precedence.
+func ContextWithFields(ctx context.Context, fields ...Field) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	existing := FieldsFromContext(ctx)
+	all := make([]Field, 0, len(existing)+len(fields))
+	all = append(all, existing...)
+	all = append(all, fields...)
+	return context.WithValue(ctx, fieldsCtxKey, all)
+}
+
+// FieldsFromContext returns the fields carried by the context.
+func FieldsFromContext(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsCtxKey).([]Field)
+	return fields
+}
+
+// Context type for the logger
+type loggerCtxKeyType string
+
+const loggerCtxKey loggerCtxKeyType = "logger"
+
+// ContextWithLogger returns a copy of ctx carrying the given logger, retrieved with FromContext.
+// It lets middlewares pass a request scoped logger (eg: with bound fields, see Logger.With) to the handlers.
+func ContextWithLogger(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, loggerCtxKey, logger)
+}
+
+// FromContext returns the logger carried by the context, or the global logger if none.
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerCtxKey).(*Logger); ok && l != nil {
+			return l
+		}
+	}
+	return L()
+}
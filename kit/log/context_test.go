@@ -0,0 +1,23 @@
+package log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromContext(t *testing.T) {
+	global := NewNop()
+	defer ReplaceGlobal(global)()
+
+	// falls back to the global logger.
+	assert.Same(t, global, FromContext(context.Background()))
+
+	logger := NewNop().With(String("request.id", "r1"))
+	ctx := ContextWithLogger(context.Background(), logger)
+	assert.Same(t, logger, FromContext(ctx))
+
+	// a nil logger is ignored.
+	assert.Same(t, global, FromContext(ContextWithLogger(context.Background(), nil)))
+}
@@ -0,0 +1,40 @@
+package log_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anthonycorbacho/workspace/kit/log"
+	prom "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap/zapcore"
+)
+
+// Count the emitted entries by level, eg: to alert on the error rate.
+func ExampleWithHook() {
+	entries := prom.NewCounterVec(prom.CounterOpts{
+		Name: "log_entries_total",
+		Help: "Number of log entries by level",
+	}, []string{"level"})
+
+	logger, err := log.New(
+		log.WithOutputPaths("/dev/null"),
+		log.WithHook(func(entry zapcore.Entry) error {
+			entries.WithLabelValues(entry.Level.String()).Inc()
+			return nil
+		}),
+	)
+	if err != nil {
+		// handle error
+	}
+	defer logger.Close()
+
+	logger.Info(context.Background(), "starting")
+	logger.Error(context.Background(), "failure")
+	logger.Error(context.Background(), "another failure")
+
+	var m dto.Metric
+	_ = entries.WithLabelValues("error").Write(&m)
+	fmt.Println(m.GetCounter().GetValue())
+	// Output: 2
+}
@@ -127,6 +127,18 @@ func Time(key string, val time.Time) Field {
 }
 
 // Any field.
+//
+// The fields of the structs tagged with `log:"redact"` are masked and the ones tagged with `log:"-"` are omitted,
+// including in the nested structs, pointers and slices:
+//
+//	type User struct {
+//		Name     string
+//		Password string `log:"redact"`
+//		Token    string `log:"-"`
+//	}
 func Any(key string, val interface{}) Field {
+	if f, ok := redactedField(key, val); ok {
+		return f
+	}
 	return zap.Any(key, val)
 }
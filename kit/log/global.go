@@ -1,6 +1,9 @@
 package log
 
-import "sync"
+import (
+	"net/http"
+	"sync"
+)
 
 var (
 	_globalMu sync.RWMutex
@@ -29,3 +32,11 @@ func ReplaceGlobal(logger *Logger) func() {
 		ReplaceGlobal(prev)
 	}
 }
+
+// LevelHandler returns an HTTP handler reading (GET) and changing (PUT) the level of the global Logger,
+// see Logger.LevelHandler.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		L().LevelHandler().ServeHTTP(w, r)
+	})
+}
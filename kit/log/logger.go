@@ -2,9 +2,12 @@ package log
 
 import (
 	"context"
-	"runtime"
+	"fmt"
+	"net/http"
+	"sync/atomic"
 
 	"github.com/anthonycorbacho/workspace/kit/config"
+	"github.com/anthonycorbacho/workspace/kit/errors"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -13,13 +16,23 @@ import (
 // A Logger provides fast, leveled, structured logging.
 // All methods are safe for concurrent use.
 type Logger struct {
-	log *zap.Logger
+	log   *zap.Logger
+	level zap.AtomicLevel
+	// fields bound to the logger, see With.
+	fields []Field
+	// files closed by Close, see WithRotatingFile.
+	files []*rotatingFile
+	// dropped counts the entries dropped by the sampling, see DroppedCount.
+	dropped *atomic.Uint64
+	// traceKeys are the keys of the span context fields, see WithTraceKeys.
+	traceKeys TraceKeys
 }
 
 // New is a reasonable production logging configuration.
 // Logging is enabled at InfoLevel and above by default.
 //
-// It uses a JSON encoder, writes to standard error, and enables sampling.
+// It uses a JSON encoder (see WithEncoding), writes to standard error (see WithOutputPaths),
+// and enables sampling (see WithSampling).
 // Stacktraces are automatically included on logs of ErrorLevel and above.
 func New(opts ...func(*Option)) (*Logger, error) {
 	level, err := parse(config.LookupEnv("FOUNDATION_LOG_LEVEL", "INFO"))
@@ -27,19 +40,55 @@ func New(opts ...func(*Option)) (*Logger, error) {
 		return nil, err
 	}
 
-	options := &Option{Level: level}
+	options := &Option{
+		Level:            level,
+		OutputPaths:      []string{"stderr"},
+		ErrorOutputPaths: []string{"stderr"},
+		Encoding:         "json",
+		Sampling:         &Sampling{Initial: 100, Thereafter: 100},
+		TimeEncoder:      EpochNanosTime(),
+		TraceKeys:        defaultTraceKeys,
+	}
 	for _, o := range opts {
 		o(options)
 	}
+	if len(options.OutputPaths) == 0 {
+		return nil, errors.New("at least one output path is required")
+	}
+	if len(options.ErrorOutputPaths) == 0 {
+		return nil, errors.New("at least one error output path is required")
+	}
+	if options.TimeEncoder == nil {
+		return nil, errors.New("time encoder is required")
+	}
+	if options.Encoding != "json" && options.Encoding != "console" {
+		return nil, errors.Newf("invalid encoding '%s', expected json or console", options.Encoding)
+	}
+	encodeLevel := zapcore.CapitalLevelEncoder
+	if options.Encoding == "console" && options.Color {
+		encodeLevel = zapcore.CapitalColorLevelEncoder
+	}
+
+	dropped := &atomic.Uint64{}
+	var sampling *zap.SamplingConfig
+	if options.Sampling != nil {
+		sampling = &zap.SamplingConfig{
+			Initial:    options.Sampling.Initial,
+			Thereafter: options.Sampling.Thereafter,
+			Hook: func(_ zapcore.Entry, decision zapcore.SamplingDecision) {
+				if decision&zapcore.LogDropped != 0 {
+					dropped.Add(1)
+				}
+			},
+		}
+	}
 
+	atomicLevel := zap.NewAtomicLevelAt(zapcore.Level(options.Level))
 	config := zap.Config{
-		Level:       zap.NewAtomicLevelAt(zapcore.Level(options.Level)),
+		Level:       atomicLevel,
 		Development: false,
-		Sampling: &zap.SamplingConfig{
-			Initial:    100,
-			Thereafter: 100,
-		},
-		Encoding: "json",
+		Sampling:    sampling,
+		Encoding:    callerEncodings[options.Encoding],
 		EncoderConfig: zapcore.EncoderConfig{
 			TimeKey:       "Timestamp",
 			LevelKey:      "Severity",
@@ -47,20 +96,38 @@ func New(opts ...func(*Option)) (*Logger, error) {
 			MessageKey:    "Body",
 			StacktraceKey: "Stacktrace",
 			LineEnding:    zapcore.DefaultLineEnding,
-			EncodeLevel:   zapcore.CapitalLevelEncoder,
-			EncodeTime:    zapcore.EpochNanosTimeEncoder,
+			EncodeLevel:   encodeLevel,
+			EncodeTime:    options.TimeEncoder,
 		},
-		OutputPaths:      []string{"stderr"},
-		ErrorOutputPaths: []string{"stderr"},
+		OutputPaths:      options.OutputPaths,
+		ErrorOutputPaths: options.ErrorOutputPaths,
 	}
 
-	log, err := config.Build()
+	if err := registerRotatingSink(); err != nil {
+		return nil, errors.Wrap(err, "register rotating file sink")
+	}
+	if err := registerCallerEncoders(); err != nil {
+		return nil, errors.Wrap(err, "register caller encoders")
+	}
+	buildOpts := []zap.Option{zap.AddCaller(), zap.AddCallerSkip(callerSkip + options.CallerSkip)}
+	if len(options.Hooks) > 0 {
+		buildOpts = append(buildOpts, zap.Hooks(options.Hooks...))
+	}
+	log, err := config.Build(buildOpts...)
+	files := claimRotatingFiles(options.OutputPaths)
 	if err != nil {
+		for _, f := range files {
+			_ = f.Close()
+		}
 		return nil, err
 	}
 
 	return &Logger{
-		log: log,
+		log:       log,
+		level:     atomicLevel,
+		files:     files,
+		dropped:   dropped,
+		traceKeys: options.TraceKeys,
 	}, nil
 }
 
@@ -68,11 +135,64 @@ func New(opts ...func(*Option)) (*Logger, error) {
 // and it never runs user-defined hooks.
 func NewNop() *Logger {
 	return &Logger{
-		log: zap.NewNop(),
+		log:       zap.NewNop(),
+		level:     zap.NewAtomicLevel(),
+		dropped:   &atomic.Uint64{},
+		traceKeys: defaultTraceKeys,
+	}
+}
+
+// SetLevel changes the level of the logger while running, the loggers created with With share it.
+// It is safe for concurrent use.
+func (l *Logger) SetLevel(level Level) {
+	l.level.SetLevel(zapcore.Level(level))
+}
+
+// Level returns the current level of the logger.
+func (l *Logger) Level() Level {
+	return Level(l.level.Level())
+}
+
+// LevelHandler returns an HTTP handler reading (GET) and changing (PUT) the level of the logger, eg:
+//
+//	curl -X PUT -d '{"level":"debug"}' localhost:9091/debug/log/level
+func (l *Logger) LevelHandler() http.Handler {
+	return l.level
+}
+
+// DroppedCount returns the number of entries dropped by the sampling since the logger has been created,
+// the loggers created with With share it.
+// A count growing quickly means the service is logging too much and entries are silently lost.
+func (l *Logger) DroppedCount() uint64 {
+	return l.dropped.Load()
+}
+
+// With creates a child logger with the given fields bound to it,
+// they are added to the Attributes of every log entry, along with the fields of the call and of the context.
+func (l *Logger) With(fields ...Field) *Logger {
+	bound := make([]Field, 0, len(l.fields)+len(fields))
+	bound = append(bound, l.fields...)
+	bound = append(bound, fields...)
+	return &Logger{
+		log:       l.log,
+		level:     l.level,
+		fields:    bound,
+		dropped:   l.dropped,
+		traceKeys: l.traceKeys,
 	}
 }
 
-// Close is flushing any buffered log entries.
+// withFields returns the fields bound to the logger followed by the given fields.
+func (l *Logger) withFields(fields []Field) []Field {
+	if len(l.fields) == 0 {
+		return fields
+	}
+	all := make([]Field, 0, len(l.fields)+len(fields))
+	all = append(all, l.fields...)
+	return append(all, fields...)
+}
+
+// Close is flushing any buffered log entries, and closes the rotating files.
 // Applications should take care to call Close before exiting.
 func (l *Logger) Close() {
 	if l.log == nil {
@@ -80,30 +200,33 @@ func (l *Logger) Close() {
 	}
 
 	_ = l.log.Sync() //nolint
+	for _, f := range l.files {
+		_ = f.Close() //nolint
+	}
 }
 
 // Debug logs a message at DebugLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func (l *Logger) Debug(ctx context.Context, message string, fields ...Field) {
-	log(l.log.Debug, ctx, message, fields...)
+	log(l.log.Debug, l.traceKeys, ctx, message, l.withFields(fields)...)
 }
 
 // Info logs a message at InfoLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func (l *Logger) Info(ctx context.Context, message string, fields ...Field) {
-	log(l.log.Info, ctx, message, fields...)
+	log(l.log.Info, l.traceKeys, ctx, message, l.withFields(fields)...)
 }
 
 // Warn logs a message at WarnLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func (l *Logger) Warn(ctx context.Context, message string, fields ...Field) {
-	log(l.log.Warn, ctx, message, fields...)
+	log(l.log.Warn, l.traceKeys, ctx, message, l.withFields(fields)...)
 }
 
 // Error logs a message at ErrorLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func (l *Logger) Error(ctx context.Context, message string, fields ...Field) {
-	log(l.log.Error, ctx, message, fields...)
+	log(l.log.Error, l.traceKeys, ctx, message, l.withFields(fields)...)
 }
 
 // Fatal logs a message at FatalLevel. The message includes any fields passed
@@ -112,11 +235,32 @@ func (l *Logger) Error(ctx context.Context, message string, fields ...Field) {
 // The logger then calls os.Exit(1), even if logging at FatalLevel is
 // disabled.
 func (l *Logger) Fatal(ctx context.Context, message string, fields ...Field) {
-	log(l.log.Fatal, ctx, message, fields...)
+	log(l.log.Fatal, l.traceKeys, ctx, message, l.withFields(fields)...)
+}
+
+// Debugf formats the message according to the format specifier and logs it at DebugLevel.
+func (l *Logger) Debugf(ctx context.Context, format string, args ...interface{}) {
+	// log is called directly to keep the caller depth, see callerSkip.
+	log(l.log.Debug, l.traceKeys, ctx, fmt.Sprintf(format, args...), l.fields...)
 }
 
-func log(fn func(msg string, fields ...Field), ctx context.Context, msg string, fields ...Field) { //nolint
-	attributes := attributeFields(fields...)
+// Infof formats the message according to the format specifier and logs it at InfoLevel.
+func (l *Logger) Infof(ctx context.Context, format string, args ...interface{}) {
+	log(l.log.Info, l.traceKeys, ctx, fmt.Sprintf(format, args...), l.fields...)
+}
+
+// Warnf formats the message according to the format specifier and logs it at WarnLevel.
+func (l *Logger) Warnf(ctx context.Context, format string, args ...interface{}) {
+	log(l.log.Warn, l.traceKeys, ctx, fmt.Sprintf(format, args...), l.fields...)
+}
+
+// Errorf formats the message according to the format specifier and logs it at ErrorLevel.
+func (l *Logger) Errorf(ctx context.Context, format string, args ...interface{}) {
+	log(l.log.Error, l.traceKeys, ctx, fmt.Sprintf(format, args...), l.fields...)
+}
+
+func log(fn func(msg string, fields ...Field), keys TraceKeys, ctx context.Context, msg string, fields ...Field) { //nolint
+	attributes := attributeFields(ctx, fields...)
 	span := trace.SpanFromContext(ctx)
 
 	// If trace information is not set (non trace context)
@@ -129,19 +273,27 @@ func log(fn func(msg string, fields ...Field), ctx context.Context, msg string,
 		return
 	}
 
+	spanFields := make([]Field, 0, 4)
+	if keys.Trace != "" {
+		spanFields = append(spanFields, String(keys.Trace, span.SpanContext().TraceID().String()))
+	}
+	if keys.Span != "" {
+		spanFields = append(spanFields, String(keys.Span, span.SpanContext().SpanID().String()))
+	}
+	if keys.Flags != "" {
+		spanFields = append(spanFields, String(keys.Flags, span.SpanContext().TraceFlags().String()))
+	}
 	fn(
 		msg,
-		String("TraceId", span.SpanContext().TraceID().String()),
-		String("SpanId", span.SpanContext().SpanID().String()),
-		String("TraceFlags", span.SpanContext().TraceFlags().String()),
-		attributeField(attributes),
+		append(spanFields, attributeField(attributes))...,
 	)
 }
 
-func attributeFields(fields ...Field) *attributes {
+func attributeFields(ctx context.Context, fields ...Field) *attributes {
 	atts := newAttributes()
-	caller := zapcore.NewEntryCaller(runtime.Caller(3))
-	atts.Add(zap.String("caller.full_path", caller.FullPath()))
+	for _, f := range FieldsFromContext(ctx) {
+		atts.Add(f)
+	}
 	for _, f := range fields {
 		atts.Add(f)
 	}
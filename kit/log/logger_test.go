@@ -0,0 +1,404 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestWithOutputPaths(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.log")
+	second := filepath.Join(dir, "second.log")
+
+	logger, err := New(WithOutputPaths(first, second), WithErrorOutputPaths(first))
+	if !assert.NoError(t, err) {
+		return
+	}
+	logger.Info(context.Background(), "to every sink")
+	logger.Close()
+
+	for _, path := range []string{first, second} {
+		b, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.True(t, strings.Contains(string(b), `"Body":"to every sink"`), path)
+	}
+}
+
+func TestWithOutputPaths_Required(t *testing.T) {
+	_, err := New(WithOutputPaths())
+	assert.Error(t, err)
+
+	_, err = New(WithErrorOutputPaths())
+	assert.Error(t, err)
+}
+
+func TestWithEncoding(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "console.log")
+	logger, err := New(WithOutputPaths(path), WithEncoding("console"), WithColor(true))
+	if !assert.NoError(t, err) {
+		return
+	}
+	logger.Warn(context.Background(), "readable")
+	logger.Close()
+
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "readable")
+	assert.False(t, strings.HasPrefix(string(b), "{"))
+	// colored level
+	assert.Contains(t, string(b), "\x1b[33mWARN\x1b[0m")
+
+	_, err = New(WithEncoding("xml"))
+	assert.Error(t, err)
+}
+
+func TestWith(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "with.log")
+	logger, err := New(WithOutputPaths(path))
+	if !assert.NoError(t, err) {
+		return
+	}
+	child := logger.With(String("user.id", "u1"))
+	grandchild := child.With(String("request.id", "r1"))
+
+	ctx := ContextWithFields(context.Background(), String("from", "context"))
+	grandchild.Info(ctx, "bound", Int("call", 1))
+	logger.Info(ctx, "parent")
+	logger.Close()
+
+	b, err := os.ReadFile(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if !assert.Len(t, lines, 2) {
+		return
+	}
+
+	var entry struct {
+		Body       string
+		Attributes map[string]interface{}
+	}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "bound", entry.Body)
+	assert.Equal(t, "u1", entry.Attributes["user.id"])
+	assert.Equal(t, "r1", entry.Attributes["request.id"])
+	assert.Equal(t, "context", entry.Attributes["from"])
+	assert.Equal(t, float64(1), entry.Attributes["call"])
+
+	// the parent logger is not affected.
+	entry.Attributes = nil
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.NotContains(t, entry.Attributes, "user.id")
+}
+
+func TestPrintf(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "printf.log")
+	logger, err := New(WithOutputPaths(path), WithLevel(DebugLevel))
+	if !assert.NoError(t, err) {
+		return
+	}
+	logger.With(String("user.id", "u1")).Infof(context.Background(), "hello %s, %d", "world", 42)
+	_, _, line, _ := runtime.Caller(0)
+	logger.Close()
+
+	b, err := os.ReadFile(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var entry struct {
+		Body       string
+		Attributes map[string]interface{}
+	}
+	assert.NoError(t, json.Unmarshal(b, &entry))
+	assert.Equal(t, "hello world, 42", entry.Body)
+	assert.Equal(t, "u1", entry.Attributes["user.id"])
+	// the caller is the call site, not the formatting method.
+	assert.True(t, strings.HasSuffix(entry.Attributes["caller.full_path"].(string), fmt.Sprintf("logger_test.go:%d", line-1)),
+		entry.Attributes["caller.full_path"])
+}
+
+func TestLevelHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "level.log")
+	logger, err := New(WithOutputPaths(path))
+	if !assert.NoError(t, err) {
+		return
+	}
+	child := logger.With(String("user.id", "u1"))
+	assert.Equal(t, InfoLevel, logger.Level())
+
+	child.Debug(context.Background(), "hidden")
+	logger.SetLevel(DebugLevel)
+	assert.Equal(t, DebugLevel, child.Level())
+	child.Debug(context.Background(), "visible")
+
+	// read and change the level over HTTP
+	defer ReplaceGlobal(logger)()
+	rec := httptest.NewRecorder()
+	LevelHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"level":"debug"`)
+
+	rec = httptest.NewRecorder()
+	LevelHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"level":"warn"}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, WarnLevel, logger.Level())
+
+	logger.Close()
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), "hidden")
+	assert.Contains(t, string(b), "visible")
+}
+
+func TestSampling(t *testing.T) {
+	count := func(opts ...func(*Option)) int {
+		path := filepath.Join(t.TempDir(), "sampling.log")
+		logger, err := New(append(opts, WithOutputPaths(path))...)
+		if !assert.NoError(t, err) {
+			return 0
+		}
+		for i := 0; i < 300; i++ {
+			logger.Error(context.Background(), "burst")
+		}
+		logger.Close()
+		b, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		return strings.Count(string(b), `"Body":"burst"`)
+	}
+
+	// default sampling: the first 100, then every 100th.
+	assert.Equal(t, 102, count())
+	assert.Equal(t, 12, count(WithSampling(10, 100)))
+	assert.Equal(t, 300, count(WithoutSampling()))
+}
+
+func TestWithHook(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hook.log")
+	errPath := filepath.Join(dir, "errors.log")
+	var levels []zapcore.Level
+	logger, err := New(WithOutputPaths(path), WithErrorOutputPaths(errPath), WithLevel(DebugLevel),
+		WithHook(func(entry zapcore.Entry) error {
+			levels = append(levels, entry.Level)
+			return nil
+		}),
+		WithHook(func(entry zapcore.Entry) error {
+			return fmt.Errorf("hook failure")
+		}),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	logger.Debug(context.Background(), "debug")
+	logger.Warn(context.Background(), "warn")
+	logger.Close()
+
+	assert.Equal(t, []zapcore.Level{zapcore.DebugLevel, zapcore.WarnLevel}, levels)
+
+	// hook errors do not prevent the entries from being logged.
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"Body":"debug"`)
+	assert.Contains(t, string(b), `"Body":"warn"`)
+	b, err = os.ReadFile(errPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "hook failure")
+}
+
+func TestDroppedCount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dropped.log")
+	logger, err := New(WithOutputPaths(path), WithSampling(1, 1000))
+	if !assert.NoError(t, err) {
+		return
+	}
+	child := logger.With(String("component", "test"))
+	assert.Equal(t, uint64(0), logger.DroppedCount())
+
+	for i := 0; i < 50; i++ {
+		child.Info(context.Background(), "burst")
+	}
+	logger.Info(context.Background(), "other")
+	logger.Close()
+
+	// only the first entry of each message is kept, the dropped count is shared with the child logger.
+	assert.Equal(t, uint64(49), logger.DroppedCount())
+	assert.Equal(t, uint64(49), child.DroppedCount())
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(b), `"Body":"burst"`))
+	assert.Equal(t, 1, strings.Count(string(b), `"Body":"other"`))
+
+	assert.Equal(t, uint64(0), NewNop().DroppedCount())
+}
+
+func TestCaller(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "caller.log")
+	logger, err := New(WithOutputPaths(path), WithLevel(DebugLevel), WithoutSampling())
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, file, line, _ := runtime.Caller(0)
+	logger.Debug(context.Background(), "debug")
+	logger.Info(context.Background(), "info")
+	logger.Warn(context.Background(), "warn")
+	logger.Error(context.Background(), "error")
+	logger.With(String("user.id", "u1")).Info(context.Background(), "with")
+	logger.Close()
+
+	b, err := os.ReadFile(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if !assert.Len(t, lines, 5) {
+		return
+	}
+	for i, l := range lines {
+		var entry struct {
+			Body       string
+			Attributes map[string]interface{}
+		}
+		assert.NoError(t, json.Unmarshal([]byte(l), &entry))
+		assert.Equal(t, fmt.Sprintf("%s:%d", file, line+i+1), entry.Attributes["caller.full_path"], entry.Body)
+	}
+}
+
+// wrapper is a user defined wrapper around the Logger.
+type wrapper struct {
+	logger *Logger
+}
+
+func (w wrapper) info(msg string) {
+	w.logger.Info(context.Background(), msg)
+}
+
+func TestWithCallerSkip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "skip.log")
+	logger, err := New(WithOutputPaths(path), WithCallerSkip(1))
+	if !assert.NoError(t, err) {
+		return
+	}
+	wrapper{logger: logger}.info("wrapped")
+	_, file, line, _ := runtime.Caller(0)
+	logger.Close()
+
+	b, err := os.ReadFile(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var entry struct {
+		Attributes map[string]interface{}
+	}
+	assert.NoError(t, json.Unmarshal(b, &entry))
+	// the caller is the call site of the wrapper.
+	assert.Equal(t, fmt.Sprintf("%s:%d", file, line-1), entry.Attributes["caller.full_path"])
+}
+
+func TestWithTimeEncoder(t *testing.T) {
+	timestamp := func(opts ...func(*Option)) interface{} {
+		path := filepath.Join(t.TempDir(), "time.log")
+		logger, err := New(append(opts, WithOutputPaths(path))...)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		logger.Info(context.Background(), "hello")
+		logger.Close()
+
+		b, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal(b, &entry))
+		return entry["Timestamp"]
+	}
+
+	// epoch nanoseconds by default.
+	_, ok := timestamp().(float64)
+	assert.True(t, ok)
+
+	ts, ok := timestamp(WithTimeEncoder(RFC3339Time())).(string)
+	if assert.True(t, ok) {
+		parsed, err := time.Parse(time.RFC3339Nano, ts)
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), parsed, time.Minute)
+	}
+
+	ts, ok = timestamp(WithTimeEncoder(ISO8601Time())).(string)
+	if assert.True(t, ok) {
+		parsed, err := time.Parse("2006-01-02T15:04:05.000Z0700", ts)
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), parsed, time.Minute)
+	}
+
+	_, err := New(WithTimeEncoder(nil))
+	assert.Error(t, err)
+}
+
+func TestWithTraceKeys(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	tests := map[string]struct {
+		opts     []func(*Option)
+		expected map[string]interface{}
+		missing  []string
+	}{
+		"default": {
+			expected: map[string]interface{}{"TraceId": traceID.String(), "SpanId": spanID.String(), "TraceFlags": "01"},
+		},
+		"remapped": {
+			opts:     []func(*Option){WithTraceKeys("trace_id", "span_id", "trace_flags")},
+			expected: map[string]interface{}{"trace_id": traceID.String(), "span_id": spanID.String(), "trace_flags": "01"},
+			missing:  []string{"TraceId", "SpanId", "TraceFlags"},
+		},
+		"omitted flags": {
+			opts:     []func(*Option){WithTraceKeys("dd.trace_id", "dd.span_id", "")},
+			expected: map[string]interface{}{"dd.trace_id": traceID.String(), "dd.span_id": spanID.String()},
+			missing:  []string{"TraceFlags", ""},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "trace.log")
+			logger, err := New(append(tc.opts, WithOutputPaths(path))...)
+			if !assert.NoError(t, err) {
+				return
+			}
+			// the loggers created with With keep the keys.
+			logger.With(String("bound", "value")).Info(ctx, "traced")
+			logger.Close()
+
+			b, err := os.ReadFile(path)
+			if !assert.NoError(t, err) {
+				return
+			}
+			var entry map[string]interface{}
+			assert.NoError(t, json.Unmarshal(b, &entry))
+			for k, v := range tc.expected {
+				assert.Equal(t, v, entry[k], k)
+			}
+			for _, k := range tc.missing {
+				assert.NotContains(t, entry, k)
+			}
+		})
+	}
+}
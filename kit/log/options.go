@@ -1,9 +1,56 @@
 package log
 
+import "go.uber.org/zap/zapcore"
+
 // Option provide a set of optional configuration
 // that can be provided when creating a logger.
 type Option struct {
 	Level Level
+	// OutputPaths are the URLs or file paths the logs are written to, stderr by default.
+	OutputPaths []string
+	// ErrorOutputPaths are the URLs or file paths the logger internal errors are written to, stderr by default.
+	ErrorOutputPaths []string
+	// Encoding of the logs, "json" (default) or "console".
+	Encoding string
+	// Color colorizes the level of the logs with the console encoding.
+	Color bool
+	// TimeEncoder formats the Timestamp of the entries, epoch nanoseconds by default.
+	TimeEncoder zapcore.TimeEncoder
+	// CallerSkip is the number of additional frames to skip when reporting the caller.
+	CallerSkip int
+	// Hooks are called for every entry emitted.
+	Hooks []func(zapcore.Entry) error
+	// TraceKeys are the keys of the span context fields, TraceId, SpanId and TraceFlags by default.
+	TraceKeys TraceKeys
+	// Sampling of the logs, nil disables it.
+	// By default, the first 100 entries with the same level and message are logged each second, then every 100th.
+	Sampling *Sampling
+}
+
+// Sampling caps the number of logged entries with the same level and message per second:
+// the first Initial entries are logged, then every Thereafter entry.
+type Sampling struct {
+	Initial    int
+	Thereafter int
+}
+
+// TraceKeys are the keys of the span context fields added to the entries logged with a traced context.
+// An empty key omits the field.
+type TraceKeys struct {
+	Trace string
+	Span  string
+	Flags string
+}
+
+var defaultTraceKeys = TraceKeys{Trace: "TraceId", Span: "SpanId", Flags: "TraceFlags"}
+
+// WithTraceKeys set up the keys of the trace id, span id and trace flags fields to match the APM backend
+// (eg: "trace_id", "span_id" and "trace_flags"), an empty key omits the field.
+// By default, they are TraceId, SpanId and TraceFlags.
+func WithTraceKeys(traceKey, spanKey, flagsKey string) func(*Option) {
+	return func(o *Option) {
+		o.TraceKeys = TraceKeys{Trace: traceKey, Span: spanKey, Flags: flagsKey}
+	}
 }
 
 // WithLevel set up the logger log level.
@@ -12,3 +59,88 @@ func WithLevel(level Level) func(*Option) {
 		o.Level = level
 	}
 }
+
+// WithOutputPaths set up the URLs or file paths the logs are written to (eg: "stderr", "/var/log/service.log").
+// At least one path is required.
+func WithOutputPaths(paths ...string) func(*Option) {
+	return func(o *Option) {
+		o.OutputPaths = append([]string{}, paths...)
+	}
+}
+
+// WithErrorOutputPaths set up the URLs or file paths the logger internal errors are written to.
+// At least one path is required.
+func WithErrorOutputPaths(paths ...string) func(*Option) {
+	return func(o *Option) {
+		o.ErrorOutputPaths = append([]string{}, paths...)
+	}
+}
+
+// WithEncoding set up the logs encoding: "json" (default) or "console" for human-readable logs.
+func WithEncoding(encoding string) func(*Option) {
+	return func(o *Option) {
+		o.Encoding = encoding
+	}
+}
+
+// WithColor colorizes the logs level, only applies to the console encoding.
+func WithColor(color bool) func(*Option) {
+	return func(o *Option) {
+		o.Color = color
+	}
+}
+
+// WithSampling set up the logs sampling, see Sampling.
+func WithSampling(initial, thereafter int) func(*Option) {
+	return func(o *Option) {
+		o.Sampling = &Sampling{Initial: initial, Thereafter: thereafter}
+	}
+}
+
+// WithoutSampling disables the logs sampling, every entry is logged.
+func WithoutSampling() func(*Option) {
+	return func(o *Option) {
+		o.Sampling = nil
+	}
+}
+
+// WithTimeEncoder set up the format of the Timestamp of the entries, eg:
+//
+//	log.New(log.WithTimeEncoder(log.RFC3339Time()))
+func WithTimeEncoder(enc zapcore.TimeEncoder) func(*Option) {
+	return func(o *Option) {
+		o.TimeEncoder = enc
+	}
+}
+
+// EpochNanosTime formats the Timestamp as the number of nanoseconds since the epoch, the default.
+func EpochNanosTime() zapcore.TimeEncoder {
+	return zapcore.EpochNanosTimeEncoder
+}
+
+// RFC3339Time formats the Timestamp as a RFC3339 string with nanoseconds (eg: 2006-01-02T15:04:05.999999999Z07:00).
+func RFC3339Time() zapcore.TimeEncoder {
+	return zapcore.RFC3339NanoTimeEncoder
+}
+
+// ISO8601Time formats the Timestamp as an ISO8601 string with milliseconds (eg: 2006-01-02T15:04:05.000Z0700).
+func ISO8601Time() zapcore.TimeEncoder {
+	return zapcore.ISO8601TimeEncoder
+}
+
+// WithCallerSkip increases the number of frames skipped when reporting the caller (caller.full_path),
+// so that the caller of a wrapper around the Logger is reported instead of the wrapper itself.
+func WithCallerSkip(skip int) func(*Option) {
+	return func(o *Option) {
+		o.CallerSkip = skip
+	}
+}
+
+// WithHook registers a function called for every emitted entry, regardless of its level
+// (eg: counting the logged errors). Entries dropped by the level or the sampling are not emitted.
+// Errors returned by the hook are reported to the error output paths, they do not prevent the entry from being logged.
+func WithHook(hook func(entry zapcore.Entry) error) func(*Option) {
+	return func(o *Option) {
+		o.Hooks = append(o.Hooks, hook)
+	}
+}
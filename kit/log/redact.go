@@ -0,0 +1,232 @@
+package log
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redactedValue replaces the value of the fields tagged with `log:"redact"`.
+const redactedValue = "[REDACTED]"
+
+// redactTypes caches whether a type has fields tagged with `log`, directly or in its nested structs, slices and maps.
+var redactTypes sync.Map
+
+// redactable reports whether values of t must be marshalled by redactObject.
+func redactable(t reflect.Type) bool {
+	if cached, ok := redactTypes.Load(t); ok {
+		return cached.(bool)
+	}
+	has := hasLogTags(t, map[reflect.Type]bool{})
+	redactTypes.Store(t, has)
+	return has
+}
+
+// hasLogTags reports whether t has fields tagged with `log`, the visited types are not inspected again.
+// An interface may hold a value with tags: its dynamic type is checked once marshalled.
+func hasLogTags(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return hasLogTags(t.Elem(), visited)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if _, ok := f.Tag.Lookup("log"); ok || hasLogTags(f.Type, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// redactObject marshals a struct honoring the `log` tags of its fields:
+// `log:"redact"` masks the value and `log:"-"` omits the field.
+// The fields are named after their json tag, like the other values logged with Any.
+type redactObject struct {
+	value reflect.Value
+}
+
+func (r redactObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	t := r.value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+
+		switch f.Tag.Get("log") {
+		case "-":
+			continue
+		case "redact":
+			enc.AddString(name, redactedValue)
+			continue
+		}
+
+		v := r.value.Field(i)
+		if f.Anonymous && v.Kind() == reflect.Struct && name == f.Name {
+			if err := (redactObject{value: v}).MarshalLogObject(enc); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := addRedacted(enc, name, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redactArray marshals a slice or an array of values honoring their `log` tags.
+type redactArray struct {
+	value reflect.Value
+}
+
+func (r redactArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for i := 0; i < r.value.Len(); i++ {
+		v := indirect(r.value.Index(i))
+		var err error
+		switch {
+		case v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface:
+			err = enc.AppendReflected(nil)
+		case v.Kind() == reflect.Struct && redactable(v.Type()):
+			err = enc.AppendObject(redactObject{value: v})
+		case (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && redactable(v.Type()):
+			err = enc.AppendArray(redactArray{value: v})
+		case v.Kind() == reflect.Map && !v.IsNil() && redactable(v.Type()):
+			err = enc.AppendObject(redactMap{value: v})
+		default:
+			err = enc.AppendReflected(v.Interface())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redactMap marshals a map honoring the `log` tags of its values, sorted by key.
+type redactMap struct {
+	value reflect.Value
+}
+
+func (r redactMap) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	keys := make([]string, 0, r.value.Len())
+	values := make(map[string]reflect.Value, r.value.Len())
+	iter := r.value.MapRange()
+	for iter.Next() {
+		key := mapKey(iter.Key())
+		keys = append(keys, key)
+		values[key] = iter.Value()
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := addRedacted(enc, key, values[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mapKey returns the name of a map key, like encoding/json.
+func mapKey(k reflect.Value) string {
+	if k.Kind() == reflect.String {
+		return k.String()
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if b, err := tm.MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(k.Interface())
+}
+
+// indirect returns the value v points to, through the pointers and interfaces until a nil one.
+func indirect(v reflect.Value) reflect.Value {
+	for (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+// addRedacted adds the value to the encoder, honoring the `log` tags of the nested structs.
+// The values held by an interface are redacted according to their dynamic type.
+func addRedacted(enc zapcore.ObjectEncoder, key string, v reflect.Value) error {
+	v = indirect(v)
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface || !redactable(v.Type()) {
+		return enc.AddReflected(key, v.Interface())
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return enc.AddObject(key, redactObject{value: v})
+	case reflect.Map:
+		if v.IsNil() {
+			return enc.AddReflected(key, nil)
+		}
+		return enc.AddObject(key, redactMap{value: v})
+	case reflect.Slice:
+		if v.IsNil() {
+			return enc.AddReflected(key, nil)
+		}
+	}
+	return enc.AddArray(key, redactArray{value: v})
+}
+
+// redactedField returns the field of a value having `log` tags, or false if the value has none.
+func redactedField(key string, val interface{}) (Field, bool) {
+	if val == nil {
+		return Field{}, false
+	}
+	switch val.(type) {
+	case zapcore.ObjectMarshaler, zapcore.ArrayMarshaler:
+		return Field{}, false
+	}
+
+	v := reflect.ValueOf(val)
+	if !redactable(v.Type()) {
+		return Field{}, false
+	}
+	v = indirect(v)
+	switch v.Kind() {
+	case reflect.Struct:
+		return zap.Object(key, redactObject{value: v}), true
+	case reflect.Map:
+		if v.IsNil() {
+			return Field{}, false
+		}
+		return zap.Object(key, redactMap{value: v}), true
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return Field{}, false
+		}
+		return zap.Array(key, redactArray{value: v}), true
+	}
+	return Field{}, false
+}
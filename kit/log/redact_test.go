@@ -0,0 +1,138 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type credentials struct {
+	Login    string `json:"login"`
+	Password string `json:"password" log:"redact"`
+}
+
+type account struct {
+	Name        string
+	Token       string `log:"-"`
+	APIKey      string `json:"api_key" log:"redact"`
+	Credentials *credentials
+	History     []credentials
+	private     string
+}
+
+func TestAny_Redact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redact.log")
+	logger, err := New(WithOutputPaths(path))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	a := account{
+		Name:        "alice",
+		Token:       "secret-token",
+		APIKey:      "secret-key",
+		Credentials: &credentials{Login: "alice", Password: "secret-password"},
+		History:     []credentials{{Login: "old", Password: "secret-old-password"}},
+		private:     "private",
+	}
+	logger.Info(context.Background(), "account", Any("account", a), Any("pointer", &a), Any("untagged", struct{ Value string }{"plain"}))
+	logger.Close()
+
+	b, err := os.ReadFile(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, secret := range []string{"secret-token", "secret-key", "secret-password", "secret-old-password"} {
+		assert.NotContains(t, string(b), secret)
+	}
+
+	var entry struct {
+		Attributes map[string]interface{}
+	}
+	assert.NoError(t, json.Unmarshal(b, &entry))
+	for _, key := range []string{"account", "pointer"} {
+		logged, _ := entry.Attributes[key].(map[string]interface{})
+		assert.Equal(t, "alice", logged["Name"], key)
+		assert.NotContains(t, logged, "Token", key)
+		assert.NotContains(t, logged, "private", key)
+		assert.Equal(t, redactedValue, logged["api_key"], key)
+		assert.Equal(t, map[string]interface{}{"login": "alice", "password": redactedValue}, logged["Credentials"], key)
+		assert.Equal(t, []interface{}{map[string]interface{}{"login": "old", "password": redactedValue}}, logged["History"], key)
+	}
+	assert.Equal(t, map[string]interface{}{"Value": "plain"}, entry.Attributes["untagged"])
+}
+
+type vault struct {
+	Owners  map[string]credentials `json:"owners"`
+	Current interface{}            `json:"current"`
+	Missing interface{}            `json:"missing"`
+}
+
+func TestAny_RedactMapAndInterface(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redact.log")
+	logger, err := New(WithOutputPaths(path))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	v := vault{
+		Owners:  map[string]credentials{"alice": {Login: "alice", Password: "secret-alice"}},
+		Current: &credentials{Login: "bob", Password: "secret-bob"},
+	}
+	var current interface{} = credentials{Login: "carol", Password: "secret-carol"}
+	logger.Info(context.Background(), "vault",
+		Any("vault", v),
+		Any("owners", map[string]*credentials{"dave": {Login: "dave", Password: "secret-dave"}}),
+		Any("values", []interface{}{current, "plain"}),
+		Any("plain", map[string]interface{}{"b": 2, "a": "one"}),
+	)
+	logger.Close()
+
+	b, err := os.ReadFile(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, secret := range []string{"secret-alice", "secret-bob", "secret-carol", "secret-dave"} {
+		assert.NotContains(t, string(b), secret)
+	}
+
+	var entry struct {
+		Attributes map[string]interface{}
+	}
+	assert.NoError(t, json.Unmarshal(b, &entry))
+	assert.Equal(t, map[string]interface{}{
+		"owners":  map[string]interface{}{"alice": map[string]interface{}{"login": "alice", "password": redactedValue}},
+		"current": map[string]interface{}{"login": "bob", "password": redactedValue},
+		"missing": nil,
+	}, entry.Attributes["vault"])
+	assert.Equal(t, map[string]interface{}{"dave": map[string]interface{}{"login": "dave", "password": redactedValue}}, entry.Attributes["owners"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"login": "carol", "password": redactedValue}, "plain"}, entry.Attributes["values"])
+	assert.Equal(t, map[string]interface{}{"a": "one", "b": float64(2)}, entry.Attributes["plain"])
+}
+
+type node struct {
+	Next   *node
+	Secret credentials
+}
+
+func TestRedactable(t *testing.T) {
+	assert.True(t, redactable(reflect.TypeOf(account{})))
+	assert.True(t, redactable(reflect.TypeOf(&account{})))
+	assert.True(t, redactable(reflect.TypeOf([]credentials{})))
+	assert.False(t, redactable(reflect.TypeOf(struct{ Value string }{})))
+	assert.False(t, redactable(reflect.TypeOf("")))
+
+	assert.True(t, redactable(reflect.TypeOf(map[string]credentials{})))
+	assert.False(t, redactable(reflect.TypeOf(map[string]string{})))
+	// the dynamic type of an interface is only known once marshalled.
+	assert.True(t, redactable(reflect.TypeOf(struct{ Value interface{} }{})))
+
+	// the tags found after a recursive field.
+	assert.True(t, redactable(reflect.TypeOf(node{})))
+	assert.True(t, redactable(reflect.TypeOf(&node{})))
+}
@@ -0,0 +1,124 @@
+package log
+
+import (
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"go.uber.org/zap"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// rotateScheme is the zap sink scheme of the rotating files, see WithRotatingFile.
+const rotateScheme = "kitrotate"
+
+var (
+	registerRotateOnce sync.Once
+	// rotatingFiles are the files opened by the sink factory, by url, until claimed by a Logger.
+	rotatingFiles   = map[string]*rotatingFile{}
+	rotatingFilesMu sync.Mutex
+)
+
+// WithRotatingFile writes the logs to the file at path, rotated by lumberjack once it reaches maxSizeMB megabytes.
+// At most maxBackups rotated files are kept (0 keeps them all), and rotated files older than
+// maxAgeDays days are removed (0 keeps them regardless of their age).
+// Rotated files are named after the file with the rotation time, eg: service-2006-01-02T15-04-05.000.log.
+//
+// The file is added to the output paths, the logs are still written to the other paths (stderr by default).
+func WithRotatingFile(path string, maxSizeMB, maxBackups, maxAgeDays int) func(*Option) {
+	return func(o *Option) {
+		q := url.Values{}
+		q.Set("maxsize", strconv.Itoa(maxSizeMB))
+		q.Set("maxbackups", strconv.Itoa(maxBackups))
+		q.Set("maxage", strconv.Itoa(maxAgeDays))
+		u := url.URL{Scheme: rotateScheme, Path: path, RawQuery: q.Encode()}
+		o.OutputPaths = append(o.OutputPaths, u.String())
+	}
+}
+
+// registerRotatingSink registers the sink opening the rotating files.
+func registerRotatingSink() error {
+	var err error
+	registerRotateOnce.Do(func() {
+		err = zap.RegisterSink(rotateScheme, func(u *url.URL) (zap.Sink, error) {
+			maxSize, err := strconv.Atoi(u.Query().Get("maxsize"))
+			if err != nil || maxSize <= 0 {
+				return nil, errors.Newf("invalid rotating file max size '%s'", u.Query().Get("maxsize"))
+			}
+			maxBackups, _ := strconv.Atoi(u.Query().Get("maxbackups"))
+			maxAge, _ := strconv.Atoi(u.Query().Get("maxage"))
+
+			f, err := openRotatingFile(&lumberjack.Logger{
+				Filename:   u.Path,
+				MaxSize:    maxSize,
+				MaxBackups: maxBackups,
+				MaxAge:     maxAge,
+			})
+			if err != nil {
+				return nil, err
+			}
+			rotatingFilesMu.Lock()
+			rotatingFiles[u.String()] = f
+			rotatingFilesMu.Unlock()
+			return f, nil
+		})
+	})
+	return err
+}
+
+// claimRotatingFiles returns the rotating files opened for the given output paths.
+func claimRotatingFiles(paths []string) []*rotatingFile {
+	rotatingFilesMu.Lock()
+	defer rotatingFilesMu.Unlock()
+
+	var files []*rotatingFile
+	for _, p := range paths {
+		if f, ok := rotatingFiles[p]; ok {
+			files = append(files, f)
+			delete(rotatingFiles, p)
+		}
+	}
+	return files
+}
+
+// rotatingFile is a zap.Sink writing to a file rotated by lumberjack.
+// The writes are rejected once closed, lumberjack opens the file again otherwise.
+type rotatingFile struct {
+	mu     sync.RWMutex
+	file   *lumberjack.Logger
+	closed bool
+}
+
+// openRotatingFile opens (or creates) the file, so an invalid path is reported when the logger is created.
+func openRotatingFile(file *lumberjack.Logger) (*rotatingFile, error) {
+	if _, err := file.Write(nil); err != nil {
+		return nil, errors.Wrap(err, "open log file")
+	}
+	return &rotatingFile{file: file}, nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	return f.file.Write(p)
+}
+
+// Sync is a no-op, lumberjack writes to the file without buffering.
+func (f *rotatingFile) Sync() error {
+	return nil
+}
+
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	return f.file.Close()
+}
@@ -0,0 +1,127 @@
+package log
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+func TestWithRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "service.log")
+	logger, err := New(WithOutputPaths(filepath.Join(dir, "other.log")), WithRotatingFile(path, 1, 1, 0))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// write more than 1MB, distinct messages are not sampled.
+	payload := strings.Repeat("x", 1024)
+	for i := 0; i < 1500; i++ {
+		logger.Infof(context.Background(), "entry %d %s", i, payload)
+	}
+	logger.Close()
+
+	backups, err := filepath.Glob(filepath.Join(dir, "service-*.log"))
+	assert.NoError(t, err)
+	assert.Len(t, backups, 1)
+
+	info, err := os.Stat(path)
+	if assert.NoError(t, err) {
+		assert.LessOrEqual(t, info.Size(), int64(1024*1024))
+	}
+
+	// closed by Close
+	rotating := logger.files
+	if assert.Len(t, rotating, 1) {
+		_, err = rotating[0].Write([]byte("closed"))
+		assert.ErrorIs(t, err, os.ErrClosed)
+	}
+}
+
+// rotate rotates the file n times, the rotated file names have a millisecond precision.
+func rotate(t *testing.T, f *rotatingFile, n int) {
+	for i := 0; i < n; i++ {
+		_, err := f.Write([]byte("0123456789"))
+		assert.NoError(t, err)
+		assert.NoError(t, f.file.Rotate())
+		time.Sleep(2 * time.Millisecond)
+	}
+}
+
+// backups returns the rotated files of the file at path once at most max remain,
+// lumberjack removes the expired backups in the background.
+func backups(t *testing.T, path string, max int) []string {
+	pattern := strings.TrimSuffix(path, ".log") + "-*T*.log"
+	var matches []string
+	assert.Eventually(t, func() bool {
+		var err error
+		matches, err = filepath.Glob(pattern)
+		return err == nil && len(matches) <= max
+	}, 5*time.Second, 10*time.Millisecond)
+	return matches
+}
+
+func TestRotatingFile_MaxBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "small.log")
+	f, err := openRotatingFile(&lumberjack.Logger{Filename: path, MaxSize: 1, MaxBackups: 2})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer f.Close()
+
+	rotate(t, f, 5)
+	assert.Len(t, backups(t, path, 2), 2)
+}
+
+func TestRotatingFile_KeepsOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	// files next to the log that are not backups of it.
+	others := []string{"app-audit.log", "app-2006-01-02.log", "app-.log"}
+	for _, name := range others {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("keep"), 0o644))
+	}
+
+	f, err := openRotatingFile(&lumberjack.Logger{Filename: path, MaxSize: 1, MaxBackups: 1})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer f.Close()
+
+	rotate(t, f, 4)
+	assert.Len(t, backups(t, path, 1), 1)
+	for _, name := range others {
+		assert.FileExists(t, filepath.Join(dir, name))
+	}
+}
+
+func TestRotatingFile_Closed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "closed.log")
+	f, err := openRotatingFile(&lumberjack.Logger{Filename: path, MaxSize: 1})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.FileExists(t, path)
+	assert.NoError(t, f.Close())
+
+	// the file is not opened again.
+	assert.NoError(t, os.Remove(path))
+	_, err = f.Write([]byte("closed"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+	assert.NoFileExists(t, path)
+}
+
+func TestWithRotatingFile_InvalidPath(t *testing.T) {
+	// the parent of the log file is a file.
+	parent := filepath.Join(t.TempDir(), "file")
+	assert.NoError(t, os.WriteFile(parent, nil, 0o644))
+
+	_, err := New(WithOutputPaths(filepath.Join(t.TempDir(), "other.log")), WithRotatingFile(filepath.Join(parent, "service.log"), 1, 1, 0))
+	assert.Error(t, err)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/anthonycorbacho/workspace/kit/log"
 	"github.com/rs/cors"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
 // FoundationOptions provides a set of configurable options for Foundation.
@@ -18,6 +19,14 @@ type FoundationOptions struct {
 	httpWriteTimeout time.Duration
 	httpReadTimeout  time.Duration
 	logger           *log.Logger
+	allowEmpty       bool
+	drainDelay       time.Duration
+	singlePort       bool
+	accessLogger     *log.Logger
+	gatewayMarshal   protojson.MarshalOptions
+	// noTelemetry disables the tracer and meter setup, used by tests
+	// serving multiple foundations in the same process.
+	noTelemetry bool
 }
 
 // Option defines a Foundation option.
@@ -58,6 +67,18 @@ func WithHTTPAddr(addr string) Option {
 	}
 }
 
+// WithSinglePort serves both the gRPC and HTTP servers on the given host and port.
+// Like cmux, the cleartext HTTP/2 connections whose first request has the `content-type: application/grpc`
+// header are routed to the gRPC server, the other connections to the HTTP server (HTTP/1 or h2c).
+// It overrides WithGrpcAddr and WithHTTPAddr.
+func WithSinglePort(addr string) Option {
+	return func(fo *FoundationOptions) {
+		fo.singlePort = true
+		fo.grpcAddr = addr
+		fo.httpAddr = addr
+	}
+}
+
 // WithHTTPWriteTimeout defines write timeout for the HTTP server.
 func WithHTTPWriteTimeout(timeout time.Duration) Option {
 	return func(fo *FoundationOptions) {
@@ -72,6 +93,40 @@ func WithHTTPReadTimeout(timeout time.Duration) Option {
 	}
 }
 
+// WithDrainDelay defines how long the Foundation waits, once a shutdown signal is received,
+// between reporting itself as not ready (readiness probe and gRPC health) and stopping the servers.
+// It gives time to load balancers to stop routing new traffic to the service.
+func WithDrainDelay(d time.Duration) Option {
+	return func(fo *FoundationOptions) {
+		fo.drainDelay = d
+	}
+}
+
+// AllowEmpty allows the Foundation to serve without any registered gRPC service or HTTP handler,
+// only the internal server (health probes and profiling) and the gRPC health service will be started.
+func AllowEmpty() Option {
+	return func(fo *FoundationOptions) {
+		fo.allowEmpty = true
+	}
+}
+
+// WithAccessLog logs a summary of each HTTP request served (method, route, status, latency, bytes and client IP)
+// with the given logger, health and internal paths are not logged.
+func WithAccessLog(l *log.Logger) Option {
+	return func(fo *FoundationOptions) {
+		fo.accessLogger = l
+	}
+}
+
+// WithGatewayMarshaler defines how the grpc-gateway marshals the JSON responses.
+// By default, the fields use their proto names (eg: `user_id`) and the zero values are emitted,
+// eg: protojson.MarshalOptions{} uses the JSON camelCase names (eg: `userId`) and omits the zero values.
+func WithGatewayMarshaler(opts protojson.MarshalOptions) Option {
+	return func(fo *FoundationOptions) {
+		fo.gatewayMarshal = opts
+	}
+}
+
 func WithLogger(logger *log.Logger) Option {
 	return func(fo *FoundationOptions) {
 		fo.logger = logger
@@ -0,0 +1,42 @@
+package pagination
+
+import (
+	"fmt"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	rpcerrdetails "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+)
+
+// PageSize returns the page size to use for the requested page size:
+// def when requested is not set (lower or equal to 0), capped to max.
+func PageSize(requested, def, max int) int {
+	if requested <= 0 {
+		requested = def
+	}
+	if requested > max {
+		return max
+	}
+	return requested
+}
+
+// StrictPageSize returns the page size to use for the requested page size like PageSize,
+// but rejects a negative page size or a page size exceeding max with an InvalidArgument status
+// carrying a BadRequest detail on the page_size field.
+func StrictPageSize(requested, def, max int) (int, error) {
+	var description string
+	switch {
+	case requested < 0:
+		description = fmt.Sprintf("page size %d must not be negative", requested)
+	case requested > max:
+		description = fmt.Sprintf("page size %d exceeds the maximum of %d", requested, max)
+	default:
+		return PageSize(requested, def, max), nil
+	}
+
+	return 0, errors.Status(codes.InvalidArgument, description, &rpcerrdetails.BadRequest{
+		FieldViolations: []*rpcerrdetails.BadRequest_FieldViolation{
+			{Field: "page_size", Description: description},
+		},
+	})
+}
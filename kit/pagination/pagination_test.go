@@ -3,8 +3,12 @@ package pagination
 import (
 	"testing"
 
+	"github.com/anthonycorbacho/workspace/kit/errors"
 	pb "github.com/anthonycorbacho/workspace/kit/pagination/v1"
 	"github.com/stretchr/testify/assert"
+	rpcerrdetails "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestEncodeDecodeToken(t *testing.T) {
@@ -99,3 +103,44 @@ func TestBatcher(t *testing.T) {
 		})
 	}
 }
+
+func TestPageSize(t *testing.T) {
+	var cases = []struct {
+		name      string
+		requested int
+		want      int
+		strictErr bool
+	}{
+		{name: "zero uses the default", requested: 0, want: 20},
+		{name: "negative uses the default", requested: -1, want: 20, strictErr: true},
+		{name: "within range", requested: 42, want: 42},
+		{name: "max", requested: 100, want: 100},
+		{name: "over max is clamped", requested: 101, want: 100, strictErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, PageSize(tc.requested, 20, 100))
+
+			got, err := StrictPageSize(tc.requested, 20, 100)
+			if !tc.strictErr {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, got)
+				return
+			}
+			assert.Equal(t, codes.InvalidArgument, errors.Code(err))
+			st, _ := status.FromError(err)
+			if assert.Len(t, st.Details(), 1) {
+				br, ok := st.Details()[0].(*rpcerrdetails.BadRequest)
+				if assert.True(t, ok) {
+					assert.Equal(t, "page_size", br.GetFieldViolations()[0].GetField())
+				}
+			}
+		})
+	}
+}
+
+func TestPageSize_DefaultOverMax(t *testing.T) {
+	// the default is also capped.
+	assert.Equal(t, 10, PageSize(0, 20, 10))
+}
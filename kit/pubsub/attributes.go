@@ -0,0 +1,55 @@
+package pubsub
+
+import (
+	"context"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+)
+
+// reservedAttributes are the attribute keys set by the publishers to carry the topic and the trace.
+var reservedAttributes = map[string]bool{
+	"topic":        true,
+	"subject":      true,
+	"trace":        true,
+	"span":         true,
+	"trace-state":  true,
+	"trace-remote": true,
+}
+
+// ValidateAttributes returns ReservedAttribute if one of the attribute keys is reserved by the publishers
+// (topic, subject, trace, span, trace-state and trace-remote).
+func ValidateAttributes(attrs map[string]string) error {
+	for k := range attrs {
+		if reservedAttributes[k] {
+			return errors.Wrapf(ReservedAttribute, "key '%s'", k)
+		}
+	}
+	return nil
+}
+
+// Context type for attributes
+type attributesCtxKeyType string
+
+const attributesCtxKey attributesCtxKeyType = "attributes"
+
+// WithAttributes inject to the given context the attributes of a received message,
+// the reserved attributes are left out.
+func WithAttributes(ctx context.Context, attrs map[string]string) context.Context {
+	user := make(map[string]string, len(attrs))
+	for k, v := range attrs {
+		if !reservedAttributes[k] {
+			user[k] = v
+		}
+	}
+	return context.WithValue(ctx, attributesCtxKey, user)
+}
+
+// GetAttributes get the attributes of the received message from the context (see PublishWithAttributes).
+// If the context doesnt have attributes set, then the value returned will be nil.
+func GetAttributes(ctx context.Context) map[string]string {
+	attrs, ok := ctx.Value(attributesCtxKey).(map[string]string)
+	if !ok {
+		return nil
+	}
+	return attrs
+}
@@ -0,0 +1,27 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAttributes(t *testing.T) {
+	assert.NoError(t, ValidateAttributes(nil))
+	assert.NoError(t, ValidateAttributes(map[string]string{"tenant": "acme"}))
+
+	for _, key := range []string{"topic", "subject", "trace", "span", "trace-state", "trace-remote"} {
+		err := ValidateAttributes(map[string]string{"tenant": "acme", key: "value"})
+		assert.True(t, errors.Is(err, ReservedAttribute), key)
+	}
+}
+
+func TestAttributesCtx(t *testing.T) {
+	assert.Nil(t, GetAttributes(context.Background()))
+
+	// the reserved attributes set by the publishers are left out.
+	ctx := WithAttributes(context.Background(), map[string]string{"tenant": "acme", "topic": "a.topic", "trace": "abc"})
+	assert.Equal(t, map[string]string{"tenant": "acme"}, GetAttributes(ctx))
+}
@@ -0,0 +1,92 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// ReceivedMessage is a message received on the channel of Subscriber.SubscribeChan.
+// The receiver is responsible for acking (or nacking) it, eg: once handled by its own worker pool.
+type ReceivedMessage struct {
+	// Data of the message.
+	Data Message
+	// Topic of the message, see GetTopic.
+	Topic string
+	// Attributes of the message, see GetAttributes.
+	Attributes map[string]string
+
+	ack  func()
+	nack func()
+}
+
+// Ack acknowledges the message.
+func (m ReceivedMessage) Ack() {
+	m.ack()
+}
+
+// Nack negatively acknowledges the message, it is redelivered.
+func (m ReceivedMessage) Nack() {
+	m.nack()
+}
+
+// SubscribeChan subscribes with subscribe (eg: the SubscribeWithAck of a Subscriber) a handler sending the messages
+// on the returned channel, for the subscribers implementing Subscriber.SubscribeChan.
+// The handler blocks until the message is received from the channel, so the receiver controls the pace
+// of the deliveries (backpressure).
+//
+// The channel is closed once ctx is done or closing is closed (eg: the subscriber is closing),
+// the messages delivered from then on are nacked for redelivery.
+func SubscribeChan(ctx context.Context, subscription string, closing <-chan struct{}, subscribe func(ctx context.Context, subscription string, handler HandlerWithAck) error) (<-chan ReceivedMessage, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	handler, ch := chanHandler(ctx, cancel, closing)
+	if err := subscribe(ctx, subscription, handler); err != nil {
+		cancel()
+		return nil, err
+	}
+	return ch, nil
+}
+
+// chanHandler returns the handler sending the messages on the returned channel,
+// the channel is closed and cancel called once ctx is done or closing is closed.
+func chanHandler(ctx context.Context, cancel context.CancelFunc, closing <-chan struct{}) (HandlerWithAck, <-chan ReceivedMessage) {
+	ch := make(chan ReceivedMessage)
+
+	// the sends hold the read lock, the channel is closed once they gave up.
+	var mu sync.RWMutex
+	closed := false
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-closing:
+			cancel()
+		}
+		mu.Lock()
+		closed = true
+		close(ch)
+		mu.Unlock()
+	}()
+
+	handler := func(hctx context.Context, msg Message, ack func(), nack func()) error {
+		mu.RLock()
+		defer mu.RUnlock()
+		if closed {
+			nack()
+			return nil
+		}
+
+		m := ReceivedMessage{
+			Data:       msg,
+			Topic:      GetTopic(hctx),
+			Attributes: GetAttributes(hctx),
+			ack:        ack,
+			nack:       nack,
+		}
+		select {
+		case ch <- m:
+		case <-ctx.Done():
+			nack()
+		}
+		return nil
+	}
+	return handler, ch
+}
@@ -0,0 +1,89 @@
+package pubsub
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribeChan(t *testing.T) {
+	var handler HandlerWithAck
+	subscribe := func(ctx context.Context, subscription string, h HandlerWithAck) error {
+		handler = h
+		return nil
+	}
+	closing := make(chan struct{})
+	ch, err := SubscribeChan(context.Background(), "a.topic", closing, subscribe)
+	assert.NoError(t, err)
+
+	var acked, nacked atomic.Int32
+	ack := func() { acked.Add(1) }
+	nack := func() { nacked.Add(1) }
+
+	ctx := WithAttributes(WithTopic(context.Background(), "a.topic"), map[string]string{"tenant": "acme"})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, handler(ctx, []byte("test"), ack, nack))
+	}()
+
+	select {
+	case m := <-ch:
+		assert.Equal(t, "test", m.Data.String())
+		assert.Equal(t, "a.topic", m.Topic)
+		assert.Equal(t, map[string]string{"tenant": "acme"}, m.Attributes)
+		m.Ack()
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout waiting")
+	}
+	<-done
+	assert.Equal(t, int32(1), acked.Load())
+
+	// the channel is closed once closing, the next messages are nacked.
+	close(closing)
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout waiting for the channel to close")
+	}
+	assert.NoError(t, handler(ctx, []byte("late"), ack, nack))
+	assert.Equal(t, int32(1), nacked.Load())
+}
+
+func TestSubscribeChan_PendingNacked(t *testing.T) {
+	var handler HandlerWithAck
+	subscribe := func(ctx context.Context, subscription string, h HandlerWithAck) error {
+		handler = h
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := SubscribeChan(ctx, "a.topic", nil, subscribe)
+	assert.NoError(t, err)
+
+	// the message is not received, it is nacked once ctx is done.
+	nacked := make(chan struct{})
+	go func() {
+		_ = handler(context.Background(), []byte("test"), func() {}, func() { close(nacked) })
+	}()
+	cancel()
+
+	select {
+	case <-nacked:
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout waiting for the nack")
+	}
+}
+
+func TestSubscribeChan_Error(t *testing.T) {
+	subscribe := func(ctx context.Context, subscription string, h HandlerWithAck) error {
+		return errors.New("subscription init failed")
+	}
+	ch, err := SubscribeChan(context.Background(), "a.topic", nil, subscribe)
+	assert.Error(t, err)
+	assert.Nil(t, ch)
+}
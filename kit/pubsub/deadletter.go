@@ -0,0 +1,175 @@
+package pubsub
+
+import (
+	"context"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// maxTrackedAttempts is the maximum number of messages whose attempts are counted by the process.
+	maxTrackedAttempts = 10_000
+	// trackedAttemptsTTL is the time the attempts of a message are counted by the process after its last failure.
+	trackedAttemptsTTL = time.Hour
+)
+
+// WithDeadLetter returns a wrapper of a Handler forwarding the messages it keeps failing on
+// to the dead letter topic dlqTopic, instead of redelivering them forever:
+//   - the message is acked when the handler succeeds,
+//   - the message is nacked for redelivery when the handler fails less than maxAttempts times,
+//   - on the maxAttempts-th failure, the original message is published on dlqTopic with the "error" and "attempts"
+//     attributes (see GetAttributes) and acked. If it cannot be published, the message is nacked.
+//
+// The attempts are read from the received message (see DeliveryAttempt), eg: NATS JetStream, or Google Cloud Pub/Sub
+// with a dead letter policy. Otherwise, they are counted by the process, which has some limitations:
+// the messages are identified by their topic and content (the messages with the same content share their count),
+// the redeliveries to another replica are not counted, and the counts are forgotten after an hour without failure
+// or once 10000 messages are tracked (the oldest first).
+//
+// It can be used with any Subscriber, eg: s.SubscribeWithAck(ctx, sub, pubsub.WithDeadLetter(p, "orders.dlq", 5)(handler)).
+func WithDeadLetter(publisher Publisher, dlqTopic string, maxAttempts int) func(handler Handler) HandlerWithAck {
+	counter, err := otel.Meter("kit/pubsub").Int64Counter("pubsub.handler.dead_letters",
+		metric.WithDescription("Number of messages forwarded to a dead letter topic"),
+	)
+	if err != nil {
+		counter = nil
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return func(handler Handler) HandlerWithAck {
+		attempts := newAttemptCounter(maxTrackedAttempts, trackedAttemptsTTL)
+
+		return func(ctx context.Context, msg Message, ack func(), nack func()) error {
+			attempt, received := DeliveryAttempt(ctx)
+			var key uint64
+			if !received {
+				key = attempts.key(GetTopic(ctx), msg)
+			}
+
+			err := handler(ctx, msg)
+			if err == nil {
+				if !received {
+					attempts.forget(key)
+				}
+				ack()
+				return nil
+			}
+
+			if !received {
+				attempt = attempts.increment(key)
+			}
+			if attempt < maxAttempts {
+				nack()
+				return err
+			}
+
+			attrs := map[string]string{
+				"error":    err.Error(),
+				"attempts": strconv.Itoa(attempt),
+			}
+			if perr := publisher.PublishWithAttributes(ctx, dlqTopic, msg, attrs); perr != nil {
+				nack()
+				return errors.Join(err, errors.Wrapf(perr, "forward to dead letter topic '%s'", dlqTopic))
+			}
+			if !received {
+				attempts.forget(key)
+			}
+			ack()
+
+			trace.SpanFromContext(ctx).SetAttributes(attribute.String("handler.dead_letter", dlqTopic))
+			if counter != nil {
+				counter.Add(ctx, 1, metric.WithAttributes(attribute.String("topic", GetTopic(ctx))))
+			}
+			return err
+		}
+	}
+}
+
+// attemptCounter counts the failed attempts of the messages by topic and content,
+// when the subscriber does not provide the delivery attempt.
+// At most max messages are tracked, for ttl after their last failure.
+type attemptCounter struct {
+	mu     sync.Mutex
+	max    int
+	ttl    time.Duration
+	counts map[uint64]*attempts
+	now    func() time.Time
+}
+
+// attempts are the failed attempts of a message.
+type attempts struct {
+	count    int
+	lastFail time.Time
+}
+
+func newAttemptCounter(max int, ttl time.Duration) *attemptCounter {
+	return &attemptCounter{
+		max:    max,
+		ttl:    ttl,
+		counts: map[uint64]*attempts{},
+		now:    time.Now,
+	}
+}
+
+func (c *attemptCounter) key(topic string, msg Message) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(topic))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write(msg)
+	return h.Sum64()
+}
+
+func (c *attemptCounter) increment(key uint64) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	a, ok := c.counts[key]
+	if ok && now.Sub(a.lastFail) > c.ttl {
+		ok = false
+	}
+	if !ok {
+		if _, tracked := c.counts[key]; !tracked && len(c.counts) >= c.max {
+			c.evict(now)
+		}
+		a = &attempts{}
+		c.counts[key] = a
+	}
+	a.count++
+	a.lastFail = now
+	return a.count
+}
+
+// evict removes the expired messages, or the oldest one if none expired.
+func (c *attemptCounter) evict(now time.Time) {
+	var oldest uint64
+	var oldestFail time.Time
+	for key, a := range c.counts {
+		if now.Sub(a.lastFail) > c.ttl {
+			delete(c.counts, key)
+			continue
+		}
+		if oldestFail.IsZero() || a.lastFail.Before(oldestFail) {
+			oldest, oldestFail = key, a.lastFail
+		}
+	}
+	if len(c.counts) >= c.max {
+		delete(c.counts, oldest)
+	}
+}
+
+func (c *attemptCounter) forget(key uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.counts, key)
+}
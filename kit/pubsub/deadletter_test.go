@@ -0,0 +1,141 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingPublisher records the published messages, failing with err if set.
+type recordingPublisher struct {
+	err       error
+	published []publication
+}
+
+type publication struct {
+	topic string
+	msg   Message
+	attrs map[string]string
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, topic string, msg Message) error {
+	return p.PublishWithAttributes(ctx, topic, msg, nil)
+}
+
+func (p *recordingPublisher) PublishWithAttributes(_ context.Context, topic string, msg Message, attrs map[string]string) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, publication{topic: topic, msg: msg, attrs: attrs})
+	return nil
+}
+
+func (p *recordingPublisher) Close() error {
+	return nil
+}
+
+func TestWithDeadLetter(t *testing.T) {
+	publisher := &recordingPublisher{}
+	errInvalid := errors.New("invalid")
+	h := WithDeadLetter(publisher, "orders.dlq", 3)(func(ctx context.Context, msg Message) error {
+		return errInvalid
+	})
+
+	ctx := WithTopic(context.Background(), "orders")
+	for attempt := 1; attempt <= 3; attempt++ {
+		acked, nacked := false, false
+		err := h(ctx, Message("poison"), func() { acked = true }, func() { nacked = true })
+		assert.Equal(t, errInvalid, err)
+
+		// redelivered until the last attempt, then forwarded and acked.
+		last := attempt == 3
+		assert.Equal(t, last, acked, "attempt %d", attempt)
+		assert.Equal(t, !last, nacked, "attempt %d", attempt)
+	}
+
+	if assert.Len(t, publisher.published, 1) {
+		assert.Equal(t, publication{
+			topic: "orders.dlq",
+			msg:   Message("poison"),
+			attrs: map[string]string{"error": "invalid", "attempts": "3"},
+		}, publisher.published[0])
+	}
+
+	// the count restarts for a new delivery of the same message.
+	nacked := false
+	_ = h(ctx, Message("poison"), func() {}, func() { nacked = true })
+	assert.True(t, nacked)
+}
+
+func TestWithDeadLetter_DeliveryAttempt(t *testing.T) {
+	publisher := &recordingPublisher{}
+	h := WithDeadLetter(publisher, "orders.dlq", 5)(func(ctx context.Context, msg Message) error {
+		return errors.New("invalid")
+	})
+
+	// the attempt of the received message is used.
+	acked := false
+	ctx := WithDeliveryAttempt(WithTopic(context.Background(), "orders"), 5)
+	_ = h(ctx, Message("poison"), func() { acked = true }, func() {})
+	assert.True(t, acked)
+	if assert.Len(t, publisher.published, 1) {
+		assert.Equal(t, "5", publisher.published[0].attrs["attempts"])
+	}
+}
+
+func TestWithDeadLetter_Success(t *testing.T) {
+	publisher := &recordingPublisher{}
+	h := WithDeadLetter(publisher, "orders.dlq", 1)(func(ctx context.Context, msg Message) error {
+		return nil
+	})
+
+	acked := false
+	assert.NoError(t, h(context.Background(), Message("test"), func() { acked = true }, func() {}))
+	assert.True(t, acked)
+	assert.Empty(t, publisher.published)
+}
+
+func TestWithDeadLetter_PublishFailure(t *testing.T) {
+	errUnavailable := errors.New("unavailable")
+	errInvalid := errors.New("invalid")
+	h := WithDeadLetter(&recordingPublisher{err: errUnavailable}, "orders.dlq", 1)(func(ctx context.Context, msg Message) error {
+		return errInvalid
+	})
+
+	// the message is kept for redelivery.
+	acked, nacked := false, false
+	err := h(context.Background(), Message("poison"), func() { acked = true }, func() { nacked = true })
+	assert.True(t, errors.Is(err, errInvalid))
+	assert.True(t, errors.Is(err, errUnavailable))
+	assert.False(t, acked)
+	assert.True(t, nacked)
+}
+
+func TestAttemptCounter_Bounded(t *testing.T) {
+	now := time.Now()
+	c := newAttemptCounter(2, time.Minute)
+	c.now = func() time.Time { return now }
+
+	assert.Equal(t, 1, c.increment(1))
+	now = now.Add(time.Second)
+	assert.Equal(t, 1, c.increment(2))
+	assert.Equal(t, 2, c.increment(2))
+
+	// the oldest message is evicted once the maximum is reached.
+	now = now.Add(time.Second)
+	assert.Equal(t, 1, c.increment(3))
+	assert.Len(t, c.counts, 2)
+	assert.NotContains(t, c.counts, uint64(1))
+
+	// the count restarts once expired.
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, 1, c.increment(2))
+	// the expired messages are evicted first.
+	assert.Equal(t, 1, c.increment(4))
+	assert.Len(t, c.counts, 2)
+	assert.Contains(t, c.counts, uint64(2))
+	assert.Contains(t, c.counts, uint64(4))
+}
@@ -2,8 +2,17 @@ package pubsub
 
 // Pubsub errors.
 const (
-	PublisherClosed  = Error("publisher is closed")
-	SubscriberCLosed = Error("subscriber is closed")
+	PublisherClosed      = Error("publisher is closed")
+	SubscriberCLosed     = Error("subscriber is closed")
+	MessageTooLarge      = Error("message is too large")
+	SubscriptionNotFound = Error("subscription not found")
+	HandlerTimeout       = Error("handler timed out")
+	PublishBufferFull    = Error("publish buffer is full")
+	PublishBufferTimeout = Error("publish buffer timed out")
+	InvalidPattern       = Error("topic pattern is invalid")
+	NoRoute              = Error("no route matching the topic")
+	ReservedAttribute    = Error("attribute key is reserved")
+	CloseTimeout         = Error("close timed out")
 )
 
 // Error represents a cache error.
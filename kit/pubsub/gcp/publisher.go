@@ -16,6 +16,44 @@ import (
 
 var _ pubsub.Publisher = (*Publisher)(nil)
 
+// maxMessageSize is the maximum size of a Google Cloud Pub/Sub message (10MB).
+const maxMessageSize = 10_000_000
+
+// PublisherOption defines a Publisher option.
+type PublisherOption func(*Publisher)
+
+// WithMaxMessageSize defines the maximum size in bytes of a published message, its data and attributes
+// (keys and values, the tracing attributes included). Messages exceeding it are rejected with pubsub.MessageTooLarge
+// without being sent.
+// The size must be positive and cannot exceed the Google Cloud Pub/Sub limit (10MB), NewPublisher returns an error otherwise.
+func WithMaxMessageSize(bytes int) PublisherOption {
+	return func(p *Publisher) {
+		p.maxMessageSize = bytes
+	}
+}
+
+// defaultPublishTimeout is the default maximum time Publish waits for a message to be published.
+const defaultPublishTimeout = 5 * time.Second
+
+// WithPublishTimeout defines the maximum time Publish waits for a message to be published, 5 seconds by default.
+// The deadline and the cancellation of the context given to Publish still apply.
+func WithPublishTimeout(timeout time.Duration) PublisherOption {
+	return func(p *Publisher) {
+		if timeout > 0 {
+			p.publishTimeout = timeout
+		}
+	}
+}
+
+// WithPublishSettings defines the publish settings of the topics, eg: the batching thresholds
+// (gcppubsub.PublishSettings.CountThreshold, ByteThreshold and DelayThreshold) used by PublishAsync.
+// By default, gcppubsub.DefaultPublishSettings are used.
+func WithPublishSettings(settings gcppubsub.PublishSettings) PublisherOption {
+	return func(p *Publisher) {
+		p.publishSettings = &settings
+	}
+}
+
 // Publisher publishes a message on a Google Cloud Pub/Sub topic.
 //
 // For more info on how Google Cloud Pub/Sub Publisher work, check https://cloud.google.com/pubsub/docs/publisher.
@@ -25,20 +63,33 @@ type Publisher struct {
 	closed     bool
 	closeLock  sync.RWMutex
 	client     *gcppubsub.Client
+	// maximum size of a message
+	maxMessageSize  int
+	publishTimeout  time.Duration
+	publishSettings *gcppubsub.PublishSettings
 }
 
 // NewPublisher create a new GCP publisher.
 //
 // It required a call to Close in order to stop processing messages and close topic connections.
-func NewPublisher(client *gcppubsub.Client) (*Publisher, error) {
+func NewPublisher(client *gcppubsub.Client, opts ...PublisherOption) (*Publisher, error) {
 	if client == nil {
 		return nil, fmt.Errorf("pubsub client is nil")
 	}
 
-	return &Publisher{
-		topics: map[string]*gcppubsub.Topic{},
-		client: client,
-	}, nil
+	p := &Publisher{
+		topics:         map[string]*gcppubsub.Topic{},
+		client:         client,
+		maxMessageSize: maxMessageSize,
+		publishTimeout: defaultPublishTimeout,
+	}
+	for _, o := range opts {
+		o(p)
+	}
+	if p.maxMessageSize <= 0 || p.maxMessageSize > maxMessageSize {
+		return nil, fmt.Errorf("max message size of %d bytes is not between 1 and %d bytes", p.maxMessageSize, maxMessageSize)
+	}
+	return p, nil
 }
 
 // Close notifies the Publisher to stop processing messages, send all the remaining messages and close the connection.
@@ -61,22 +112,110 @@ func (p *Publisher) Close() error {
 }
 
 // Publish publishes a message on a Google Cloud Pub/Sub topic.
-// It blocks until the message is successfully published or an error occurred.
+// It blocks until the message is successfully published, an error occurred, ctx is done
+// or the publish timeout expired (see WithPublishTimeout).
 //
 // To receive messages published to a topic, you must create a subscription to that topic.
 // Only messages published to the topic after the subscription is created are available to subscriber applications.
 //
+// The topic is added to ctx (see pubsub.GetTopic) for the duration of the publication.
+//
 // See https://cloud.google.com/pubsub/docs/publisher to find out more about how Google Cloud Pub/Sub Publishers work.
 func (p *Publisher) Publish(ctx context.Context, topic string, msg pubsub.Message) error {
+	return p.PublishWithAttributes(ctx, topic, msg, nil)
+}
+
+// PublishWithAttributes publishes a message with attributes on a Google Cloud Pub/Sub topic, like Publish.
+// The subscribers get the attributes with pubsub.GetAttributes, the reserved keys are rejected (see pubsub.ValidateAttributes).
+func (p *Publisher) PublishWithAttributes(ctx context.Context, topic string, msg pubsub.Message, attrs map[string]string) error {
 	if len(topic) == 0 {
 		return fmt.Errorf("topic is nil")
 	}
+	if err := pubsub.ValidateAttributes(attrs); err != nil {
+		return err
+	}
 
+	// the topic is carried by the context of the span and the publish path (eg: logs).
+	ctx = pubsub.WithTopic(ctx, topic)
 	var span trace.Span
 	ctx, span = tracer.Start(ctx, fmt.Sprintf("Publish %s", topic))
 	span.SetAttributes(attribute.String("topic", topic))
 	defer span.End()
 
+	res, err := p.send(ctx, span, topic, msg, attrs)
+	if err != nil {
+		return err
+	}
+
+	// Setup a timeout for the publisher to give up and attempt to publish the message to the pubsub,
+	// the caller cancellation is honored.
+	timeoutCtx, fn := context.WithTimeout(ctx, p.publishTimeout)
+	defer fn()
+	_, err = res.Get(timeoutCtx)
+
+	// in case of error we set the trace to error and return.
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// PublishAsync publishes a message on a Google Cloud Pub/Sub topic without waiting for it to be published,
+// the messages are batched following the publish settings of the topic (see WithPublishSettings).
+//
+// The returned function blocks until the message is published, an error occurred, ctx is done
+// or the publish timeout expired (see WithPublishTimeout), eg: to fan out many messages then await them:
+//
+//	var waits []func() error
+//	for _, msg := range msgs {
+//		wait, err := p.PublishAsync(ctx, topic, msg)
+//		...
+//		waits = append(waits, wait)
+//	}
+//	for _, wait := range waits {
+//		err := wait()
+//		...
+//	}
+//
+// The publish span ends once the message is published, whether it is awaited or not.
+func (p *Publisher) PublishAsync(ctx context.Context, topic string, msg pubsub.Message) (func() error, error) {
+	if len(topic) == 0 {
+		return nil, fmt.Errorf("topic is nil")
+	}
+
+	ctx = pubsub.WithTopic(ctx, topic)
+	var span trace.Span
+	ctx, span = tracer.Start(ctx, fmt.Sprintf("Publish %s", topic))
+	span.SetAttributes(attribute.String("topic", topic), attribute.Bool("async", true))
+
+	res, err := p.send(ctx, span, topic, msg, nil)
+	if err != nil {
+		span.End()
+		return nil, err
+	}
+
+	go func() {
+		<-res.Ready()
+		if _, err := res.Get(context.Background()); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	return func() error {
+		timeoutCtx, fn := context.WithTimeout(ctx, p.publishTimeout)
+		defer fn()
+		_, err := res.Get(timeoutCtx)
+		return err
+	}, nil
+}
+
+// send checks the message and sends it to the topic with the user attributes, the errors are recorded on the span.
+func (p *Publisher) send(ctx context.Context, span trace.Span, topic string, msg pubsub.Message, attrs map[string]string) (*gcppubsub.PublishResult, error) {
 	// if the publisher is in closing state or has been closed
 	// we return an error and annotate the trace with the error.
 	if p.isClose() {
@@ -84,36 +223,48 @@ func (p *Publisher) Publish(ctx context.Context, topic string, msg pubsub.Messag
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 
-		return err
+		return nil, err
 	}
 
 	// Prepare attributes that will be passed to the pubsub
-	attributes := make(map[string]string)
+	attributes := make(map[string]string, len(attrs)+6)
+	for k, v := range attrs {
+		attributes[k] = v
+	}
 	attributes["topic"] = topic
 	tracingAttributes(span, attributes)
 
+	// reject oversized messages before reaching the broker, the attributes count in the limit.
+	if size := messageSize(msg, attributes); size > p.maxMessageSize {
+		err := errors.Wrapf(pubsub.MessageTooLarge, "message of %d bytes exceeds the limit of %d bytes", size, p.maxMessageSize)
+		span.SetAttributes(attribute.Int("message.size", size))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return nil, err
+	}
+
 	// Get the topic
 	t, err := p.topic(ctx, topic)
 	if err != nil {
-		return err
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
 	}
 
-	// Setup a timeout for the publisher to give up and attempt to publish the message to the pubsub.
-	timeoutCtx, fn := context.WithTimeout(context.Background(), 5*time.Second)
-	defer fn()
-	_, err = t.Publish(ctx, &gcppubsub.Message{
+	return t.Publish(ctx, &gcppubsub.Message{
 		Data:       msg,
 		Attributes: attributes,
-	}).Get(timeoutCtx)
+	}), nil
+}
 
-	// in case of error we set the trace to error and return.
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, err.Error())
-		return err
+// messageSize returns the size of a message counted by Google Cloud Pub/Sub: its data and the keys and values of its attributes.
+func messageSize(msg pubsub.Message, attributes map[string]string) int {
+	size := len(msg)
+	for k, v := range attributes {
+		size += len(k) + len(v)
 	}
-
-	return nil
+	return size
 }
 
 func (p *Publisher) isClose() bool {
@@ -135,6 +286,9 @@ func (p *Publisher) topic(ctx context.Context, topic string) (*gcppubsub.Topic,
 	defer p.topicsLock.Unlock()
 
 	t = p.client.Topic(topic)
+	if p.publishSettings != nil {
+		t.PublishSettings = *p.publishSettings
+	}
 	exists, err := t.Exists(ctx)
 	if err != nil {
 		return nil, errors.Wrapf(err, "could not check if topic %s exists", topic)
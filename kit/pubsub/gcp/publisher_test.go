@@ -0,0 +1,177 @@
+package gcp
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gcppubsub "cloud.google.com/go/pubsub"
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/pubsub"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestPublishMaxMessageSize(t *testing.T) {
+
+	// Dummy, the message is rejected before reaching the client.
+	c := gcppubsub.Client{}
+	p, err := NewPublisher(&c, WithMaxMessageSize(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = p.Publish(context.Background(), "a.topic", pubsub.Message("more than 8 bytes"))
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, pubsub.MessageTooLarge))
+}
+
+func TestPublishWithAttributesReserved(t *testing.T) {
+	// Dummy, the attributes are rejected before reaching the client.
+	c := gcppubsub.Client{}
+	p, err := NewPublisher(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = p.PublishWithAttributes(context.Background(), "a.topic", pubsub.Message("msg"), map[string]string{"trace": "abc"})
+	assert.True(t, errors.Is(err, pubsub.ReservedAttribute))
+}
+
+func TestPublisherMaxMessageSizeOption(t *testing.T) {
+	c := gcppubsub.Client{}
+
+	p, err := NewPublisher(&c)
+	assert.NoError(t, err)
+	assert.Equal(t, maxMessageSize, p.maxMessageSize)
+
+	p, err = NewPublisher(&c, WithMaxMessageSize(1024))
+	assert.NoError(t, err)
+	assert.Equal(t, 1024, p.maxMessageSize)
+
+	// must be positive and cannot exceed the Google Cloud Pub/Sub limit.
+	for _, size := range []int{0, -1, maxMessageSize + 1} {
+		_, err = NewPublisher(&c, WithMaxMessageSize(size))
+		assert.Error(t, err, size)
+	}
+}
+
+func TestPublishMaxMessageSize_Attributes(t *testing.T) {
+	// Dummy, the message is rejected before reaching the client.
+	c := gcppubsub.Client{}
+	p, err := NewPublisher(&c, WithMaxMessageSize(64))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the data is under the limit, not with the attributes.
+	err = p.PublishWithAttributes(context.Background(), "a.topic", pubsub.Message("small"), map[string]string{
+		"key": strings.Repeat("v", 64),
+	})
+	assert.True(t, errors.Is(err, pubsub.MessageTooLarge))
+}
+
+func TestPublisherPublishTimeoutOption(t *testing.T) {
+	c := gcppubsub.Client{}
+
+	p, err := NewPublisher(&c)
+	assert.NoError(t, err)
+	assert.Equal(t, defaultPublishTimeout, p.publishTimeout)
+
+	p, err = NewPublisher(&c, WithPublishTimeout(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, p.publishTimeout)
+
+	// invalid timeouts are ignored.
+	p, err = NewPublisher(&c, WithPublishTimeout(-time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, defaultPublishTimeout, p.publishTimeout)
+}
+
+func TestPublishAsyncMaxMessageSize(t *testing.T) {
+	// Dummy, the message is rejected before reaching the client.
+	c := gcppubsub.Client{}
+	p, err := NewPublisher(&c, WithMaxMessageSize(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wait, err := p.PublishAsync(context.Background(), "a.topic", pubsub.Message("more than 8 bytes"))
+	assert.True(t, errors.Is(err, pubsub.MessageTooLarge))
+	assert.Nil(t, wait)
+}
+
+func TestPublishAsync(t *testing.T) {
+	if os.Getenv("PUBSUB_EMULATOR_HOST") == "" {
+		t.Skip("Skipping, no env variable PUBSUB_EMULATOR_HOST")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c, err := gcppubsub.NewClient(ctx, "fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	topic, err := c.CreateTopic(ctx, "async-topic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := c.CreateSubscription(ctx, "async-subscription", gcppubsub.SubscriptionConfig{Topic: topic})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settings := gcppubsub.DefaultPublishSettings
+	settings.CountThreshold = 10
+	settings.DelayThreshold = 50 * time.Millisecond
+	p, err := NewPublisher(c, WithPublishSettings(settings))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	// fire all the publications, then await them.
+	const count = 100
+	waits := make([]func() error, 0, count)
+	for i := 0; i < count; i++ {
+		wait, err := p.PublishAsync(ctx, "async-topic", pubsub.Message("msg"))
+		if !assert.NoError(t, err) {
+			return
+		}
+		waits = append(waits, wait)
+	}
+	for _, wait := range waits {
+		assert.NoError(t, wait())
+	}
+	assert.Equal(t, 10, p.topics["async-topic"].PublishSettings.CountThreshold)
+
+	var received int32
+	receiveCtx, stop := context.WithCancel(ctx)
+	err = sub.Receive(receiveCtx, func(ctx context.Context, msg *gcppubsub.Message) {
+		msg.Ack()
+		if atomic.AddInt32(&received, 1) == count {
+			stop()
+		}
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(count), atomic.LoadInt32(&received))
+}
+
+func TestPublishTopicContext(t *testing.T) {
+	recorder := &topicRecorder{}
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	c := gcppubsub.Client{}
+	p, err := NewPublisher(&c, WithMaxMessageSize(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the message is rejected once the publish span is started.
+	_ = p.Publish(context.Background(), "a.topic", pubsub.Message("more than 8 bytes"))
+	assert.Equal(t, []string{"a.topic"}, recorder.recorded())
+}
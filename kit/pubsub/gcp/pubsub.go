@@ -1,3 +1,7 @@
+// Package gcp provides the pubsub publisher and subscriber based on Google Cloud Pub/Sub.
+//
+// The subscriptions for which receiving messages permanently fails are dropped silently by default:
+// the errors are only reported with WithErrorHandler and logged with WithLogger.
 package gcp
 
 import (
@@ -8,17 +8,26 @@ import (
 
 	gcppubsub "cloud.google.com/go/pubsub"
 	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/log"
 	"github.com/anthonycorbacho/workspace/kit/pubsub"
 	"github.com/cenkalti/backoff/v4"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var _ pubsub.Subscriber = (*Subscriber)(nil)
 
+// receiver is a running reception of messages on a subscription.
+type receiver struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
 // SubscriberOption defines a Subscriber option.
-type SubscriberOption func(*gcppubsub.ReceiveSettings)
+type SubscriberOption func(*Subscriber)
 
 // Subscriber attaches to a Google Cloud Pub/Sub subscription and returns a Go channel with messages from the topic.
 // Be aware that in Google Cloud Pub/Sub, only messages sent after the subscription was created can be consumed.
@@ -31,8 +40,15 @@ type Subscriber struct {
 	subscriptionsWaitGroup  sync.WaitGroup
 	activeSubscriptions     map[string]*gcppubsub.Subscription
 	activeSubscriptionsLock sync.RWMutex
+	receivers               map[string][]receiver
+	receiversLock           sync.Mutex
 	client                  *gcppubsub.Client
 	settings                gcppubsub.ReceiveSettings
+	slowConsumer            *pubsub.SlowConsumerMonitor
+	errorHandler            func(subscription string, err error)
+	logger                  *log.Logger
+	handlerTimeout          time.Duration
+	retryPolicy             bool
 }
 
 // NewSubscriber creates a new GCP PubSub Subscriber.
@@ -43,33 +59,51 @@ func NewSubscriber(client *gcppubsub.Client, opts ...SubscriberOption) (*Subscri
 		return nil, fmt.Errorf("pubsub client is nil")
 	}
 
-	// default receiveSettings
-	settings := gcppubsub.ReceiveSettings{
-		MaxExtension:           60 * time.Minute,
-		MaxExtensionPeriod:     0,
-		MinExtensionPeriod:     0,
-		MaxOutstandingMessages: 1000,
-		MaxOutstandingBytes:    1e9, // 1G
-		NumGoroutines:          10,
-	}
-	for _, o := range opts {
-		o(&settings)
-	}
-
-	return &Subscriber{
+	s := &Subscriber{
 		closing:                 make(chan struct{}, 1),
 		closed:                  false,
 		closedLock:              sync.Mutex{},
 		subscriptionsWaitGroup:  sync.WaitGroup{},
 		activeSubscriptionsLock: sync.RWMutex{},
 		activeSubscriptions:     map[string]*gcppubsub.Subscription{},
+		receivers:               map[string][]receiver{},
 		client:                  client,
-		settings:                settings,
-	}, nil
+		errorHandler:            func(string, error) {},
+		logger:                  log.NewNop(),
+		// default receiveSettings
+		settings: gcppubsub.ReceiveSettings{
+			MaxExtension:           60 * time.Minute,
+			MaxExtensionPeriod:     0,
+			MinExtensionPeriod:     0,
+			MaxOutstandingMessages: 1000,
+			MaxOutstandingBytes:    1e9, // 1G
+			NumGoroutines:          10,
+		},
+	}
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s, nil
 }
 
+// defaultCloseTimeout is the maximum time Close waits for the in-flight messages to be processed.
+const defaultCloseTimeout = 30 * time.Second
+
 // Close notifies the Subscriber to stop processing messages on all subscriptions, and terminate the connection.
+// It waits at most 30 seconds for the in-flight messages to be processed, see CloseWithTimeout.
 func (s *Subscriber) Close() error {
+	return s.CloseWithTimeout(defaultCloseTimeout)
+}
+
+// CloseWithTimeout notifies the Subscriber to stop processing messages on all subscriptions,
+// waits at most d for the in-flight messages to be processed and terminates the connection,
+// eg: to shut down within the termination grace period of the pod.
+//
+// The contexts given to the handlers are cancelled. If the handlers don't return within d,
+// pubsub.CloseTimeout is returned and the connection is terminated anyway,
+// the messages not acknowledged are redelivered once their ack deadline expires.
+func (s *Subscriber) CloseWithTimeout(d time.Duration) error {
 	if s.isClosed() {
 		return nil
 	}
@@ -77,9 +111,26 @@ func (s *Subscriber) Close() error {
 	close(s.closing)
 
 	// wait for all subscribers
-	s.subscriptionsWaitGroup.Wait()
+	done := make(chan struct{})
+	go func() {
+		s.subscriptionsWaitGroup.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	var errs []error
+	select {
+	case <-done:
+	case <-timer.C:
+		errs = append(errs, errors.Wrapf(pubsub.CloseTimeout, "in-flight messages not processed within %s", d))
+	}
 
-	return s.client.Close()
+	if err := s.client.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
 }
 
 // Subscribe consumes Google Cloud Pub/Sub.
@@ -91,6 +142,9 @@ func (s *Subscriber) Close() error {
 //
 // See https://cloud.google.com/pubsub/docs/subscriber to find out more about how Google Cloud Pub/Sub Subscriptions work.
 func (s *Subscriber) Subscribe(ctx context.Context, subscription string, handler pubsub.Handler) error {
+	if s.retryPolicy {
+		return s.SubscribeWithAck(ctx, subscription, pubsub.RetryHandler(handler))
+	}
 
 	h := func(ctx context.Context, msg pubsub.Message, ack func(), nack func()) error {
 		// default behavior is to always ack.
@@ -102,6 +156,34 @@ func (s *Subscriber) Subscribe(ctx context.Context, subscription string, handler
 }
 
 func (s *Subscriber) SubscribeWithAck(ctx context.Context, subscription string, handler pubsub.HandlerWithAck) error {
+	if s.handlerTimeout > 0 {
+		handler = pubsub.TimeoutHandler(handler, s.handlerTimeout)
+	}
+
+	h := func(ctx context.Context, m *gcppubsub.Message) error {
+		return handler(ctx, m.Data, m.Ack, m.Nack)
+	}
+
+	return s.SubscribeRaw(ctx, subscription, h)
+}
+
+// SubscribeChan delivers the messages of the subscription on the returned channel, like SubscribeWithAck:
+// the receiver is responsible for acking (or nacking) them, the next message is delivered once the previous one is received.
+// The channel is closed once ctx is done or the subscriber is closing, see pubsub.SubscribeChan.
+func (s *Subscriber) SubscribeChan(ctx context.Context, subscription string) (<-chan pubsub.ReceivedMessage, error) {
+	return pubsub.SubscribeChan(ctx, subscription, s.closing, s.SubscribeWithAck)
+}
+
+// RawHandler is the handler receiving the Google Cloud Pub/Sub message as is.
+type RawHandler func(ctx context.Context, msg *gcppubsub.Message) error
+
+// SubscribeRaw consumes Google Cloud Pub/Sub, delivering the provider message as is to the handler,
+// which is responsible for acking (or nacking) it.
+//
+// It is meant for advanced use cases needing Google Cloud Pub/Sub specific features (eg: attributes, ordering key,
+// delivery attempt); the handler is coupled to the provider, prefer Subscribe or SubscribeWithAck otherwise.
+// The handler timeout does not apply to raw handlers.
+func (s *Subscriber) SubscribeRaw(ctx context.Context, subscription string, handler RawHandler) error {
 	if s.isClosed() {
 		return fmt.Errorf("subscriber is closed")
 	}
@@ -114,6 +196,7 @@ func (s *Subscriber) SubscribeWithAck(ctx context.Context, subscription string,
 	sub, err := s.subscription(ctx, subscription)
 	if err != nil {
 		cancelFn()
+		s.errorHandler(subscription, err)
 		return err
 	}
 
@@ -122,7 +205,7 @@ func (s *Subscriber) SubscribeWithAck(ctx context.Context, subscription string,
 
 	receiveFinished := make(chan struct{})
 	s.subscriptionsWaitGroup.Add(1)
-	go func(sub *gcppubsub.Subscription, handler pubsub.HandlerWithAck) {
+	go func(sub *gcppubsub.Subscription, handler RawHandler) {
 
 		// utilise exponential Backoff on the subscription to give room to breeze.
 		exponentialBackoff := backoff.NewExponentialBackOff()
@@ -140,30 +223,64 @@ func (s *Subscriber) SubscribeWithAck(ctx context.Context, subscription string,
 				return backoff.Permanent(err)
 			}
 
+			// the subscription has been deleted, retrying will never succeed.
+			if status.Code(err) == grpccodes.NotFound {
+				return backoff.Permanent(err)
+			}
+
 			// Receiving messages failed, retrying
 			return err
-		}, exponentialBackoff); err != nil {
-			// Retrying receiving messages failed
-			fmt.Printf("retrying receiving messages failed: %s\n", err)
+		}, backoff.WithContext(exponentialBackoff, ctx)); err != nil && ctx.Err() == nil {
+			// Receiving messages permanently failed, the other subscriptions keep running.
+			s.receiveFailed(ctx, subscription, err)
 		}
 		close(receiveFinished)
 	}(sub, handler)
 
-	// terminate the subscription
-	go func(cancelFn context.CancelFunc) {
-		<-s.closing
-		cancelFn()
-	}(cancelFn)
-
+	// terminate the subscription once the subscriber is closing, ctx is done (eg: Unsubscribe)
+	// or receiving messages finished.
 	go func() {
+		select {
+		case <-s.closing:
+		case <-ctx.Done():
+		case <-receiveFinished:
+		}
+		cancelFn()
 		<-receiveFinished
 		s.subscriptionsWaitGroup.Done()
 	}()
 
+	s.receiversLock.Lock()
+	s.receivers[subscription] = append(s.receivers[subscription], receiver{cancel: cancelFn, done: receiveFinished})
+	s.receiversLock.Unlock()
+
+	return nil
+}
+
+// Unsubscribe stops receiving messages on the given subscription and waits for the in-flight messages to be processed.
+// The other subscriptions and the client are kept alive.
+func (s *Subscriber) Unsubscribe(subscription string) error {
+	s.receiversLock.Lock()
+	receivers, ok := s.receivers[subscription]
+	delete(s.receivers, subscription)
+	s.receiversLock.Unlock()
+
+	if !ok {
+		return pubsub.SubscriptionNotFound
+	}
+
+	for _, r := range receivers {
+		r.cancel()
+		<-r.done
+	}
+
+	s.activeSubscriptionsLock.Lock()
+	delete(s.activeSubscriptions, subscription)
+	s.activeSubscriptionsLock.Unlock()
 	return nil
 }
 
-func (s *Subscriber) receive(ctx context.Context, sub *gcppubsub.Subscription, handler pubsub.HandlerWithAck) error {
+func (s *Subscriber) receive(ctx context.Context, sub *gcppubsub.Subscription, handler RawHandler) error {
 	err := sub.Receive(ctx, func(ctx context.Context, m *gcppubsub.Message) {
 
 		select {
@@ -181,8 +298,13 @@ func (s *Subscriber) receive(ctx context.Context, sub *gcppubsub.Subscription, h
 		ctx = contextFromTracingAttributes(ctx, m.Attributes)
 		topic := m.Attributes["topic"]
 
-		// Add to the context the topic.
+		// Add to the context the topic and the attributes.
 		ctx = pubsub.WithTopic(ctx, topic)
+		ctx = pubsub.WithAttributes(ctx, m.Attributes)
+		// the delivery attempt is only set on subscriptions with a dead letter policy.
+		if m.DeliveryAttempt != nil {
+			ctx = pubsub.WithDeliveryAttempt(ctx, *m.DeliveryAttempt)
+		}
 
 		// annotate the span
 		var span trace.Span
@@ -191,16 +313,12 @@ func (s *Subscriber) receive(ctx context.Context, sub *gcppubsub.Subscription, h
 		span.SetAttributes(attribute.String("topic", topic))
 		defer span.End()
 
-		ack := func() {
-			m.Ack()
-		}
-		nack := func() {
-			m.Nack()
-		}
+		// track the message to detect when the subscription handler is too slow.
+		defer s.slowConsumer.Track(ctx, sub.ID())()
 
 		// Process the message
 		// in case of error, we record and label the error in the span.
-		if err := handler(ctx, m.Data, ack, nack); err != nil {
+		if err := handler(ctx, m); err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, err.Error())
 		}
@@ -208,6 +326,12 @@ func (s *Subscriber) receive(ctx context.Context, sub *gcppubsub.Subscription, h
 	return err
 }
 
+// receiveFailed reports a subscription for which receiving messages permanently failed.
+func (s *Subscriber) receiveFailed(ctx context.Context, subscription string, err error) {
+	s.logger.Error(ctx, "receiving messages failed", log.String("subscription", subscription), log.Error(err))
+	s.errorHandler(subscription, errors.Wrap(err, "receiving messages"))
+}
+
 func (s *Subscriber) subscription(ctx context.Context, subscription string) (*gcppubsub.Subscription, error) {
 	s.activeSubscriptionsLock.RLock()
 	sub, ok := s.activeSubscriptions[subscription]
@@ -247,6 +371,50 @@ func (s *Subscriber) isClosed() bool {
 	return s.closed
 }
 
+// WithErrorHandler defines a function called each time a subscription fails,
+// either when subscribing (eg: the subscription does not exist) or when receiving messages
+// permanently fails. The other subscriptions of the Subscriber keep running.
+func WithErrorHandler(fn func(subscription string, err error)) SubscriberOption {
+	return func(s *Subscriber) {
+		s.errorHandler = fn
+	}
+}
+
+// WithLogger defines the logger used to report the subscriptions for which receiving messages permanently failed.
+// By default, nothing is logged.
+func WithLogger(logger *log.Logger) SubscriberOption {
+	return func(s *Subscriber) {
+		s.logger = logger
+	}
+}
+
+// WithRetryPolicy settles the messages of the handlers registered with Subscribe based on their outcome:
+// acked on success, nacked for redelivery on a retryable error (see errors.IsRetryable) and acked (dropped) on any other error.
+// By default, Subscribe always acks the messages.
+func WithRetryPolicy() SubscriberOption {
+	return func(s *Subscriber) {
+		s.retryPolicy = true
+	}
+}
+
+// WithHandlerTimeout bounds the time given to the handler to process a message,
+// the message is nacked for redelivery if the handler does not finish in time.
+// See pubsub.TimeoutHandler.
+func WithHandlerTimeout(d time.Duration) SubscriberOption {
+	return func(s *Subscriber) {
+		s.handlerTimeout = d
+	}
+}
+
+// WithSlowConsumer reports (warning log and metric) the subscriptions with more than highWaterMark
+// messages handled concurrently, or with a handler taking more than latency to process a message.
+// See pubsub.SlowConsumerMonitor.
+func WithSlowConsumer(highWaterMark int, latency time.Duration) SubscriberOption {
+	return func(s *Subscriber) {
+		s.slowConsumer = pubsub.NewSlowConsumerMonitor(highWaterMark, latency)
+	}
+}
+
 // WithMaxExtension defines the maximum period for which the Subscription should
 // automatically extend the ack deadline for each message.
 //
@@ -255,8 +423,8 @@ func (s *Subscriber) isClosed() bool {
 // extension beyond the initial receipt may be disabled by specifying a
 // duration less than 0.
 func WithMaxExtension(d time.Duration) SubscriberOption {
-	return func(o *gcppubsub.ReceiveSettings) {
-		o.MaxExtension = d
+	return func(s *Subscriber) {
+		s.settings.MaxExtension = d
 	}
 }
 
@@ -269,8 +437,8 @@ func WithMaxExtension(d time.Duration) SubscriberOption {
 // MaxExtensionPeriod must be between 10s and 600s (inclusive). This configuration
 // can be disabled by specifying a duration less than (or equal to) 0.
 func WithMaxExtensionPeriod(d time.Duration) SubscriberOption {
-	return func(o *gcppubsub.ReceiveSettings) {
-		o.MaxExtensionPeriod = d
+	return func(s *Subscriber) {
+		s.settings.MaxExtensionPeriod = d
 	}
 }
 
@@ -283,8 +451,8 @@ func WithMaxExtensionPeriod(d time.Duration) SubscriberOption {
 // Defaults to off but set to 60 seconds if the subscription has exactly-once delivery enabled,
 // which will be added in a future release.
 func WithMinExtensionPeriod(d time.Duration) SubscriberOption {
-	return func(o *gcppubsub.ReceiveSettings) {
-		o.MinExtensionPeriod = d
+	return func(s *Subscriber) {
+		s.settings.MinExtensionPeriod = d
 	}
 }
 
@@ -294,8 +462,8 @@ func WithMinExtensionPeriod(d time.Duration) SubscriberOption {
 // If the value is negative, then there will be no limit on the number of
 // unprocessed messages.
 func WithMaxOutstandingMessages(n int) SubscriberOption {
-	return func(o *gcppubsub.ReceiveSettings) {
-		o.MaxOutstandingMessages = n
+	return func(s *Subscriber) {
+		s.settings.MaxOutstandingMessages = n
 	}
 }
 
@@ -305,8 +473,8 @@ func WithMaxOutstandingMessages(n int) SubscriberOption {
 // the value is negative, then there will be no limit on the number of bytes
 // for unprocessed messages.
 func WithMaxOutstandingBytes(n int) SubscriberOption {
-	return func(o *gcppubsub.ReceiveSettings) {
-		o.MaxOutstandingBytes = n
+	return func(s *Subscriber) {
+		s.settings.MaxOutstandingBytes = n
 	}
 }
 
@@ -322,7 +490,7 @@ func WithMaxOutstandingBytes(n int) SubscriberOption {
 // function passed to Receive on them. To limit the number of messages being
 // processed concurrently, set MaxOutstandingMessages.
 func WithNumGoroutines(n int) SubscriberOption {
-	return func(o *gcppubsub.ReceiveSettings) {
-		o.NumGoroutines = n
+	return func(s *Subscriber) {
+		s.settings.NumGoroutines = n
 	}
 }
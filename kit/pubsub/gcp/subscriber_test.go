@@ -1,11 +1,23 @@
 package gcp
 
 import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 
 	gcppubsub "cloud.google.com/go/pubsub"
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/log"
+	"github.com/anthonycorbacho/workspace/kit/pubsub"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 func TestSubscriberOption(t *testing.T) {
@@ -30,3 +42,260 @@ func TestSubscriberOption(t *testing.T) {
 		NumGoroutines:          1,
 	}, s.settings)
 }
+
+func TestUnsubscribeUnknownSubscription(t *testing.T) {
+
+	// Dummy
+	c := gcppubsub.Client{}
+	s, err := NewSubscriber(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, pubsub.SubscriptionNotFound, s.Unsubscribe("unknown"))
+}
+
+// offlineClient returns a client to an address nothing listens on, it can be created and closed.
+func offlineClient(t *testing.T) *gcppubsub.Client {
+	c, err := gcppubsub.NewClient(context.Background(), "fake",
+		option.WithEndpoint("127.0.0.1:1"),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCloseWithTimeout(t *testing.T) {
+	s, err := NewSubscriber(offlineClient(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an in-flight handler never returning.
+	s.subscriptionsWaitGroup.Add(1)
+
+	start := time.Now()
+	err = s.CloseWithTimeout(50 * time.Millisecond)
+	assert.True(t, errors.Is(err, pubsub.CloseTimeout))
+	assert.Less(t, time.Since(start), time.Second)
+
+	// the receive contexts are cancelled.
+	select {
+	case <-s.closing:
+	default:
+		assert.Fail(t, "subscriber not closing")
+	}
+
+	// closing twice is a no-op.
+	assert.NoError(t, s.Close())
+}
+
+func TestCloseWithTimeout_Drained(t *testing.T) {
+	s, err := NewSubscriber(offlineClient(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.subscriptionsWaitGroup.Add(1)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		s.subscriptionsWaitGroup.Done()
+	}()
+	assert.NoError(t, s.CloseWithTimeout(time.Second))
+}
+
+func TestErrorHandlerUnknownSubscription(t *testing.T) {
+	if os.Getenv("PUBSUB_EMULATOR_HOST") == "" {
+		t.Skip("Skipping, no env variable PUBSUB_EMULATOR_HOST")
+	}
+
+	ctx := context.Background()
+	c, err := gcppubsub.NewClient(ctx, "fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type failure struct {
+		subscription string
+		err          error
+	}
+	failures := make(chan failure, 1)
+	s, err := NewSubscriber(c, WithErrorHandler(func(subscription string, err error) {
+		failures <- failure{subscription: subscription, err: err}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	err = s.Subscribe(ctx, "does-not-exist", func(ctx context.Context, msg pubsub.Message) error {
+		return nil
+	})
+	assert.Error(t, err)
+
+	select {
+	case f := <-failures:
+		assert.Equal(t, "does-not-exist", f.subscription)
+		assert.Error(t, f.err)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "timeout waiting for the error handler")
+	}
+}
+
+func TestLoggerReceiveFailed(t *testing.T) {
+	// logger writing to a file, injected with WithLogger.
+	output := filepath.Join(t.TempDir(), "log")
+	logger, err := log.New(log.WithOutputPaths(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Dummy
+	c := gcppubsub.Client{}
+	var handled error
+	s, err := NewSubscriber(&c, WithLogger(logger), WithErrorHandler(func(subscription string, err error) {
+		handled = err
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	s.receiveFailed(ctx, "a.subscription", errors.New("boom"))
+	logger.Close()
+
+	b, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, bytes.Contains(b, []byte(`"receiving messages failed"`)))
+	assert.True(t, bytes.Contains(b, []byte(`"subscription":"a.subscription"`)))
+	assert.True(t, bytes.Contains(b, []byte(`"TraceId":"4bf92f3577b34da6a3ce929d0e0e4736"`)))
+	assert.True(t, bytes.Contains(b, []byte(`boom`)))
+	assert.Error(t, handled)
+}
+
+func TestSubscribeRaw(t *testing.T) {
+	if os.Getenv("PUBSUB_EMULATOR_HOST") == "" {
+		t.Skip("Skipping, no env variable PUBSUB_EMULATOR_HOST")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c, err := gcppubsub.NewClient(ctx, "fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	topic, err := c.CreateTopic(ctx, "raw-topic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.CreateSubscription(ctx, "raw-subscription", gcppubsub.SubscriptionConfig{Topic: topic})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewSubscriber(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	received := make(chan *gcppubsub.Message, 1)
+	err = s.SubscribeRaw(ctx, "raw-subscription", func(ctx context.Context, msg *gcppubsub.Message) error {
+		msg.Ack()
+		received <- msg
+		return nil
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	id, err := topic.Publish(ctx, &gcppubsub.Message{
+		Data:       []byte("raw"),
+		Attributes: map[string]string{"custom": "value"},
+	}).Get(ctx)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, id, msg.ID)
+		assert.Equal(t, []byte("raw"), msg.Data)
+		assert.Equal(t, "value", msg.Attributes["custom"])
+	case <-ctx.Done():
+		assert.Fail(t, "timeout waiting for the raw message")
+	}
+}
+
+func TestUnsubscribe_NoLeak(t *testing.T) {
+	if os.Getenv("PUBSUB_EMULATOR_HOST") == "" {
+		t.Skip("Skipping, no env variable PUBSUB_EMULATOR_HOST")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	c, err := gcppubsub.NewClient(ctx, "fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	topic, err := c.CreateTopic(ctx, "churn-topic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.CreateSubscription(ctx, "churn-subscription", gcppubsub.SubscriptionConfig{Topic: topic})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewSubscriber(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	handler := func(ctx context.Context, m *gcppubsub.Message) error { return nil }
+	churn := func() {
+		assert.NoError(t, s.SubscribeRaw(ctx, "churn-subscription", handler))
+		assert.NoError(t, s.Unsubscribe("churn-subscription"))
+	}
+	// warm up the client connection.
+	churn()
+	time.Sleep(100 * time.Millisecond)
+	baseline := runtime.NumGoroutine()
+
+	for i := 0; i < 20; i++ {
+		churn()
+	}
+
+	// the goroutines of the unsubscribed subscriptions are gone.
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), baseline)
+
+	// a done subscription context terminates the subscription too.
+	subCtx, subCancel := context.WithCancel(ctx)
+	assert.NoError(t, s.SubscribeRaw(subCtx, "churn-subscription", handler))
+	subCancel()
+	done := make(chan struct{})
+	go func() {
+		s.subscriptionsWaitGroup.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "subscription not terminated")
+	}
+}
@@ -0,0 +1,31 @@
+package gcp
+
+import (
+	"context"
+	"sync"
+
+	"github.com/anthonycorbacho/workspace/kit/pubsub"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// topicRecorder records the topic carried by the context of the spans started.
+type topicRecorder struct {
+	mu     sync.Mutex
+	topics []string
+}
+
+func (r *topicRecorder) OnStart(parent context.Context, _ sdktrace.ReadWriteSpan) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.topics = append(r.topics, pubsub.GetTopic(parent))
+}
+
+func (r *topicRecorder) OnEnd(sdktrace.ReadOnlySpan)      {}
+func (r *topicRecorder) Shutdown(context.Context) error   { return nil }
+func (r *topicRecorder) ForceFlush(context.Context) error { return nil }
+
+func (r *topicRecorder) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.topics...)
+}
@@ -0,0 +1,226 @@
+// Package inmem provides an in-memory implementation of the pubsub publisher and subscriber.
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/anthonycorbacho/workspace/kit/pubsub"
+)
+
+var (
+	_ pubsub.Publisher  = (*PubSub)(nil)
+	_ pubsub.Subscriber = (*PubSub)(nil)
+)
+
+// Option defines a PubSub option.
+type Option func(*PubSub)
+
+// WithErrorHandler defines a function called each time a subscription handler returns an error.
+func WithErrorHandler(fn func(topic string, err error)) Option {
+	return func(p *PubSub) {
+		p.errorHandler = fn
+	}
+}
+
+// WithRetryPolicy settles the messages of the handlers registered with Subscribe based on their outcome:
+// acked on success, nacked for redelivery on a retryable error (see errors.IsRetryable) and acked (dropped) on any other error.
+// By default, Subscribe always acks the messages.
+func WithRetryPolicy() Option {
+	return func(p *PubSub) {
+		p.retryPolicy = true
+	}
+}
+
+type subscription struct {
+	ctx     context.Context
+	handler pubsub.HandlerWithAck
+}
+
+// PubSub is an in-memory publisher and subscriber.
+//
+// Messages published on a topic are delivered asynchronously to every subscription
+// registered with the same name as the topic. Nacked messages are redelivered,
+// the handlers get the delivery attempt with pubsub.DeliveryAttempt.
+// It is meant to be used in tests and single process applications, messages are not persisted.
+type PubSub struct {
+	subscriptions     map[string][]subscription
+	subscriptionsLock sync.RWMutex
+	closed            bool
+	closedLock        sync.RWMutex
+	closing           chan struct{}
+	inflight          sync.WaitGroup
+	errorHandler      func(topic string, err error)
+	retryPolicy       bool
+}
+
+// New creates a new in-memory PubSub.
+func New(opts ...Option) *PubSub {
+	p := &PubSub{
+		subscriptions: map[string][]subscription{},
+		closing:       make(chan struct{}),
+		errorHandler:  func(string, error) {},
+	}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// Close stops delivering messages and waits for the in-flight messages to be processed.
+func (p *PubSub) Close() error {
+	p.closedLock.Lock()
+	if p.closed {
+		p.closedLock.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.closing)
+	p.closedLock.Unlock()
+
+	p.inflight.Wait()
+	return nil
+}
+
+// Publish publishes a message to all the subscriptions of the topic.
+func (p *PubSub) Publish(ctx context.Context, topic string, msg pubsub.Message) error {
+	return p.PublishWithAttributes(ctx, topic, msg, nil)
+}
+
+// PublishWithAttributes publishes a message with attributes to all the subscriptions of the topic,
+// the handlers get them with pubsub.GetAttributes.
+func (p *PubSub) PublishWithAttributes(ctx context.Context, topic string, msg pubsub.Message, attrs map[string]string) error {
+	if len(topic) == 0 {
+		return fmt.Errorf("topic is nil")
+	}
+	if err := pubsub.ValidateAttributes(attrs); err != nil {
+		return err
+	}
+	if p.isClosed() {
+		return pubsub.PublisherClosed
+	}
+
+	p.subscriptionsLock.RLock()
+	subs := p.subscriptions[topic]
+	p.subscriptionsLock.RUnlock()
+
+	for _, sub := range subs {
+		// copy the message so handlers cannot alter each other data.
+		data := make(pubsub.Message, len(msg))
+		copy(data, msg)
+		p.deliver(topic, attrs, sub, data, 1)
+	}
+	return nil
+}
+
+// Subscribe registers a handler on the subscription, messages are always acked unless WithRetryPolicy is used.
+func (p *PubSub) Subscribe(ctx context.Context, subscription string, handler pubsub.Handler) error {
+	if p.retryPolicy {
+		return p.SubscribeWithAck(ctx, subscription, pubsub.RetryHandler(handler))
+	}
+
+	h := func(ctx context.Context, msg pubsub.Message, ack func(), nack func()) error {
+		// default behavior is to always ack.
+		ack()
+		return handler(ctx, msg)
+	}
+
+	return p.SubscribeWithAck(ctx, subscription, h)
+}
+
+// SubscribeWithAck registers a handler on the subscription.
+// The handler is receiving messages until ctx is cancelled or the PubSub is closed.
+func (p *PubSub) SubscribeWithAck(ctx context.Context, sub string, handler pubsub.HandlerWithAck) error {
+	if p.isClosed() {
+		return pubsub.SubscriberCLosed
+	}
+	if len(sub) == 0 {
+		return fmt.Errorf("subscription is nil")
+	}
+
+	p.subscriptionsLock.Lock()
+	p.subscriptions[sub] = append(p.subscriptions[sub], subscription{ctx: ctx, handler: handler})
+	p.subscriptionsLock.Unlock()
+	return nil
+}
+
+// SubscribeChan delivers the messages of the subscription on the returned channel, like SubscribeWithAck:
+// the receiver is responsible for acking (or nacking) them.
+// The channel is closed once ctx is done or the PubSub is closed, see pubsub.SubscribeChan.
+func (p *PubSub) SubscribeChan(ctx context.Context, sub string) (<-chan pubsub.ReceivedMessage, error) {
+	return pubsub.SubscribeChan(ctx, sub, p.closing, p.SubscribeWithAck)
+}
+
+// Unsubscribe removes all the handlers registered on the subscription.
+// Messages already being delivered are still processed.
+func (p *PubSub) Unsubscribe(sub string) error {
+	p.subscriptionsLock.Lock()
+	defer p.subscriptionsLock.Unlock()
+
+	if _, ok := p.subscriptions[sub]; !ok {
+		return pubsub.SubscriptionNotFound
+	}
+	delete(p.subscriptions, sub)
+	return nil
+}
+
+// deliver delivers the message to the subscription in its own goroutine, unless the PubSub is closed.
+// A nacked message is delivered again, even when nacked after the handler returned (eg: SubscribeChan).
+func (p *PubSub) deliver(topic string, attrs map[string]string, sub subscription, msg pubsub.Message, attempt int) {
+	// the in-flight messages are only added while not closed, so Close waits for all of them.
+	p.closedLock.RLock()
+	defer p.closedLock.RUnlock()
+	if p.closed {
+		return
+	}
+	p.inflight.Add(1)
+
+	go func() {
+		defer p.inflight.Done()
+
+		if sub.ctx.Err() != nil {
+			return
+		}
+
+		// a message nacked by the handler is delivered again once the handler returned.
+		var (
+			once      sync.Once
+			mu        sync.Mutex
+			returned  bool
+			redeliver bool
+		)
+		ack := func() { once.Do(func() {}) }
+		nack := func() {
+			once.Do(func() {
+				mu.Lock()
+				defer mu.Unlock()
+				if returned {
+					p.deliver(topic, attrs, sub, msg, attempt+1)
+					return
+				}
+				redeliver = true
+			})
+		}
+
+		ctx := pubsub.WithTopic(sub.ctx, topic)
+		ctx = pubsub.WithAttributes(ctx, attrs)
+		ctx = pubsub.WithDeliveryAttempt(ctx, attempt)
+		if err := sub.handler(ctx, msg, ack, nack); err != nil {
+			p.errorHandler(topic, err)
+		}
+
+		mu.Lock()
+		returned = true
+		mu.Unlock()
+		if redeliver {
+			p.deliver(topic, attrs, sub, msg, attempt+1)
+		}
+	}()
+}
+
+func (p *PubSub) isClosed() bool {
+	p.closedLock.RLock()
+	defer p.closedLock.RUnlock()
+	return p.closed
+}
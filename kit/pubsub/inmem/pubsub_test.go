@@ -0,0 +1,238 @@
+package inmem
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/pubsub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishAndSubscribe(t *testing.T) {
+	ps := New()
+	ctx := context.Background()
+
+	ch := make(chan string, 1)
+	err := ps.Subscribe(ctx, "a.topic", func(ctx context.Context, msg pubsub.Message) error {
+		ch <- pubsub.GetTopic(ctx) + ":" + msg.String()
+		return nil
+	})
+	assert.NoError(t, err)
+
+	err = ps.Publish(ctx, "a.topic", []byte("test"))
+	assert.NoError(t, err)
+
+	select {
+	case result := <-ch:
+		assert.Equal(t, "a.topic:test", result)
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout waiting")
+	}
+
+	assert.NoError(t, ps.Close())
+	assert.Equal(t, pubsub.PublisherClosed, ps.Publish(ctx, "a.topic", []byte("closed")))
+}
+
+func TestPublishWithAttributes(t *testing.T) {
+	ps := New()
+	defer ps.Close()
+	ctx := context.Background()
+
+	ch := make(chan map[string]string, 1)
+	err := ps.Subscribe(ctx, "a.topic", func(ctx context.Context, msg pubsub.Message) error {
+		ch <- pubsub.GetAttributes(ctx)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	err = ps.PublishWithAttributes(ctx, "a.topic", []byte("test"), map[string]string{"tenant": "acme"})
+	assert.NoError(t, err)
+
+	select {
+	case attrs := <-ch:
+		assert.Equal(t, map[string]string{"tenant": "acme"}, attrs)
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout waiting")
+	}
+
+	err = ps.PublishWithAttributes(ctx, "a.topic", []byte("test"), map[string]string{"topic": "other"})
+	assert.True(t, errors.Is(err, pubsub.ReservedAttribute))
+}
+
+func TestNackRedelivery(t *testing.T) {
+	ps := New()
+	defer ps.Close()
+	ctx := context.Background()
+
+	attempts := make(chan int, 2)
+	count := 0
+	err := ps.SubscribeWithAck(ctx, "a.topic", func(ctx context.Context, msg pubsub.Message, ack func(), nack func()) error {
+		count++
+		// the redeliveries are counted.
+		if attempt, ok := pubsub.DeliveryAttempt(ctx); assert.True(t, ok) {
+			assert.Equal(t, count, attempt)
+		}
+		attempts <- count
+		if count == 1 {
+			nack()
+			return nil
+		}
+		ack()
+		return nil
+	})
+	assert.NoError(t, err)
+
+	err = ps.Publish(ctx, "a.topic", []byte("test"))
+	assert.NoError(t, err)
+
+	for want := 1; want <= 2; want++ {
+		select {
+		case got := <-attempts:
+			assert.Equal(t, want, got)
+		case <-time.After(time.Second):
+			assert.Fail(t, "timeout waiting")
+		}
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	ps := New()
+	defer ps.Close()
+	ctx := context.Background()
+
+	a := make(chan string, 1)
+	b := make(chan string, 1)
+	assert.NoError(t, ps.Subscribe(ctx, "a.topic", func(ctx context.Context, msg pubsub.Message) error {
+		a <- msg.String()
+		return nil
+	}))
+	assert.NoError(t, ps.Subscribe(ctx, "b.topic", func(ctx context.Context, msg pubsub.Message) error {
+		b <- msg.String()
+		return nil
+	}))
+
+	assert.NoError(t, ps.Unsubscribe("a.topic"))
+	assert.Equal(t, pubsub.SubscriptionNotFound, ps.Unsubscribe("a.topic"))
+
+	assert.NoError(t, ps.Publish(ctx, "a.topic", []byte("a")))
+	assert.NoError(t, ps.Publish(ctx, "b.topic", []byte("b")))
+
+	select {
+	case result := <-b:
+		assert.Equal(t, "b", result)
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout waiting")
+	}
+	select {
+	case result := <-a:
+		assert.Fail(t, "unsubscribed handler received message: "+result)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	ps := New(WithRetryPolicy())
+	defer ps.Close()
+	ctx := context.Background()
+
+	// retryable error, redelivered until success.
+	retried := make(chan int, 3)
+	count := 0
+	err := ps.Subscribe(ctx, "retryable.topic", func(ctx context.Context, msg pubsub.Message) error {
+		count++
+		retried <- count
+		if count == 1 {
+			return errors.Retryable(errors.New("temporarily unavailable"))
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	// non retryable error, acked and not redelivered.
+	dropped := make(chan string, 2)
+	err = ps.Subscribe(ctx, "invalid.topic", func(ctx context.Context, msg pubsub.Message) error {
+		dropped <- msg.String()
+		return errors.New("invalid message")
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, ps.Publish(ctx, "retryable.topic", []byte("test")))
+	assert.NoError(t, ps.Publish(ctx, "invalid.topic", []byte("test")))
+
+	for want := 1; want <= 2; want++ {
+		select {
+		case attempt := <-retried:
+			assert.Equal(t, want, attempt)
+		case <-time.After(time.Second):
+			assert.Fail(t, "timeout waiting for redelivery")
+		}
+	}
+	select {
+	case <-dropped:
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout waiting")
+	}
+
+	select {
+	case attempt := <-retried:
+		assert.Fail(t, "unexpected redelivery", attempt)
+	case <-dropped:
+		assert.Fail(t, "non retryable message redelivered")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSubscribeChan(t *testing.T) {
+	ps := New()
+	ctx := context.Background()
+
+	ch, err := ps.SubscribeChan(ctx, "a.topic")
+	assert.NoError(t, err)
+
+	err = ps.PublishWithAttributes(ctx, "a.topic", []byte("test"), map[string]string{"tenant": "acme"})
+	assert.NoError(t, err)
+
+	// the message nacked after being received is redelivered.
+	for _, nack := range []bool{true, false} {
+		select {
+		case m := <-ch:
+			assert.Equal(t, "test", m.Data.String())
+			assert.Equal(t, "a.topic", m.Topic)
+			assert.Equal(t, map[string]string{"tenant": "acme"}, m.Attributes)
+			if nack {
+				m.Nack()
+			} else {
+				m.Ack()
+			}
+		case <-time.After(time.Second):
+			assert.Fail(t, "timeout waiting")
+		}
+	}
+
+	assert.NoError(t, ps.Close())
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout waiting for the channel to close")
+	}
+}
+
+func TestSubscribeChan_Cancel(t *testing.T) {
+	ps := New()
+	defer ps.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ch, err := ps.SubscribeChan(ctx, "a.topic")
+	assert.NoError(t, err)
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout waiting for the channel to close")
+	}
+}
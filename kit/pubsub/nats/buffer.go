@@ -0,0 +1,122 @@
+package nats
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/pubsub"
+	nats "github.com/nats-io/nats.go"
+)
+
+// WithReconnectBuffer buffers, up to size messages, the messages published while the connection
+// to NATS is lost (eg: during a reconnection) and publishes them once reconnected.
+//
+// Publish returns pubsub.PublishBufferFull when the buffer is full, the buffered messages
+// that cannot be published within deadline are dropped and reported with pubsub.PublishBufferTimeout
+// to the error handler, see WithPublishErrorHandler.
+// By default, publishing fails while the connection is lost.
+func WithReconnectBuffer(size int, deadline time.Duration) PublisherOption {
+	return func(p *Publisher) {
+		if size > 0 && deadline > 0 {
+			p.buffer = make(chan bufferedMsg, size)
+			p.bufferDeadline = deadline
+		}
+	}
+}
+
+// bufferedMsg is a message published while the connection was lost.
+type bufferedMsg struct {
+	msg    *nats.Msg
+	queued time.Time
+}
+
+// reconnectPollInterval is the interval at which the connection is checked while buffering.
+const reconnectPollInterval = 50 * time.Millisecond
+
+// buffering returns true if the message should be buffered instead of published.
+// Once messages are buffered, the following ones are buffered too to keep the publication order.
+func (p *Publisher) buffering() bool {
+	return p.buffer != nil && (!p.nc.IsConnected() || atomic.LoadInt32(&p.buffered) > 0)
+}
+
+// bufferMsg adds the message to the buffer, pubsub.PublishBufferFull is returned if the buffer is full
+// and pubsub.PublisherClosed once the buffer is stopped.
+//
+// The stop check and the enqueue hold the buffer lock, also held to stop the buffer (see stopBuffer):
+// the messages are queued before the flusher drains the buffer.
+func (p *Publisher) bufferMsg(msg *nats.Msg) error {
+	p.bufferLock.Lock()
+	defer p.bufferLock.Unlock()
+	select {
+	case <-p.stop:
+		return pubsub.PublisherClosed
+	default:
+	}
+	if int(atomic.AddInt32(&p.buffered, 1)) > cap(p.buffer) {
+		atomic.AddInt32(&p.buffered, -1)
+		return pubsub.PublishBufferFull
+	}
+	p.buffer <- bufferedMsg{msg: msg, queued: time.Now()}
+	return nil
+}
+
+// stopBuffer stops the buffer and waits for the flusher to publish (or report) the buffered messages.
+func (p *Publisher) stopBuffer() {
+	p.bufferLock.Lock()
+	p.stopOnce.Do(func() { close(p.stop) })
+	p.bufferLock.Unlock()
+	<-p.flushed
+}
+
+// flushBuffer publishes the buffered messages once reconnected, until the Publisher is closed.
+func (p *Publisher) flushBuffer() {
+	defer close(p.flushed)
+
+	for {
+		select {
+		case <-p.stop:
+			// no message is queued anymore, publish what can be: the remaining messages
+			// are reported to the error handler (see flush).
+			for {
+				select {
+				case m := <-p.buffer:
+					p.flush(m)
+				default:
+					return
+				}
+			}
+		case m := <-p.buffer:
+			p.flush(m)
+		}
+	}
+}
+
+// flush publishes the buffered message once connected, or drops it when the deadline is exceeded.
+func (p *Publisher) flush(m bufferedMsg) {
+	defer atomic.AddInt32(&p.buffered, -1)
+
+	ticker := time.NewTicker(reconnectPollInterval)
+	defer ticker.Stop()
+	for !p.nc.IsConnected() {
+		if time.Since(m.queued) > p.bufferDeadline {
+			p.errorHandler(m.msg.Subject, errors.Wrapf(pubsub.PublishBufferTimeout, "connection lost for more than %s", p.bufferDeadline))
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-p.stop:
+			if !p.nc.IsConnected() {
+				p.errorHandler(m.msg.Subject, errors.Wrap(pubsub.PublisherClosed, "connection lost"))
+				return
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.publishTimeout)
+	defer cancel()
+	if _, err := p.js.PublishMsg(m.msg, nats.Context(ctx)); err != nil {
+		p.errorHandler(m.msg.Subject, err)
+	}
+}
@@ -0,0 +1,91 @@
+package nats
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/pubsub"
+	nats "github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconnectBuffer_Disconnected(t *testing.T) {
+	var mu sync.Mutex
+	var failures []error
+	// a zero connection is disconnected.
+	p, err := NewPublisher(&nats.Conn{}, &asyncJetStream{},
+		WithReconnectBuffer(2, 100*time.Millisecond),
+		WithPublishErrorHandler(func(_ string, err error) {
+			mu.Lock()
+			failures = append(failures, err)
+			mu.Unlock()
+		}),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ctx := context.Background()
+	assert.NoError(t, p.Publish(ctx, "outage", []byte("first")))
+	assert.NoError(t, p.Publish(ctx, "outage", []byte("second")))
+	// the buffer is bounded.
+	assert.ErrorIs(t, p.Publish(ctx, "outage", []byte("third")), pubsub.PublishBufferFull)
+
+	// the outage exceeds the deadline, buffered messages are dropped.
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(failures) == 2
+	}, time.Second, 10*time.Millisecond)
+	mu.Lock()
+	for _, err := range failures {
+		assert.True(t, errors.Is(err, pubsub.PublishBufferTimeout))
+	}
+	mu.Unlock()
+
+	// the buffer is available again.
+	assert.NoError(t, p.Publish(ctx, "outage", []byte("fourth")))
+}
+
+func TestReconnectBuffer_Stop(t *testing.T) {
+	var reported atomic.Int32
+	// a zero connection is disconnected.
+	p, err := NewPublisher(&nats.Conn{}, &asyncJetStream{},
+		WithReconnectBuffer(1000, time.Hour),
+		WithPublishErrorHandler(func(_ string, err error) {
+			assert.True(t, errors.Is(err, pubsub.PublisherClosed))
+			reported.Add(1)
+		}),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// messages are buffered while the buffer stops.
+	var rejected atomic.Int32
+	var wg sync.WaitGroup
+	const workers, perWorker = 8, 50
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				if err := p.bufferMsg(&nats.Msg{Subject: "outage"}); err != nil {
+					assert.ErrorIs(t, err, pubsub.PublisherClosed)
+					rejected.Add(1)
+				}
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond)
+	p.stopBuffer()
+	wg.Wait()
+
+	// each message is either rejected or reported, none is dropped silently.
+	assert.Equal(t, int32(workers*perWorker), rejected.Load()+reported.Load())
+	assert.Zero(t, atomic.LoadInt32(&p.buffered))
+}
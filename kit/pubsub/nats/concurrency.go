@@ -0,0 +1,67 @@
+package nats
+
+import (
+	"context"
+
+	nats "github.com/nats-io/nats.go"
+)
+
+// WithMaxConcurrency handles up to n messages at once across the subscriptions of the subscriber,
+// the next messages wait for a running handler to finish before being handled.
+// The messages waiting once the subscriber is closing or the subscription context is done are nacked for redelivery.
+//
+// By default, the messages of a subscription are handled one at a time, in the order they are delivered.
+// Enabling this option makes the handling concurrent: the messages of a subscription are no longer handled
+// in order, so the handlers relying on the ordering must not use it alone.
+//
+// Combined with WithOrderedPerSubject, the ordered workers handle the messages within the same n slots:
+// it bounds the subjects handled at once, and the messages of a subject are still handled in order.
+func WithMaxConcurrency(n int) SubscriberOption {
+	return func(s *Subscriber) {
+		if n > 0 {
+			s.slots = make(chan struct{}, n)
+		}
+	}
+}
+
+// receiveConcurrently handles the message in its own goroutine once a slot is available, see WithMaxConcurrency.
+func (s *Subscriber) receiveConcurrently(ctx context.Context, subscription string, msg *nats.Msg, handler RawHandler) {
+	if !s.acquire(ctx, msg) {
+		return
+	}
+	go func() {
+		defer s.release()
+		s.receive(ctx, subscription, msg, handler)
+	}()
+}
+
+// receiveInSlot handles the message once a slot is available, see WithMaxConcurrency.
+func (s *Subscriber) receiveInSlot(ctx context.Context, subscription string, msg *nats.Msg, handler RawHandler) {
+	if !s.acquire(ctx, msg) {
+		return
+	}
+	defer s.release()
+	s.receive(ctx, subscription, msg, handler)
+}
+
+// acquire waits for a slot to handle the message, it nacks the message if the subscriber
+// is closing or ctx is done first. Without concurrency limit, a slot is always available.
+func (s *Subscriber) acquire(ctx context.Context, msg *nats.Msg) bool {
+	if s.slots == nil {
+		return true
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	case <-s.closing:
+	case <-ctx.Done():
+	}
+	msg.Nak()
+	return false
+}
+
+func (s *Subscriber) release() {
+	if s.slots != nil {
+		<-s.slots
+	}
+}
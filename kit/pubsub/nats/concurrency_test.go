@@ -0,0 +1,86 @@
+package nats
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxConcurrency(t *testing.T) {
+	s, err := NewSubscriber("group", &nats.Conn{}, &asyncJetStream{}, &nats.ConsumerInfo{}, WithMaxConcurrency(3))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 3, cap(s.slots))
+
+	const count = 30
+	var (
+		wg          sync.WaitGroup
+		concurrent  int32
+		maxParallel int32
+	)
+	wg.Add(count)
+	handler := func(ctx context.Context, msg *nats.Msg) error {
+		defer wg.Done()
+		n := atomic.AddInt32(&concurrent, 1)
+		defer atomic.AddInt32(&concurrent, -1)
+		for {
+			max := atomic.LoadInt32(&maxParallel)
+			if n <= max || atomic.CompareAndSwapInt32(&maxParallel, max, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}
+
+	// a flood of messages, delivered one at a time like the NATS subscription callback.
+	for i := 0; i < count; i++ {
+		s.receiveConcurrently(context.Background(), "orders", &nats.Msg{Subject: "orders"}, handler)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&maxParallel))
+}
+
+func TestMaxConcurrency_Cancelled(t *testing.T) {
+	s, err := NewSubscriber("group", &nats.Conn{}, &asyncJetStream{}, &nats.ConsumerInfo{}, WithMaxConcurrency(1))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// the only slot is taken.
+	release := make(chan struct{})
+	s.receiveConcurrently(context.Background(), "orders", &nats.Msg{Subject: "orders"}, func(ctx context.Context, msg *nats.Msg) error {
+		<-release
+		return nil
+	})
+	defer close(release)
+
+	// the waiting message is given up (nacked) once the subscription context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	handled := false
+	s.receiveConcurrently(ctx, "orders", &nats.Msg{Subject: "orders"}, func(ctx context.Context, msg *nats.Msg) error {
+		handled = true
+		return nil
+	})
+	assert.False(t, handled)
+	assert.Len(t, s.slots, 1)
+}
+
+func TestMaxConcurrencyOption(t *testing.T) {
+	s, err := NewSubscriber("group", &nats.Conn{}, &asyncJetStream{}, &nats.ConsumerInfo{})
+	assert.NoError(t, err)
+	assert.Nil(t, s.slots)
+
+	// invalid limits are ignored.
+	s, err = NewSubscriber("group", &nats.Conn{}, &asyncJetStream{}, &nats.ConsumerInfo{}, WithMaxConcurrency(0))
+	assert.NoError(t, err)
+	assert.Nil(t, s.slots)
+}
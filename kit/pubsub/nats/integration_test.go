@@ -3,11 +3,16 @@ package nats
 import (
 	"context"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
 	"os"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/anthonycorbacho/workspace/kit/errors"
 	"github.com/anthonycorbacho/workspace/kit/log"
 	"github.com/anthonycorbacho/workspace/kit/pubsub"
 	"github.com/nats-io/nats.go"
@@ -157,6 +162,319 @@ func (n *natsTestSuite) TestPublishAndSubscribe() {
 	}
 }
 
+func (n *natsTestSuite) TestPublishMaxMessageSize() {
+	p, err := NewPublisher(n.nc, n.js, WithMaxMessageSize(8))
+	if err != nil {
+		n.T().Fatalf("setting up publisher: %v", err)
+	}
+
+	// configured limit
+	err = p.Publish(n.ctx, testDefaultSubject, []byte("more than 8 bytes"))
+	assert.True(n.T(), errors.Is(err, pubsub.MessageTooLarge))
+
+	// server limit
+	err = n.p.Publish(n.ctx, testDefaultSubject, make([]byte, n.nc.MaxPayload()+1))
+	assert.True(n.T(), errors.Is(err, pubsub.MessageTooLarge))
+}
+
+func (n *natsTestSuite) TestPublishCancelled() {
+	p, err := NewPublisher(n.nc, n.js, WithPublishTimeout(time.Second))
+	if err != nil {
+		n.T().Fatalf("setting up publisher: %v", err)
+	}
+
+	// the caller cancellation is honored.
+	ctx, cancel := context.WithCancel(n.ctx)
+	cancel()
+	err = p.Publish(ctx, testDefaultSubject, []byte(test))
+	assert.ErrorIs(n.T(), err, context.Canceled)
+}
+
+func (n *natsTestSuite) TestUnsubscribe() {
+	// Given
+	const testUnsubscribeSubject = "test.unsubscribe"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addr, _ := os.LookupEnv("TESTINGNATS_URL")
+	js, nc, err := New(addr)
+	if err != nil {
+		n.T().Fatalf("setting up nats server failed: %v", err)
+	}
+	defer nc.Close()
+	consumer, err := n.js.AddConsumer(test, &nats.ConsumerConfig{
+		Durable:        test + "unsubscribe",
+		FilterSubject:  testUnsubscribeSubject,
+		AckPolicy:      nats.AckExplicitPolicy,
+		DeliverSubject: testDeliverySubject + "unsubscribe",
+		DeliverGroup:   testGroup + "unsubscribe",
+	})
+	if err != nil {
+		n.T().Fatalf("setting up consumer: %v", err)
+	}
+	s, err := NewSubscriber(testGroup+"unsubscribe", nc, js, consumer)
+	if err != nil {
+		n.T().Fatalf("setting up subscriber: %v", err)
+	}
+
+	// both subscriptions share the consumer messages.
+	a := make(chan string, 10)
+	b := make(chan string, 10)
+	err = s.Subscribe(ctx, testUnsubscribeSubject+".a", func(ctx context.Context, msg pubsub.Message) error {
+		a <- string(msg)
+		return nil
+	})
+	n.Require().NoError(err)
+	err = s.Subscribe(ctx, testUnsubscribeSubject+".b", func(ctx context.Context, msg pubsub.Message) error {
+		b <- string(msg)
+		return nil
+	})
+	n.Require().NoError(err)
+
+	// When
+	n.NoError(s.Unsubscribe(testUnsubscribeSubject + ".a"))
+	n.Equal(pubsub.SubscriptionNotFound, s.Unsubscribe(testUnsubscribeSubject+".a"))
+
+	// Then the remaining subscription receives all the messages.
+	for i := 0; i < 5; i++ {
+		n.NoError(n.p.Publish(ctx, testUnsubscribeSubject, []byte(fmt.Sprintf("msg %d", i))))
+	}
+	for i := 0; i < 5; i++ {
+		select {
+		case <-b:
+		case result := <-a:
+			n.Fail("unsubscribed handler received message: " + result)
+		case <-time.After(time.Second):
+			n.Fail("timeout waiting")
+		}
+	}
+	n.NoError(s.Close())
+}
+
+func (n *natsTestSuite) TestHandlerTimeout() {
+	// Given
+	const testTimeoutSubject = "test.timeout"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addr, _ := os.LookupEnv("TESTINGNATS_URL")
+	js, nc, err := New(addr)
+	if err != nil {
+		n.T().Fatalf("setting up nats server failed: %v", err)
+	}
+	defer nc.Close()
+	consumer, err := n.js.AddConsumer(test, &nats.ConsumerConfig{
+		Durable:        test + "timeout",
+		FilterSubject:  testTimeoutSubject,
+		AckPolicy:      nats.AckExplicitPolicy,
+		DeliverSubject: testDeliverySubject + "timeout",
+		DeliverGroup:   testGroup + "timeout",
+	})
+	if err != nil {
+		n.T().Fatalf("setting up consumer: %v", err)
+	}
+	s, err := NewSubscriber(testGroup+"timeout", nc, js, consumer, WithHandlerTimeout(100*time.Millisecond))
+	if err != nil {
+		n.T().Fatalf("setting up subscriber: %v", err)
+	}
+
+	// the first delivery exceeds the timeout, the redelivery is acked in time.
+	deliveries := make(chan int, 10)
+	var attempt int32
+	err = s.SubscribeWithAck(ctx, testTimeoutSubject, func(ctx context.Context, msg pubsub.Message, ack func(), nack func()) error {
+		current := atomic.AddInt32(&attempt, 1)
+		if current == 1 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		ack()
+		deliveries <- int(current)
+		return nil
+	})
+	n.Require().NoError(err)
+
+	// When
+	n.NoError(n.p.Publish(ctx, testTimeoutSubject, []byte(test)))
+
+	// Then
+	select {
+	case current := <-deliveries:
+		n.Equal(2, current)
+	case <-time.After(3 * time.Second):
+		n.Fail("timeout waiting for redelivery")
+	}
+	n.NoError(s.Close())
+}
+
+func (n *natsTestSuite) TestSubscribeWithAck() {
+	// Given
+	const testAckSubject = "test.ack"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addr, _ := os.LookupEnv("TESTINGNATS_URL")
+	js, nc, err := New(addr)
+	if err != nil {
+		n.T().Fatalf("setting up nats server failed: %v", err)
+	}
+	defer nc.Close()
+	consumer, err := n.js.AddConsumer(test, &nats.ConsumerConfig{
+		Durable:        test + "ack",
+		FilterSubject:  testAckSubject,
+		AckPolicy:      nats.AckExplicitPolicy,
+		DeliverSubject: testDeliverySubject + "ack",
+		DeliverGroup:   testGroup + "ack",
+	})
+	if err != nil {
+		n.T().Fatalf("setting up consumer: %v", err)
+	}
+	s, err := NewSubscriber(testGroup+"ack", nc, js, consumer)
+	if err != nil {
+		n.T().Fatalf("setting up subscriber: %v", err)
+	}
+
+	// the first delivery is nacked, the redelivery is acked.
+	deliveries := make(chan int, 10)
+	var attempt int32
+	err = s.SubscribeWithAck(ctx, testAckSubject, func(ctx context.Context, msg pubsub.Message, ack func(), nack func()) error {
+		current := atomic.AddInt32(&attempt, 1)
+		n.Equal(testAckSubject, pubsub.GetTopic(ctx))
+		// the delivery attempt comes from the JetStream metadata.
+		delivered, ok := pubsub.DeliveryAttempt(ctx)
+		n.True(ok)
+		n.Equal(int(current), delivered)
+		if current == 1 {
+			nack()
+			return nil
+		}
+		ack()
+		deliveries <- int(current)
+		return nil
+	})
+	n.Require().NoError(err)
+
+	// When
+	n.NoError(n.p.Publish(ctx, testAckSubject, []byte(test)))
+
+	// Then
+	select {
+	case current := <-deliveries:
+		n.Equal(2, current)
+	case <-time.After(3 * time.Second):
+		n.Fail("timeout waiting for redelivery")
+	}
+
+	// the acked message is not redelivered.
+	select {
+	case current := <-deliveries:
+		n.Failf("acked message redelivered", "delivery %d", current)
+	case <-time.After(500 * time.Millisecond):
+	}
+	n.NoError(s.Close())
+}
+
+func (n *natsTestSuite) TestOrderedPerSubject() {
+	// Given
+	const testOrderedSubject = "test.ordered"
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	addr, _ := os.LookupEnv("TESTINGNATS_URL")
+	js, nc, err := New(addr)
+	if err != nil {
+		n.T().Fatalf("setting up nats server failed: %v", err)
+	}
+	defer nc.Close()
+	consumer, err := n.js.AddConsumer(test, &nats.ConsumerConfig{
+		Durable:        test + "ordered",
+		FilterSubject:  testOrderedSubject,
+		AckPolicy:      nats.AckExplicitPolicy,
+		DeliverSubject: testDeliverySubject + "ordered",
+		DeliverGroup:   testGroup + "ordered",
+	})
+	if err != nil {
+		n.T().Fatalf("setting up consumer: %v", err)
+	}
+	s, err := NewSubscriber(testGroup+"ordered", nc, js, consumer, WithOrderedPerSubject())
+	if err != nil {
+		n.T().Fatalf("setting up subscriber: %v", err)
+	}
+
+	const count = 50
+	received := make(chan string, count)
+	err = s.SubscribeWithAck(ctx, testOrderedSubject, func(ctx context.Context, msg pubsub.Message, ack func(), nack func()) error {
+		time.Sleep(time.Millisecond)
+		ack()
+		received <- string(msg)
+		return nil
+	})
+	n.Require().NoError(err)
+
+	// When
+	for i := 0; i < count; i++ {
+		n.NoError(n.p.Publish(ctx, testOrderedSubject, []byte(fmt.Sprint(i))))
+	}
+
+	// Then
+	for i := 0; i < count; i++ {
+		select {
+		case msg := <-received:
+			n.Equal(fmt.Sprint(i), msg)
+		case <-ctx.Done():
+			n.FailNow("timeout waiting for the ordered messages")
+		}
+	}
+	n.NoError(s.Close())
+}
+
+func (n *natsTestSuite) TestSubscribeRaw() {
+	// Given
+	const testRawSubject = "test.raw"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addr, _ := os.LookupEnv("TESTINGNATS_URL")
+	js, nc, err := New(addr)
+	if err != nil {
+		n.T().Fatalf("setting up nats server failed: %v", err)
+	}
+	defer nc.Close()
+	consumer, err := n.js.AddConsumer(test, &nats.ConsumerConfig{
+		Durable:        test + "raw",
+		FilterSubject:  testRawSubject,
+		AckPolicy:      nats.AckExplicitPolicy,
+		DeliverSubject: testDeliverySubject + "raw",
+		DeliverGroup:   testGroup + "raw",
+	})
+	if err != nil {
+		n.T().Fatalf("setting up consumer: %v", err)
+	}
+	s, err := NewSubscriber(testGroup+"raw", nc, js, consumer)
+	if err != nil {
+		n.T().Fatalf("setting up subscriber: %v", err)
+	}
+
+	received := make(chan *nats.Msg, 1)
+	err = s.SubscribeRaw(ctx, testRawSubject, func(ctx context.Context, msg *nats.Msg) error {
+		received <- msg
+		return msg.Ack()
+	})
+	n.Require().NoError(err)
+
+	// When
+	n.NoError(n.p.Publish(ctx, testRawSubject, []byte(test)))
+
+	// Then the provider fields are intact.
+	select {
+	case msg := <-received:
+		n.Equal(testRawSubject, msg.Subject)
+		n.Equal([]byte(test), msg.Data)
+		n.Equal(testRawSubject, msg.Header.Get("subject"))
+		meta, err := msg.Metadata()
+		n.Require().NoError(err)
+		n.Equal(test, meta.Stream)
+		n.Equal(uint64(1), meta.NumDelivered)
+	case <-time.After(time.Second):
+		n.Fail("timeout waiting")
+	}
+	n.NoError(s.Close())
+}
+
 func (n *natsTestSuite) checkHeaders(msg *nats.Msg) {
 	expectedHeaders := [5]string{"subject", "trace", "span", "trace-state", "trace-remote"}
 	for _, h := range expectedHeaders {
@@ -228,3 +546,131 @@ func (n *natsTestSuite) TestClosedStates() {
 	err = p.Publish(ctx, testClosingSubject, []byte("test closed publisher"))
 	assert.Error(n.T(), pubsub.PublisherClosed, err)
 }
+
+func (n *natsTestSuite) TestPublishAsync() {
+	// Given
+	addr, _ := os.LookupEnv("TESTINGNATS_URL")
+	js, nc, err := New(addr)
+	if err != nil {
+		n.T().Fatalf("setting up nats server failed: %v", err)
+	}
+	var failures int32
+	p, err := NewPublisher(nc, js, WithAsyncPublish(4), WithPublishErrorHandler(func(string, error) {
+		atomic.AddInt32(&failures, 1)
+	}))
+	n.Require().NoError(err)
+
+	// When a burst is published
+	for i := 0; i < 50; i++ {
+		n.NoError(p.Publish(n.ctx, "test.async", []byte(fmt.Sprintf("msg %d", i))))
+	}
+
+	// Then all the messages are acknowledged once closed.
+	n.NoError(p.Close())
+	n.Equal(int32(0), atomic.LoadInt32(&failures))
+}
+
+// proxy forwards TCP connections to the NATS server, to simulate connection losses.
+type proxy struct {
+	listener net.Listener
+	target   string
+	mu       sync.Mutex
+	conns    []net.Conn
+	down     bool
+}
+
+func newProxy(target string) (*proxy, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	p := &proxy{listener: l, target: target}
+	go p.serve()
+	return p, nil
+}
+
+func (p *proxy) serve() {
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		p.mu.Lock()
+		down := p.down
+		p.mu.Unlock()
+		if down {
+			_ = conn.Close()
+			continue
+		}
+		upstream, err := net.Dial("tcp", p.target)
+		if err != nil {
+			_ = conn.Close()
+			continue
+		}
+		p.mu.Lock()
+		p.conns = append(p.conns, conn, upstream)
+		p.mu.Unlock()
+		go func() { _, _ = io.Copy(upstream, conn) }()
+		go func() { _, _ = io.Copy(conn, upstream) }()
+	}
+}
+
+// setDown drops the established connections and refuses the new ones while down.
+func (p *proxy) setDown(down bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.down = down
+	if down {
+		for _, c := range p.conns {
+			_ = c.Close()
+		}
+		p.conns = nil
+	}
+}
+
+func (p *proxy) Close() {
+	_ = p.listener.Close()
+	p.setDown(true)
+}
+
+func (n *natsTestSuite) TestReconnectBuffer() {
+	// Given a publisher connected through a proxy
+	const testBufferSubject = "test.buffer"
+	addr, _ := os.LookupEnv("TESTINGNATS_URL")
+	u, err := url.Parse(addr)
+	n.Require().NoError(err)
+	px, err := newProxy(u.Host)
+	n.Require().NoError(err)
+	defer px.Close()
+
+	js, nc, err := New("nats://" + px.listener.Addr().String())
+	n.Require().NoError(err)
+	var failures int32
+	p, err := NewPublisher(nc, js, WithReconnectBuffer(10, 30*time.Second), WithPublishErrorHandler(func(string, error) {
+		atomic.AddInt32(&failures, 1)
+	}))
+	n.Require().NoError(err)
+
+	sub, err := n.js.PullSubscribe(testBufferSubject, "buffer")
+	n.Require().NoError(err)
+	defer sub.Unsubscribe()
+
+	// When the connection is lost
+	px.setDown(true)
+	n.Eventually(func() bool { return !nc.IsConnected() }, 5*time.Second, 10*time.Millisecond)
+	for i := 0; i < 5; i++ {
+		n.NoError(p.Publish(n.ctx, testBufferSubject, []byte(fmt.Sprintf("msg %d", i))))
+	}
+	px.setDown(false)
+
+	// Then the messages published during the outage are delivered after reconnect.
+	msgs, err := sub.Fetch(5, nats.MaxWait(10*time.Second))
+	n.Require().NoError(err)
+	n.Len(msgs, 5)
+	for i, msg := range msgs {
+		n.Equal(fmt.Sprintf("msg %d", i), string(msg.Data))
+		n.NoError(msg.Ack())
+	}
+	n.NoError(p.Close())
+	n.Equal(int32(0), atomic.LoadInt32(&failures))
+}
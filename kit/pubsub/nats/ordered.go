@@ -0,0 +1,111 @@
+package nats
+
+import (
+	"context"
+	"hash/fnv"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+)
+
+const (
+	// orderedWorkers is the number of workers handling the messages of a subscription with WithOrderedPerSubject.
+	orderedWorkers = 16
+	// orderedQueueSize is the number of messages waiting for each worker before the delivery blocks.
+	orderedQueueSize = 64
+	// drainPollInterval is the interval between two checks of the end of a subscription drain.
+	drainPollInterval = 50 * time.Millisecond
+)
+
+// WithOrderedPerSubject handles the messages of a subscription concurrently across subjects
+// (eg: `orders.1` and `orders.2` of a subscription to `orders.*`) while the messages of the same subject
+// are handled one at a time, in the order they are delivered.
+//
+// By default, the messages of a subscription are handled one at a time, whatever their subject.
+func WithOrderedPerSubject() SubscriberOption {
+	return func(s *Subscriber) {
+		s.orderedPerSubject = true
+	}
+}
+
+// orderedDispatcher dispatches the messages of a subscription to workers by subject.
+type orderedDispatcher struct {
+	queues []chan *nats.Msg
+	stop   chan struct{}
+}
+
+// newOrderedDispatcher starts the workers handling the messages of the subscription,
+// they stop once the pending messages are handled after the dispatcher is stopped, the subscriber is closing
+// or ctx is done.
+func (s *Subscriber) newOrderedDispatcher(ctx context.Context, subscription string, handler RawHandler) *orderedDispatcher {
+	d := &orderedDispatcher{
+		queues: make([]chan *nats.Msg, orderedWorkers),
+		stop:   make(chan struct{}),
+	}
+	for i := range d.queues {
+		queue := make(chan *nats.Msg, orderedQueueSize)
+		d.queues[i] = queue
+		go func() {
+			for {
+				select {
+				case msg := <-queue:
+					s.receiveInSlot(ctx, subscription, msg, handler)
+					continue
+				case <-d.stop:
+				case <-s.closing:
+				case <-ctx.Done():
+				}
+
+				// the pending messages are handled (or nacked once closing or ctx is done) before stopping.
+				for {
+					select {
+					case msg := <-queue:
+						s.receiveInSlot(ctx, subscription, msg, handler)
+					default:
+						return
+					}
+				}
+			}
+		}()
+	}
+	return d
+}
+
+// dispatch queues the message to the worker of its subject, blocking while the worker queue is full.
+// The messages delivered once the subscriber is closing, ctx is done or the dispatcher stopped are nacked for redelivery.
+func (d *orderedDispatcher) dispatch(ctx context.Context, msg *nats.Msg, closing <-chan struct{}) {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(msg.Subject))
+	queue := d.queues[h.Sum32()%uint32(len(d.queues))]
+
+	select {
+	case <-closing:
+		msg.Nak()
+		return
+	case <-ctx.Done():
+		msg.Nak()
+		return
+	default:
+	}
+
+	select {
+	case queue <- msg:
+	case <-closing:
+		msg.Nak()
+	case <-ctx.Done():
+		msg.Nak()
+	case <-d.stop:
+		msg.Nak()
+	}
+}
+
+// stopAfterDrain stops the dispatcher once the drained subscription is closed,
+// letting the workers handle the messages delivered during the drain.
+func (d *orderedDispatcher) stopAfterDrain(sub *nats.Subscription) {
+	go func() {
+		for sub.IsValid() {
+			time.Sleep(drainPollInterval)
+		}
+		close(d.stop)
+	}()
+}
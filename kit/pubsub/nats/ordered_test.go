@@ -0,0 +1,190 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderedPerSubject(t *testing.T) {
+	s, err := NewSubscriber("group", &nats.Conn{}, &asyncJetStream{}, &nats.ConsumerInfo{}, WithOrderedPerSubject())
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, s.orderedPerSubject)
+
+	const subjects, count = 4, 50
+	var (
+		mu          sync.Mutex
+		handled     = map[string][]int{}
+		wg          sync.WaitGroup
+		concurrent  int32
+		maxParallel int32
+	)
+	wg.Add(subjects * count)
+	d := s.newOrderedDispatcher(context.Background(), "orders.*", func(ctx context.Context, msg *nats.Msg) error {
+		defer wg.Done()
+		n := atomic.AddInt32(&concurrent, 1)
+		defer atomic.AddInt32(&concurrent, -1)
+		for {
+			max := atomic.LoadInt32(&maxParallel)
+			if n <= max || atomic.CompareAndSwapInt32(&maxParallel, max, n) {
+				break
+			}
+		}
+
+		time.Sleep(time.Duration(rand.Intn(500)) * time.Microsecond)
+		seq, _ := strconv.Atoi(string(msg.Data))
+		mu.Lock()
+		handled[msg.Subject] = append(handled[msg.Subject], seq)
+		mu.Unlock()
+		return nil
+	})
+	defer close(d.stop)
+
+	// the messages are delivered one at a time, interleaving the subjects.
+	for i := 0; i < count; i++ {
+		for j := 0; j < subjects; j++ {
+			d.dispatch(context.Background(), &nats.Msg{Subject: fmt.Sprintf("orders.%d", j), Data: []byte(strconv.Itoa(i))}, s.closing)
+		}
+	}
+	wg.Wait()
+
+	for j := 0; j < subjects; j++ {
+		subject := fmt.Sprintf("orders.%d", j)
+		expected := make([]int, count)
+		for i := range expected {
+			expected[i] = i
+		}
+		assert.Equal(t, expected, handled[subject], subject)
+	}
+	// the subjects have been handled concurrently.
+	assert.Greater(t, atomic.LoadInt32(&maxParallel), int32(1))
+}
+
+func TestOrderedPerSubject_Stopped(t *testing.T) {
+	s, err := NewSubscriber("group", &nats.Conn{}, &asyncJetStream{}, &nats.ConsumerInfo{}, WithOrderedPerSubject())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	d := s.newOrderedDispatcher(context.Background(), "orders.*", func(ctx context.Context, msg *nats.Msg) error {
+		return nil
+	})
+	close(d.stop)
+
+	// the messages delivered once stopped are nacked, the delivery never blocks.
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 2*orderedQueueSize; i++ {
+			d.dispatch(context.Background(), &nats.Msg{Subject: "orders.1"}, s.closing)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "dispatch blocked on a stopped dispatcher")
+	}
+}
+
+func TestOrderedPerSubject_Cancelled(t *testing.T) {
+	s, err := NewSubscriber("group", &nats.Conn{}, &asyncJetStream{}, &nats.ConsumerInfo{}, WithOrderedPerSubject())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	goroutines := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	d := s.newOrderedDispatcher(ctx, "orders.*", func(ctx context.Context, msg *nats.Msg) error {
+		assert.Fail(t, "message handled once the subscription is cancelled")
+		return nil
+	})
+	defer close(d.stop)
+	cancel()
+
+	// the workers stop without waiting for the dispatcher to be stopped.
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines)
+
+	// the messages delivered once cancelled are nacked, the delivery never blocks.
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 2*orderedQueueSize; i++ {
+			d.dispatch(ctx, &nats.Msg{Subject: "orders.1"}, s.closing)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "dispatch blocked on a cancelled subscription")
+	}
+}
+
+func TestOrderedPerSubject_MaxConcurrency(t *testing.T) {
+	s, err := NewSubscriber("group", &nats.Conn{}, &asyncJetStream{}, &nats.ConsumerInfo{},
+		WithOrderedPerSubject(), WithMaxConcurrency(2))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	const subjects, count = 8, 10
+	var (
+		mu          sync.Mutex
+		handled     = map[string][]int{}
+		wg          sync.WaitGroup
+		concurrent  int32
+		maxParallel int32
+	)
+	wg.Add(subjects * count)
+	d := s.newOrderedDispatcher(context.Background(), "orders.*", func(ctx context.Context, msg *nats.Msg) error {
+		defer wg.Done()
+		n := atomic.AddInt32(&concurrent, 1)
+		defer atomic.AddInt32(&concurrent, -1)
+		for {
+			max := atomic.LoadInt32(&maxParallel)
+			if n <= max || atomic.CompareAndSwapInt32(&maxParallel, max, n) {
+				break
+			}
+		}
+
+		time.Sleep(time.Millisecond)
+		seq, _ := strconv.Atoi(string(msg.Data))
+		mu.Lock()
+		handled[msg.Subject] = append(handled[msg.Subject], seq)
+		mu.Unlock()
+		return nil
+	})
+	defer close(d.stop)
+
+	for i := 0; i < count; i++ {
+		for j := 0; j < subjects; j++ {
+			d.dispatch(context.Background(), &nats.Msg{Subject: fmt.Sprintf("orders.%d", j), Data: []byte(strconv.Itoa(i))}, s.closing)
+		}
+	}
+	wg.Wait()
+
+	// the messages of a subject are still handled in order, at most 2 at once across the subjects.
+	for j := 0; j < subjects; j++ {
+		subject := fmt.Sprintf("orders.%d", j)
+		expected := make([]int, count)
+		for i := range expected {
+			expected[i] = i
+		}
+		assert.Equal(t, expected, handled[subject], subject)
+	}
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxParallel), int32(2))
+}
@@ -3,6 +3,7 @@ package nats
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/anthonycorbacho/workspace/kit/errors"
@@ -15,6 +16,59 @@ import (
 
 var _ pubsub.Publisher = (*Publisher)(nil)
 
+// PublisherOption defines a Publisher option.
+type PublisherOption func(*Publisher)
+
+// WithMaxMessageSize defines the maximum size in bytes of a published message, its data and headers
+// (the attributes and the tracing headers, as encoded by NATS).
+// Messages exceeding it are rejected with pubsub.MessageTooLarge without being sent.
+//
+// The size must be positive, NewPublisher returns an error otherwise. A size above the max payload
+// announced by the NATS server is clamped to it: the server limit always applies.
+func WithMaxMessageSize(bytes int) PublisherOption {
+	return func(p *Publisher) {
+		if bytes <= 0 {
+			p.optionErr = fmt.Errorf("max message size of %d bytes is not positive", bytes)
+			return
+		}
+		p.maxMessageSize = bytes
+	}
+}
+
+// WithAsyncPublish enables the asynchronous publication of the messages:
+// Publish returns once the message is sent, without waiting for the JetStream acknowledgment.
+// At most maxOutstanding messages can wait for their acknowledgment, Publish blocks when the window
+// is full until an acknowledgment is received or ctx is done, bounding the memory used during broker slowness.
+// Failed publications are reported to the error handler, see WithPublishErrorHandler.
+func WithAsyncPublish(maxOutstanding int) PublisherOption {
+	return func(p *Publisher) {
+		if maxOutstanding > 0 {
+			p.window = make(chan struct{}, maxOutstanding)
+		}
+	}
+}
+
+// WithPublishErrorHandler defines a function called each time an asynchronous publication fails,
+// see WithAsyncPublish.
+func WithPublishErrorHandler(fn func(topic string, err error)) PublisherOption {
+	return func(p *Publisher) {
+		p.errorHandler = fn
+	}
+}
+
+// defaultPublishTimeout is the default maximum time waited for a JetStream acknowledgment.
+const defaultPublishTimeout = 5 * time.Second
+
+// WithPublishTimeout defines the maximum time waited for the JetStream acknowledgment of a message, 5 seconds by default.
+// The deadline and the cancellation of the context given to Publish still apply to the synchronous publications.
+func WithPublishTimeout(timeout time.Duration) PublisherOption {
+	return func(p *Publisher) {
+		if timeout > 0 {
+			p.publishTimeout = timeout
+		}
+	}
+}
+
 // Publisher publishes a message on a NATS JetStream Stream's Pub/Sub topic.
 //
 // Subjects (topics) are managed by the server automatically following presence/absence of subscriptions
@@ -24,12 +78,33 @@ var _ pubsub.Publisher = (*Publisher)(nil)
 type Publisher struct {
 	nc *nats.Conn
 	js nats.JetStreamContext
+	// maximum size of a message, 0 means only the server limit applies.
+	maxMessageSize int
+	// optionErr is the error of an invalid option, returned by NewPublisher.
+	optionErr      error
+	publishTimeout time.Duration
+	// closed rejects the publications once Close is called, publishing counts the ongoing Publish calls.
+	closed     bool
+	closedLock sync.RWMutex
+	publishing sync.WaitGroup
+	// window of the asynchronous publications waiting for their acknowledgment, nil when publishing synchronously.
+	window       chan struct{}
+	outstanding  sync.WaitGroup
+	errorHandler func(topic string, err error)
+	// buffer of the messages published while the connection is lost, nil when not buffering.
+	buffer         chan bufferedMsg
+	bufferLock     sync.Mutex
+	bufferDeadline time.Duration
+	buffered       int32
+	stop           chan struct{}
+	stopOnce       sync.Once
+	flushed        chan struct{}
 }
 
 // NewPublisher create a new Nats JetStream publisher.
 //
 // It required a call to Close in order to stop processing messages and close topic connections.
-func NewPublisher(nc *nats.Conn, js nats.JetStreamContext) (*Publisher, error) {
+func NewPublisher(nc *nats.Conn, js nats.JetStreamContext, opts ...PublisherOption) (*Publisher, error) {
 	if nc == nil {
 		return nil, errors.New("invalid nats connection")
 	}
@@ -37,17 +112,45 @@ func NewPublisher(nc *nats.Conn, js nats.JetStreamContext) (*Publisher, error) {
 		return nil, errors.New("invalid jet stream connection")
 	}
 
-	return &Publisher{
-		nc: nc,
-		js: js,
-	}, nil
+	p := &Publisher{
+		nc:             nc,
+		js:             js,
+		errorHandler:   func(string, error) {},
+		publishTimeout: defaultPublishTimeout,
+	}
+	for _, o := range opts {
+		o(p)
+	}
+	if p.optionErr != nil {
+		return nil, p.optionErr
+	}
+	if p.buffer != nil {
+		p.stop = make(chan struct{})
+		p.flushed = make(chan struct{})
+		go p.flushBuffer()
+	}
+	return p, nil
 }
 
 // Close notifies the Publisher to stop processing messages, send all the remaining messages and close the connection.
+// The ongoing publications are finished, the outstanding asynchronous publications are acknowledged (or time out)
+// and the buffered messages are published if the connection is up (dropped otherwise) before closing.
+// Publish returns pubsub.PublisherClosed once Close is called.
 func (p *Publisher) Close() error {
-	if p.nc.IsClosed() {
+	p.closedLock.Lock()
+	if p.closed || p.nc.IsClosed() {
+		p.closedLock.Unlock()
 		return pubsub.PublisherClosed
 	}
+	p.closed = true
+	p.closedLock.Unlock()
+
+	// no publication starts anymore, the ongoing ones can buffer or publish asynchronously.
+	p.publishing.Wait()
+	if p.buffer != nil {
+		p.stopBuffer()
+	}
+	p.outstanding.Wait()
 	return p.nc.Drain()
 }
 
@@ -58,38 +161,79 @@ func (p *Publisher) Close() error {
 // JetStream publish calls are acknowledged by the JetStream enabled servers
 // To receive messages published to a topic, you must create a subscription to that topic.
 //
+// The topic is added to ctx (see pubsub.GetTopic) for the duration of the publication.
+//
 // See https://docs.nats.io/nats-concepts/jetstream/streams to find out more about how NATS streams work.
 func (p *Publisher) Publish(ctx context.Context, topic string, msg pubsub.Message) error {
+	return p.PublishWithAttributes(ctx, topic, msg, nil)
+}
+
+// PublishWithAttributes publishes a message with attributes, sent as NATS headers, like Publish.
+// The subscribers get the attributes with pubsub.GetAttributes, the reserved keys are rejected (see pubsub.ValidateAttributes).
+func (p *Publisher) PublishWithAttributes(ctx context.Context, topic string, msg pubsub.Message, attrs map[string]string) error {
 	if len(topic) == 0 {
 		return fmt.Errorf("topic is nil")
 	}
+	if err := pubsub.ValidateAttributes(attrs); err != nil {
+		return err
+	}
 
+	// the topic is carried by the context of the span and the publish path (eg: logs).
+	ctx = pubsub.WithTopic(ctx, topic)
 	var span trace.Span
-	_, span = tracer.Start(ctx, fmt.Sprintf("Publish %s", topic))
+	ctx, span = tracer.Start(ctx, fmt.Sprintf("Publish %s", topic))
 	span.SetAttributes(attribute.String("topic", topic))
 	defer span.End()
 
 	// if the publisher is in closing state or has been closed
 	// we return an error and annotate the trace with the error.
-	if p.nc.IsClosed() {
+	if !p.begin() {
 		err := pubsub.PublisherClosed
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 
 		return err
 	}
+	defer p.publishing.Done()
 
 	// Prepare headers that will be passed to the pubsub
-	headers := make(map[string][]string)
+	headers := make(map[string][]string, len(attrs)+5)
+	for k, v := range attrs {
+		headers[k] = []string{v}
+	}
 	headers["subject"] = []string{topic}
 	tracingAttributes(span, headers)
+
+	// reject oversized messages before reaching the broker, the headers count in the NATS max payload.
+	if size, limit := len(msg)+headersSize(headers), p.maxSize(); limit > 0 && size > limit {
+		err := errors.Wrapf(pubsub.MessageTooLarge, "message of %d bytes exceeds the limit of %d bytes", size, limit)
+		span.SetAttributes(attribute.Int("message.size", size))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
 	natsMsg := &nats.Msg{
 		Subject: topic,
 		Header:  headers,
 		Data:    msg,
 	}
 
-	timeoutCtx, fn := context.WithTimeout(context.Background(), 5*time.Second)
+	if p.buffering() {
+		if err := p.bufferMsg(natsMsg); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+		return nil
+	}
+
+	if p.window != nil {
+		return p.publishAsync(ctx, span, natsMsg)
+	}
+
+	// the caller cancellation is honored.
+	timeoutCtx, fn := context.WithTimeout(ctx, p.publishTimeout)
 	defer fn()
 	_, err := p.js.PublishMsg(natsMsg, nats.Context(timeoutCtx))
 
@@ -102,3 +246,79 @@ func (p *Publisher) Publish(ctx context.Context, topic string, msg pubsub.Messag
 
 	return nil
 }
+
+// begin registers an ongoing publication, it returns false once the publisher is closed.
+// The publication is registered under the lock so Close waits for it.
+func (p *Publisher) begin() bool {
+	p.closedLock.RLock()
+	defer p.closedLock.RUnlock()
+	if p.closed || p.nc.IsClosed() {
+		return false
+	}
+	p.publishing.Add(1)
+	return true
+}
+
+// publishAsync publishes the message without waiting for its acknowledgment,
+// blocking while the window of outstanding publications is full.
+func (p *Publisher) publishAsync(ctx context.Context, span trace.Span, msg *nats.Msg) error {
+	select {
+	case p.window <- struct{}{}:
+	case <-ctx.Done():
+		err := errors.Wrap(ctx.Err(), "waiting for publish window")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	future, err := p.js.PublishMsgAsync(msg)
+	if err != nil {
+		<-p.window
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	// the publication is ongoing (see begin), Close waits for the acknowledgment once it returns.
+	p.outstanding.Add(1)
+	go func() {
+		defer p.outstanding.Done()
+		defer func() { <-p.window }()
+
+		timer := time.NewTimer(p.publishTimeout)
+		defer timer.Stop()
+		select {
+		case <-future.Ok():
+		case err := <-future.Err():
+			p.errorHandler(msg.Subject, err)
+		case <-timer.C:
+			p.errorHandler(msg.Subject, errors.Wrap(nats.ErrTimeout, "waiting for publish acknowledgment"))
+		}
+	}()
+	return nil
+}
+
+// headersSize returns the size of the headers once encoded in a NATS message, 0 without headers.
+func headersSize(headers nats.Header) int {
+	if len(headers) == 0 {
+		return 0
+	}
+	// NATS/1.0\r\n, a "key: value\r\n" line per value and a final \r\n.
+	size := len("NATS/1.0\r\n") + len("\r\n")
+	for k, values := range headers {
+		for _, v := range values {
+			size += len(k) + len(": ") + len(v) + len("\r\n")
+		}
+	}
+	return size
+}
+
+// maxSize returns the maximum size of a message, the smallest of the configured size and the server max payload.
+// 0 means no limit is known.
+func (p *Publisher) maxSize() int {
+	limit := p.maxMessageSize
+	if serverLimit := int(p.nc.MaxPayload()); serverLimit > 0 && (limit == 0 || serverLimit < limit) {
+		limit = serverLimit
+	}
+	return limit
+}
@@ -0,0 +1,254 @@
+package nats
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/pubsub"
+	nats "github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// asyncJetStream records the asynchronous publications, acknowledged on demand.
+type asyncJetStream struct {
+	nats.JetStreamContext
+	mu      sync.Mutex
+	futures []*ackFuture
+}
+
+func (js *asyncJetStream) PublishMsgAsync(m *nats.Msg, _ ...nats.PubOpt) (nats.PubAckFuture, error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	f := &ackFuture{msg: m, ok: make(chan *nats.PubAck, 1), err: make(chan error, 1)}
+	js.futures = append(js.futures, f)
+	return f, nil
+}
+
+// ack acknowledges the oldest not yet acknowledged publication.
+func (js *asyncJetStream) ack() bool {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	for _, f := range js.futures {
+		if !f.acked {
+			f.acked = true
+			f.ok <- &nats.PubAck{}
+			return true
+		}
+	}
+	return false
+}
+
+type ackFuture struct {
+	msg   *nats.Msg
+	ok    chan *nats.PubAck
+	err   chan error
+	acked bool
+}
+
+func (f *ackFuture) Ok() <-chan *nats.PubAck { return f.ok }
+func (f *ackFuture) Err() <-chan error       { return f.err }
+func (f *ackFuture) Msg() *nats.Msg          { return f.msg }
+
+func TestPublishAsync_Window(t *testing.T) {
+	js := &asyncJetStream{}
+	var failures []error
+	p, err := NewPublisher(&nats.Conn{}, js, WithAsyncPublish(3), WithPublishErrorHandler(func(_ string, err error) {
+		failures = append(failures, err)
+	}))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ctx := context.Background()
+	// the window is filled without blocking.
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, p.Publish(ctx, "burst", []byte("msg")))
+	}
+
+	// the caller blocks once the window is full.
+	published := make(chan error, 1)
+	go func() {
+		published <- p.Publish(ctx, "burst", []byte("msg"))
+	}()
+	select {
+	case <-published:
+		assert.Fail(t, "publish did not block on a full window")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// an acknowledgment frees a slot.
+	assert.True(t, js.ack())
+	select {
+	case err := <-published:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		assert.Fail(t, "publish still blocked after an acknowledgment")
+	}
+
+	// a full window is bounded by the context.
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, p.Publish(timeoutCtx, "burst", []byte("msg")), context.DeadlineExceeded)
+
+	// all the messages are eventually acknowledged.
+	for js.ack() {
+	}
+	p.outstanding.Wait()
+	assert.Len(t, js.futures, 4)
+	assert.Empty(t, failures)
+	assert.Empty(t, p.window)
+}
+
+func TestPublishWithAttributes(t *testing.T) {
+	js := &asyncJetStream{}
+	p, err := NewPublisher(&nats.Conn{}, js, WithAsyncPublish(1))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ctx := context.Background()
+	err = p.PublishWithAttributes(ctx, "orders.created", []byte("msg"), map[string]string{"subject": "other"})
+	assert.True(t, errors.Is(err, pubsub.ReservedAttribute))
+
+	// the attributes are sent as headers, next to the subject.
+	assert.NoError(t, p.PublishWithAttributes(ctx, "orders.created", []byte("msg"), map[string]string{"tenant": "acme"}))
+	if assert.Len(t, js.futures, 1) {
+		header := js.futures[0].msg.Header
+		assert.Equal(t, "acme", header.Get("tenant"))
+		assert.Equal(t, "orders.created", header.Get("subject"))
+	}
+	js.ack()
+	p.outstanding.Wait()
+}
+
+func TestPublishMaxMessageSize_Headers(t *testing.T) {
+	js := &asyncJetStream{}
+	p, err := NewPublisher(&nats.Conn{}, js, WithAsyncPublish(1), WithMaxMessageSize(64))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// the data is under the limit, not with the headers.
+	err = p.PublishWithAttributes(context.Background(), "orders.created", []byte("small"), map[string]string{
+		"key": strings.Repeat("v", 64),
+	})
+	assert.True(t, errors.Is(err, pubsub.MessageTooLarge))
+	assert.Empty(t, js.futures)
+}
+
+func TestHeadersSize(t *testing.T) {
+	assert.Equal(t, 0, headersSize(nil))
+
+	// the size of the headers as encoded by NATS.
+	headers := nats.Header{"subject": {"orders.created"}, "tenant": {"acme", "other"}}
+	var b bytes.Buffer
+	b.WriteString("NATS/1.0\r\n")
+	assert.NoError(t, http.Header(headers).Write(&b))
+	b.WriteString("\r\n")
+	assert.Equal(t, b.Len(), headersSize(headers))
+}
+
+func TestMaxMessageSizeOption(t *testing.T) {
+	p, err := NewPublisher(&nats.Conn{}, &asyncJetStream{}, WithMaxMessageSize(1024))
+	assert.NoError(t, err)
+	assert.Equal(t, 1024, p.maxMessageSize)
+
+	// the size must be positive.
+	for _, size := range []int{0, -1} {
+		_, err = NewPublisher(&nats.Conn{}, &asyncJetStream{}, WithMaxMessageSize(size))
+		assert.Error(t, err, size)
+	}
+}
+
+func TestPublishTimeoutOption(t *testing.T) {
+	p, err := NewPublisher(&nats.Conn{}, &asyncJetStream{})
+	assert.NoError(t, err)
+	assert.Equal(t, defaultPublishTimeout, p.publishTimeout)
+
+	p, err = NewPublisher(&nats.Conn{}, &asyncJetStream{}, WithPublishTimeout(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, p.publishTimeout)
+
+	// invalid timeouts are ignored.
+	p, err = NewPublisher(&nats.Conn{}, &asyncJetStream{}, WithPublishTimeout(0))
+	assert.NoError(t, err)
+	assert.Equal(t, defaultPublishTimeout, p.publishTimeout)
+}
+
+func TestPublishTopicContext(t *testing.T) {
+	recorder := &topicRecorder{}
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	js := &asyncJetStream{}
+	p, err := NewPublisher(&nats.Conn{}, js, WithAsyncPublish(1))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.NoError(t, p.Publish(context.Background(), "orders.created", []byte("msg")))
+	assert.Equal(t, []string{"orders.created"}, recorder.recorded())
+	js.ack()
+}
+
+func TestPublisherClose_Concurrent(t *testing.T) {
+	// a connection to a server that never answers, it can be drained (closed) unlike a zero connection.
+	nc, err := nats.Connect("nats://127.0.0.1:1", nats.RetryOnFailedConnect(true), nats.ReconnectWait(time.Hour))
+	if !assert.NoError(t, err) {
+		return
+	}
+	js := &asyncJetStream{}
+	p, err := NewPublisher(nc, js, WithAsyncPublish(1000))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// acknowledge the publications as they come.
+	acking := make(chan struct{})
+	defer close(acking)
+	go func() {
+		for {
+			select {
+			case <-acking:
+				return
+			default:
+				js.ack()
+			}
+		}
+	}()
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				err := p.Publish(ctx, "closing", []byte("msg"))
+				if err != nil {
+					assert.ErrorIs(t, err, pubsub.PublisherClosed)
+					return
+				}
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond)
+	_ = p.Close()
+	wg.Wait()
+
+	// the accepted publications are all acknowledged, the next ones are rejected.
+	js.mu.Lock()
+	for _, f := range js.futures {
+		assert.True(t, f.acked)
+	}
+	js.mu.Unlock()
+	assert.ErrorIs(t, p.Publish(ctx, "closing", []byte("msg")), pubsub.PublisherClosed)
+	assert.ErrorIs(t, p.Close(), pubsub.PublisherClosed)
+}
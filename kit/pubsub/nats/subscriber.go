@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/anthonycorbacho/workspace/kit/errors"
 	"github.com/anthonycorbacho/workspace/kit/pubsub"
@@ -15,6 +16,36 @@ import (
 
 var _ pubsub.Subscriber = (*Subscriber)(nil)
 
+// SubscriberOption defines a Subscriber option.
+type SubscriberOption func(*Subscriber)
+
+// WithRetryPolicy settles the messages of the handlers registered with Subscribe based on their outcome:
+// acked on success, nacked for redelivery on a retryable error (see errors.IsRetryable) and acked (dropped) on any other error.
+// By default, Subscribe always acks the messages.
+func WithRetryPolicy() SubscriberOption {
+	return func(s *Subscriber) {
+		s.retryPolicy = true
+	}
+}
+
+// WithHandlerTimeout bounds the time given to the handler to process a message,
+// the message is nacked for redelivery if the handler does not finish in time.
+// See pubsub.TimeoutHandler.
+func WithHandlerTimeout(d time.Duration) SubscriberOption {
+	return func(s *Subscriber) {
+		s.handlerTimeout = d
+	}
+}
+
+// WithSlowConsumer reports (warning log and metric) the subscriptions with more than highWaterMark
+// messages handled concurrently, or with a handler taking more than latency to process a message.
+// See pubsub.SlowConsumerMonitor.
+func WithSlowConsumer(highWaterMark int, latency time.Duration) SubscriberOption {
+	return func(s *Subscriber) {
+		s.slowConsumer = pubsub.NewSlowConsumerMonitor(highWaterMark, latency)
+	}
+}
+
 // Subscriber is our wrapper around NATS subscription.
 // In current implementation, one Subscriber corresponds to one NATS subscription,
 // as it's ok to have many subscriptions per client(https://docs.nats.io/using-nats/developer/anatomy#connecting-and-disconnecting)
@@ -22,19 +53,29 @@ var _ pubsub.Subscriber = (*Subscriber)(nil)
 // The following features are available our of the box:
 // - automatic reconnection: https://docs.nats.io/using-nats/developer/connecting/reconnect
 type Subscriber struct {
-	closing    chan struct{}
-	closed     bool
-	closedLock sync.Mutex
-	queueGroup string
-	consumer   *nats.ConsumerInfo
-	nc         *nats.Conn
-	js         nats.JetStreamContext
+	closing        chan struct{}
+	closed         bool
+	closedLock     sync.Mutex
+	subs           map[string][]*nats.Subscription
+	subsLock       sync.Mutex
+	queueGroup     string
+	consumer       *nats.ConsumerInfo
+	nc             *nats.Conn
+	js             nats.JetStreamContext
+	slowConsumer   *pubsub.SlowConsumerMonitor
+	handlerTimeout time.Duration
+	retryPolicy    bool
+	// orderedPerSubject dispatches the messages to workers by subject, see WithOrderedPerSubject.
+	orderedPerSubject bool
+	dispatchers       map[*nats.Subscription]*orderedDispatcher
+	// slots bounds the messages handled at once, see WithMaxConcurrency.
+	slots chan struct{}
 }
 
 // NewSubscriber creates a new Nats Subscriber.
 //
 // it required a call to Close in order to stop processing messages and close subscriber connections.
-func NewSubscriber(queueGroup string, natsClient *nats.Conn, jetStreamCtx nats.JetStreamContext, consumer *nats.ConsumerInfo) (*Subscriber, error) {
+func NewSubscriber(queueGroup string, natsClient *nats.Conn, jetStreamCtx nats.JetStreamContext, consumer *nats.ConsumerInfo, opts ...SubscriberOption) (*Subscriber, error) {
 	if len(queueGroup) == 0 {
 		return nil, errors.New("invalid queueGroup")
 	}
@@ -48,15 +89,21 @@ func NewSubscriber(queueGroup string, natsClient *nats.Conn, jetStreamCtx nats.J
 		return nil, errors.New("invalid nats consumer")
 	}
 
-	return &Subscriber{
-		closing:    make(chan struct{}, 1),
-		closed:     false,
-		closedLock: sync.Mutex{},
-		queueGroup: queueGroup,
-		nc:         natsClient,
-		js:         jetStreamCtx,
-		consumer:   consumer,
-	}, nil
+	s := &Subscriber{
+		closing:     make(chan struct{}, 1),
+		closed:      false,
+		closedLock:  sync.Mutex{},
+		subs:        map[string][]*nats.Subscription{},
+		dispatchers: map[*nats.Subscription]*orderedDispatcher{},
+		queueGroup:  queueGroup,
+		nc:          natsClient,
+		js:          jetStreamCtx,
+		consumer:    consumer,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s, nil
 }
 
 // Close notifies the Subscriber to stop processing messages on all subscriptions, and terminate the connection.
@@ -86,6 +133,10 @@ func (s *Subscriber) Close() error {
 // Depending on the Consumer `DeliverPolicy`, `all`, `last`, `new`, `by_start_time`, `by_start_sequence`
 // persisted messages can be received
 func (s *Subscriber) Subscribe(ctx context.Context, subscription string /* subject */, handler pubsub.Handler) error {
+	if s.retryPolicy {
+		return s.SubscribeWithAck(ctx, subscription, pubsub.RetryHandler(handler))
+	}
+
 	h := func(ctx context.Context, msg pubsub.Message, ack func(), nack func()) error {
 		// default behavior is to always ack.
 		ack()
@@ -95,7 +146,45 @@ func (s *Subscriber) Subscribe(ctx context.Context, subscription string /* subje
 	return s.SubscribeWithAck(ctx, subscription, h)
 }
 
+// SubscribeWithAck consumes NATS Pub/Sub, the handler is responsible for acking (or nacking) the messages.
+//
+// Like Subscribe, the messages received once the subscriber is closing or ctx is done are nacked for redelivery,
+// and the handler context carries the trace and the subject of the message (see pubsub.GetTopic).
 func (s *Subscriber) SubscribeWithAck(ctx context.Context, subscription string /* subject */, handler pubsub.HandlerWithAck) error {
+	if s.handlerTimeout > 0 {
+		handler = pubsub.TimeoutHandler(handler, s.handlerTimeout)
+	}
+
+	h := func(ctx context.Context, msg *nats.Msg) error {
+		ack := func() {
+			msg.Ack()
+		}
+		nack := func() {
+			msg.Nak()
+		}
+		return handler(ctx, msg.Data, ack, nack)
+	}
+
+	return s.SubscribeRaw(ctx, subscription, h)
+}
+
+// SubscribeChan delivers the messages of the subscription on the returned channel, like SubscribeWithAck:
+// the receiver is responsible for acking (or nacking) them, the next message is delivered once the previous one is received.
+// The channel is closed once ctx is done or the subscriber is closing, see pubsub.SubscribeChan.
+func (s *Subscriber) SubscribeChan(ctx context.Context, subscription string) (<-chan pubsub.ReceivedMessage, error) {
+	return pubsub.SubscribeChan(ctx, subscription, s.closing, s.SubscribeWithAck)
+}
+
+// RawHandler is the handler receiving the NATS message as is.
+type RawHandler func(ctx context.Context, msg *nats.Msg) error
+
+// SubscribeRaw consumes NATS Pub/Sub, delivering the provider message as is to the handler,
+// which is responsible for acking (or nacking) it.
+//
+// It is meant for advanced use cases needing NATS specific features (eg: headers, jetstream metadata, in progress acks);
+// the handler is coupled to the provider, prefer Subscribe or SubscribeWithAck otherwise.
+// The handler timeout does not apply to raw handlers.
+func (s *Subscriber) SubscribeRaw(ctx context.Context, subscription string /* subject */, handler RawHandler) error {
 	if s.nc.IsClosed() {
 		return fmt.Errorf("subscriber is closed")
 	}
@@ -104,24 +193,74 @@ func (s *Subscriber) SubscribeWithAck(ctx context.Context, subscription string /
 	}
 
 	subHandler := func(msg *nats.Msg) {
-		s.receive(ctx, msg, handler)
+		s.receive(ctx, subscription, msg, handler)
+	}
+	if s.slots != nil {
+		subHandler = func(msg *nats.Msg) {
+			s.receiveConcurrently(ctx, subscription, msg, handler)
+		}
+	}
+	// the ordered workers take precedence, they handle each message within a slot, see WithMaxConcurrency.
+	var dispatcher *orderedDispatcher
+	if s.orderedPerSubject {
+		dispatcher = s.newOrderedDispatcher(ctx, subscription, handler)
+		subHandler = func(msg *nats.Msg) {
+			dispatcher.dispatch(ctx, msg, s.closing)
+		}
 	}
 
-	_, err := s.js.QueueSubscribe(
+	sub, err := s.js.QueueSubscribe(
 		subscription, /* subject */
 		s.queueGroup,
 		subHandler,
 		nats.Bind(s.consumer.Stream, s.consumer.Name),
 		nats.ManualAck())
 	if err != nil {
-
+		if dispatcher != nil {
+			close(dispatcher.stop)
+		}
 		return fmt.Errorf("subscription init failed: %v", err)
 	}
 
+	s.subsLock.Lock()
+	s.subs[subscription] = append(s.subs[subscription], sub)
+	if dispatcher != nil {
+		s.dispatchers[sub] = dispatcher
+	}
+	s.subsLock.Unlock()
+
 	return nil
 }
 
-func (s *Subscriber) receive(ctx context.Context, msg *nats.Msg, handler pubsub.HandlerWithAck) {
+// Unsubscribe drains the subscriptions to the given subject, pending messages are processed
+// before unsubscribing. The other subscriptions and the connection are kept alive.
+func (s *Subscriber) Unsubscribe(subscription string /* subject */) error {
+	s.subsLock.Lock()
+	subs, ok := s.subs[subscription]
+	delete(s.subs, subscription)
+	dispatchers := make([]*orderedDispatcher, len(subs))
+	for i, sub := range subs {
+		dispatchers[i] = s.dispatchers[sub]
+		delete(s.dispatchers, sub)
+	}
+	s.subsLock.Unlock()
+
+	if !ok {
+		return pubsub.SubscriptionNotFound
+	}
+
+	for i, sub := range subs {
+		if err := sub.Drain(); err != nil {
+			return errors.Wrapf(err, "unsubscribe '%s'", subscription)
+		}
+		if dispatchers[i] != nil {
+			dispatchers[i].stopAfterDrain(sub)
+		}
+	}
+	return nil
+}
+
+func (s *Subscriber) receive(ctx context.Context, subscription string, msg *nats.Msg, handler RawHandler) {
 
 	select {
 	case <-s.closing:
@@ -141,8 +280,13 @@ func (s *Subscriber) receive(ctx context.Context, msg *nats.Msg, handler pubsub.
 	}
 	ctx = contextFromTracingAttributes(ctx, firstHeaders)
 
-	// Add to the context the topic (subject).
+	// Add to the context the topic (subject) and the attributes.
 	ctx = pubsub.WithTopic(ctx, msg.Subject)
+	ctx = pubsub.WithAttributes(ctx, firstHeaders)
+	// only JetStream messages have metadata.
+	if meta, err := msg.Metadata(); err == nil {
+		ctx = pubsub.WithDeliveryAttempt(ctx, int(meta.NumDelivered))
+	}
 
 	// annotate the span
 	var span trace.Span
@@ -150,16 +294,12 @@ func (s *Subscriber) receive(ctx context.Context, msg *nats.Msg, handler pubsub.
 	span.SetAttributes(attribute.String("topic", msg.Subject))
 	defer span.End()
 
-	ack := func() {
-		msg.Ack()
-	}
-	nack := func() {
-		msg.Nak()
-	}
+	// track the message to detect when the subscription handler is too slow.
+	defer s.slowConsumer.Track(ctx, subscription)()
 
 	// Process the message
 	// in case of error, we record and label the error in the span.
-	err := handler(ctx, msg.Data, ack, nack)
+	err := handler(ctx, msg)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
@@ -5,12 +5,24 @@ import "context"
 // Publisher publishes a message to the given topic.
 type Publisher interface {
 	Publish(ctx context.Context, topic string, msg Message) error
+	// PublishWithAttributes publishes a message with attributes (eg: routing keys, content-type),
+	// available to the subscribers with GetAttributes.
+	// ReservedAttribute is returned if one of the keys is reserved, see ValidateAttributes.
+	PublishWithAttributes(ctx context.Context, topic string, msg Message, attrs map[string]string) error
 }
 
 // Subscriber subscribe to a topic subscription and handle the incoming event published to the topic.
 type Subscriber interface {
 	Subscribe(ctx context.Context, subscription string, handler Handler) error
 	SubscribeWithAck(ctx context.Context, subscription string, handler HandlerWithAck) error
+	// SubscribeChan delivers the messages of the subscription on the returned channel instead of calling a handler,
+	// the receiver is responsible for acking (or nacking) them. The channel is closed once ctx is done
+	// or the subscriber is closing.
+	SubscribeChan(ctx context.Context, subscription string) (<-chan ReceivedMessage, error)
+	// Unsubscribe stops processing messages on the given subscription, keeping the other subscriptions alive.
+	Unsubscribe(subscription string) error
+	// Close stops processing messages on all subscriptions, waiting for in-flight messages to be handled.
+	Close() error
 }
 
 // Message is the message that is going to transit to the event pubsub.
@@ -46,3 +58,22 @@ func GetTopic(ctx context.Context) string {
 	}
 	return subject
 }
+
+// Context type for delivery attempt
+type deliveryAttemptCtxKeyType string
+
+const deliveryAttemptCtxKey deliveryAttemptCtxKeyType = "delivery-attempt"
+
+// WithDeliveryAttempt inject to the given context the delivery attempt of a received message,
+// starting at 1 for the first delivery.
+func WithDeliveryAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, deliveryAttemptCtxKey, attempt)
+}
+
+// DeliveryAttempt get the delivery attempt of the received message from the context,
+// eg: to drop a poison message after N attempts. It returns false if the attempt is unknown
+// (eg: a Google Cloud Pub/Sub subscription without dead letter policy).
+func DeliveryAttempt(ctx context.Context) (int, bool) {
+	attempt, ok := ctx.Value(deliveryAttemptCtxKey).(int)
+	return attempt, ok
+}
@@ -13,3 +13,12 @@ func TestCtx(t *testing.T) {
 
 	assert.Equal(t, "a.topic", topic)
 }
+
+func TestDeliveryAttemptCtx(t *testing.T) {
+	_, ok := DeliveryAttempt(context.Background())
+	assert.False(t, ok)
+
+	attempt, ok := DeliveryAttempt(WithDeliveryAttempt(context.Background(), 3))
+	assert.True(t, ok)
+	assert.Equal(t, 3, attempt)
+}
@@ -0,0 +1,24 @@
+package pubsub
+
+import (
+	"context"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+)
+
+// RetryHandler returns a HandlerWithAck settling the message based on the handler outcome,
+// instead of always acking it:
+//   - the message is acked when the handler succeeds,
+//   - the message is nacked for redelivery when the handler returns a retryable error (see errors.IsRetryable),
+//   - the message is acked (dropped, not redelivered) when the handler returns any other error.
+func RetryHandler(handler Handler) HandlerWithAck {
+	return func(ctx context.Context, msg Message, ack func(), nack func()) error {
+		err := handler(ctx, msg)
+		if errors.IsRetryable(err) {
+			nack()
+			return err
+		}
+		ack()
+		return err
+	}
+}
@@ -0,0 +1,36 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryHandler(t *testing.T) {
+	var cases = []struct {
+		name  string
+		err   error
+		acked bool
+	}{
+		{name: "success", err: nil, acked: true},
+		{name: "retryable error", err: errors.Retryable(errors.New("unavailable")), acked: false},
+		{name: "wrapped retryable error", err: errors.Wrap(errors.Retryable(errors.New("unavailable")), "handle"), acked: false},
+		{name: "non retryable error", err: errors.New("invalid"), acked: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			acked, nacked := false, false
+			h := RetryHandler(func(ctx context.Context, msg Message) error {
+				return c.err
+			})
+
+			err := h(context.Background(), Message("test"), func() { acked = true }, func() { nacked = true })
+			assert.Equal(t, c.err, err)
+			assert.Equal(t, c.acked, acked)
+			assert.Equal(t, !c.acked, nacked)
+		})
+	}
+}
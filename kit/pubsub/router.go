@@ -0,0 +1,80 @@
+package pubsub
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// Router dispatches the messages of a subscription to handlers registered by topic pattern,
+// eg: a single subscription to `orders.>` handled per concrete subject.
+//
+// Patterns are NATS-style dot separated tokens, `*` matches exactly one token
+// and `>`, only allowed as the last token, matches one or more tokens.
+// The concrete topic is read from the message context (see GetTopic),
+// the messages without topic go to the fallback, and the first registered pattern matching it handles the message.
+type Router struct {
+	routes   []route
+	fallback Handler
+	lock     sync.RWMutex
+}
+
+type route struct {
+	tokens  []string
+	handler Handler
+}
+
+// NewRouter creates a new Router, fallback handles the messages matching no pattern.
+// If fallback is nil, those messages are rejected with NoRoute.
+func NewRouter(fallback Handler) *Router {
+	return &Router{fallback: fallback}
+}
+
+// Handle registers the handler of the topics matching pattern.
+func (r *Router) Handle(pattern string, handler Handler) error {
+	tokens := strings.Split(pattern, ".")
+	for i, token := range tokens {
+		if token == "" || (token == ">" && i != len(tokens)-1) {
+			return InvalidPattern
+		}
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.routes = append(r.routes, route{tokens: tokens, handler: handler})
+	return nil
+}
+
+// Dispatch handles the message with the handler matching its topic, it is the Handler to subscribe with.
+func (r *Router) Dispatch(ctx context.Context, msg Message) error {
+	topic := strings.Split(GetTopic(ctx), ".")
+
+	r.lock.RLock()
+	handler := r.fallback
+	for _, route := range r.routes {
+		// a message without topic only goes to the fallback.
+		if topic[0] != "" && match(route.tokens, topic) {
+			handler = route.handler
+			break
+		}
+	}
+	r.lock.RUnlock()
+
+	if handler == nil {
+		return NoRoute
+	}
+	return handler(ctx, msg)
+}
+
+// match reports whether the topic tokens match the pattern tokens.
+func match(pattern []string, topic []string) bool {
+	for i, token := range pattern {
+		if token == ">" {
+			return len(topic) > i
+		}
+		if i >= len(topic) || (token != "*" && token != topic[i]) {
+			return false
+		}
+	}
+	return len(pattern) == len(topic)
+}
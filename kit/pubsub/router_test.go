@@ -0,0 +1,66 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter(t *testing.T) {
+	var handled string
+	handler := func(name string) Handler {
+		return func(ctx context.Context, msg Message) error {
+			handled = name
+			return nil
+		}
+	}
+
+	r := NewRouter(handler("default"))
+	assert.NoError(t, r.Handle("test.orders.created", handler("created")))
+	assert.NoError(t, r.Handle("test.orders.*", handler("orders")))
+	assert.NoError(t, r.Handle("test.*.deleted", handler("deleted")))
+	assert.NoError(t, r.Handle("test.users.>", handler("users")))
+
+	tests := []struct {
+		topic    string
+		expected string
+	}{
+		{topic: "test.orders.created", expected: "created"},
+		{topic: "test.orders.updated", expected: "orders"},
+		{topic: "test.orders.deleted", expected: "orders"},
+		{topic: "test.items.deleted", expected: "deleted"},
+		{topic: "test.users.created", expected: "users"},
+		{topic: "test.users.1.address.updated", expected: "users"},
+		{topic: "test.users", expected: "default"},
+		{topic: "test.orders", expected: "default"},
+		{topic: "test.orders.created.v2", expected: "default"},
+		{topic: "other.orders.created", expected: "default"},
+		{topic: "", expected: "default"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.topic, func(t *testing.T) {
+			handled = ""
+			assert.NoError(t, r.Dispatch(WithTopic(context.Background(), tc.topic), Message("msg")))
+			assert.Equal(t, tc.expected, handled)
+		})
+	}
+}
+
+func TestRouter_NoRoute(t *testing.T) {
+	r := NewRouter(nil)
+	assert.NoError(t, r.Handle("test.>", func(ctx context.Context, msg Message) error {
+		return nil
+	}))
+
+	assert.NoError(t, r.Dispatch(WithTopic(context.Background(), "test.orders"), Message("msg")))
+	assert.Equal(t, NoRoute, r.Dispatch(WithTopic(context.Background(), "orders"), Message("msg")))
+	assert.Equal(t, NoRoute, r.Dispatch(context.Background(), Message("msg")))
+}
+
+func TestRouter_InvalidPattern(t *testing.T) {
+	r := NewRouter(nil)
+	for _, pattern := range []string{"", "test.", "test..orders", "test.>.orders", ">.orders"} {
+		assert.Equal(t, InvalidPattern, r.Handle(pattern, nil), pattern)
+	}
+}
@@ -0,0 +1,88 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// SlowConsumerMonitor detects the subscriptions handling messages slower than they receive them.
+//
+// A subscription is reported as slow, with a warning log and the pubsub.slow_consumer metric,
+// when more than the high-water mark messages are handled concurrently
+// or when a message takes more than the latency threshold to be handled.
+// A nil SlowConsumerMonitor does not report anything.
+type SlowConsumerMonitor struct {
+	highWaterMark int
+	latency       time.Duration
+	logger        *log.Logger
+	counter       metric.Int64Counter
+
+	inflight     map[string]int
+	inflightLock sync.Mutex
+}
+
+// NewSlowConsumerMonitor creates a new SlowConsumerMonitor.
+// A highWaterMark or a latency lower or equal to 0 disables the corresponding detection.
+func NewSlowConsumerMonitor(highWaterMark int, latency time.Duration) *SlowConsumerMonitor {
+	counter, err := otel.Meter("kit/pubsub").Int64Counter("pubsub.slow_consumer",
+		metric.WithDescription("Number of times a subscription has been detected as slow"),
+	)
+	if err != nil {
+		counter = nil
+	}
+
+	return &SlowConsumerMonitor{
+		highWaterMark: highWaterMark,
+		latency:       latency,
+		logger:        log.L(),
+		counter:       counter,
+		inflight:      map[string]int{},
+	}
+}
+
+// Track tracks a message of the subscription from the moment it is received,
+// the returned function must be called once the message has been handled.
+func (m *SlowConsumerMonitor) Track(ctx context.Context, subscription string) func() {
+	if m == nil {
+		return func() {}
+	}
+
+	m.inflightLock.Lock()
+	m.inflight[subscription]++
+	inflight := m.inflight[subscription]
+	m.inflightLock.Unlock()
+
+	// only report when crossing the high-water mark, not for every message above it.
+	if m.highWaterMark > 0 && inflight == m.highWaterMark+1 {
+		m.report(ctx, subscription, "inflight", log.Int("inflight", inflight), log.Int("high_water_mark", m.highWaterMark))
+	}
+
+	start := time.Now()
+	return func() {
+		m.inflightLock.Lock()
+		m.inflight[subscription]--
+		m.inflightLock.Unlock()
+
+		if elapsed := time.Since(start); m.latency > 0 && elapsed > m.latency {
+			m.report(ctx, subscription, "latency", log.Duration("latency", elapsed), log.Duration("threshold", m.latency))
+		}
+	}
+}
+
+func (m *SlowConsumerMonitor) report(ctx context.Context, subscription string, reason string, fields ...log.Field) {
+	fields = append(fields, log.String("subscription", subscription), log.String("reason", reason))
+	m.logger.Warn(ctx, "slow consumer", fields...)
+
+	if m.counter != nil {
+		m.counter.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("subscription", subscription),
+			attribute.String("reason", reason),
+		))
+	}
+}
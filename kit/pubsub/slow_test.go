@@ -0,0 +1,85 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/log"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestSlowConsumerMonitor(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	// logger writing to a file instead of stderr
+	output := filepath.Join(t.TempDir(), "log")
+	logger, err := log.New(log.WithOutputPaths(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	m := NewSlowConsumerMonitor(2, 50*time.Millisecond)
+	m.logger = logger
+
+	// artificially slow handler, with 3 messages handled concurrently.
+	slowHandler := func() {
+		defer m.Track(ctx, "a.subscription")()
+		time.Sleep(100 * time.Millisecond)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slowHandler()
+		}()
+	}
+	wg.Wait()
+
+	// fast handler are not reported
+	m.Track(ctx, "b.subscription")()
+
+	logger.Close()
+	b, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 4, bytes.Count(b, []byte(`"slow consumer"`)))
+	assert.Equal(t, 1, bytes.Count(b, []byte(`"reason":"inflight"`)))
+	assert.Equal(t, 3, bytes.Count(b, []byte(`"reason":"latency"`)))
+	assert.NotContains(t, string(b), "b.subscription")
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, metric := range sm.Metrics {
+			if metric.Name != "pubsub.slow_consumer" {
+				continue
+			}
+			for _, dp := range metric.Data.(metricdata.Sum[int64]).DataPoints {
+				count += dp.Value
+			}
+		}
+	}
+	assert.Equal(t, int64(4), count)
+}
+
+func TestNilSlowConsumerMonitor(t *testing.T) {
+	var m *SlowConsumerMonitor
+	assert.NotPanics(t, func() {
+		m.Track(context.Background(), "a.subscription")()
+	})
+}
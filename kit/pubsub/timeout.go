@@ -0,0 +1,61 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TimeoutHandler returns a HandlerWithAck running handler with a context bounded by timeout.
+//
+// If the handler does not finish in time, the message is nacked for redelivery,
+// the timeout is recorded on the span and the pubsub.handler.timeouts metric, and HandlerTimeout is returned
+// (or the handler error if it returned on the context deadline).
+// Acks and nacks issued by the handler after the timeout are ignored.
+// The handler should honor the context cancellation, it keeps running in the background otherwise.
+func TimeoutHandler(handler HandlerWithAck, timeout time.Duration) HandlerWithAck {
+	counter, err := otel.Meter("kit/pubsub").Int64Counter("pubsub.handler.timeouts",
+		metric.WithDescription("Number of messages nacked because the handler did not finish in time"),
+	)
+	if err != nil {
+		counter = nil
+	}
+
+	return func(ctx context.Context, msg Message, ack func(), nack func()) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		// only the first of the handler and the timeout settles the message.
+		var once sync.Once
+		settledAck := func() { once.Do(ack) }
+		settledNack := func() { once.Do(nack) }
+
+		done := make(chan error, 1)
+		go func() {
+			done <- handler(ctx, msg, settledAck, settledNack)
+		}()
+
+		var err error
+		select {
+		case err = <-done:
+			// the handler honored the context and gave up, it still counts as a timeout.
+			if ctx.Err() != context.DeadlineExceeded {
+				return err
+			}
+		case <-ctx.Done():
+			err = HandlerTimeout
+		}
+
+		settledNack()
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("handler.timeout", true))
+		if counter != nil {
+			counter.Add(ctx, 1, metric.WithAttributes(attribute.String("topic", GetTopic(ctx))))
+		}
+		return err
+	}
+}
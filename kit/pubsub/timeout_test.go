@@ -0,0 +1,55 @@
+package pubsub
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutHandler(t *testing.T) {
+	var acks, nacks int32
+	ack := func() { atomic.AddInt32(&acks, 1) }
+	nack := func() { atomic.AddInt32(&nacks, 1) }
+
+	// handler finishing in time
+	h := TimeoutHandler(func(ctx context.Context, msg Message, ack func(), nack func()) error {
+		ack()
+		return nil
+	}, time.Second)
+	assert.NoError(t, h(context.Background(), Message("fast"), ack, nack))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&acks))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&nacks))
+
+	// handler exceeding the timeout, ignoring the context.
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	h = TimeoutHandler(func(ctx context.Context, msg Message, ack func(), nack func()) error {
+		defer close(finished)
+		<-release
+		ack()
+		return nil
+	}, 50*time.Millisecond)
+	err := h(context.Background(), Message("slow"), ack, nack)
+	assert.Equal(t, HandlerTimeout, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&nacks))
+
+	// late ack is ignored
+	close(release)
+	<-finished
+	assert.Equal(t, int32(1), atomic.LoadInt32(&acks))
+}
+
+func TestTimeoutHandlerContext(t *testing.T) {
+	h := TimeoutHandler(func(ctx context.Context, msg Message, ack func(), nack func()) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, 50*time.Millisecond)
+
+	nacked := false
+	err := h(context.Background(), Message("slow"), func() {}, func() { nacked = true })
+	assert.Error(t, err)
+	assert.True(t, nacked)
+}
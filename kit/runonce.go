@@ -0,0 +1,73 @@
+package kit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anthonycorbacho/workspace/kit/dlock"
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// RunOnce runs the one-time startup task fn (eg: seed data, cache warm-up) identified by key
+// on a single replica of the service.
+//
+// It acquires the lock of the task, runs fn unless its "done" marker is already set,
+// sets the marker once fn succeeded and releases the lock. The replicas waiting for the lock
+// find the marker set and skip the task. If fn fails, the marker is not set and the task
+// runs again on the next call.
+//
+// The distributed lock must persist the markers, ie: implement dlock.Marker (eg: kit/dlock/sql).
+//
+//	err := kit.RunOnce(ctx, dl, "seed-v1", func(ctx context.Context) error {
+//		return seed(ctx, db)
+//	})
+func RunOnce(ctx context.Context, dl dlock.DistributedLock, key string, fn func(ctx context.Context) error) error {
+	ctx, span := otel.Tracer("kit").Start(ctx, "kit.RunOnce")
+	span.SetAttributes(attribute.String("key", key))
+	defer span.End()
+
+	if len(key) == 0 {
+		return errors.New("task key is required")
+	}
+	marker, ok := dl.(dlock.Marker)
+	if !ok {
+		return errors.New("distributed lock does not persist markers")
+	}
+
+	lock, err := dl.Lock(ctx, fmt.Sprintf("%s_run_once", key))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return errors.Wrapf(err, "acquire lock of task '%s'", key)
+	}
+	defer func() {
+		_ = lock.Unlock(context.Background()) //nolint
+	}()
+
+	marked, err := marker.Marked(ctx, key)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return errors.Wrapf(err, "check task '%s'", key)
+	}
+	if marked {
+		span.SetAttributes(attribute.Bool("skipped", true))
+		return nil
+	}
+
+	if err := fn(ctx); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return errors.Wrapf(err, "run task '%s'", key)
+	}
+
+	if err := marker.Mark(ctx, key); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return errors.Wrapf(err, "mark task '%s' done", key)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package kit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/dlock"
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryLock is an in-process dlock.DistributedLock and dlock.Marker.
+type memoryLock struct {
+	mu      sync.Mutex
+	locks   map[string]chan struct{}
+	markers map[string]bool
+}
+
+func newMemoryLock() *memoryLock {
+	return &memoryLock{locks: map[string]chan struct{}{}, markers: map[string]bool{}}
+}
+
+func (m *memoryLock) Lock(ctx context.Context, key string) (dlock.Lock, error) {
+	m.mu.Lock()
+	l, ok := m.locks[key]
+	if !ok {
+		l = make(chan struct{}, 1)
+		m.locks[key] = l
+	}
+	m.mu.Unlock()
+
+	select {
+	case l <- struct{}{}:
+		return memoryUnlock(func() { <-l }), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (m *memoryLock) Marked(_ context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.markers[key], nil
+}
+
+func (m *memoryLock) Mark(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.markers[key] = true
+	return nil
+}
+
+type memoryUnlock func()
+
+func (fn memoryUnlock) Unlock(context.Context) error {
+	fn()
+	return nil
+}
+
+func (fn memoryUnlock) Token() int64 {
+	return 0
+}
+
+func TestRunOnce(t *testing.T) {
+	dl := newMemoryLock()
+	var runs int32
+	task := func(context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		// keep the lock long enough for the other replica to wait on it.
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}
+
+	// two replicas starting at the same time.
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- RunOnce(context.Background(), dl, "seed", task)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+
+	// later starts skip the task too.
+	assert.NoError(t, RunOnce(context.Background(), dl, "seed", task))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+}
+
+func TestRunOnce_Failure(t *testing.T) {
+	dl := newMemoryLock()
+	errTask := errors.New("task failure")
+
+	// the marker is not set on failure, the task runs again.
+	err := RunOnce(context.Background(), dl, "warm", func(context.Context) error { return errTask })
+	assert.True(t, errors.Is(err, errTask))
+
+	runs := 0
+	err = RunOnce(context.Background(), dl, "warm", func(context.Context) error {
+		runs++
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, runs)
+}
+
+// lockOnly is a distributed lock without markers.
+type lockOnly struct {
+	dlock.DistributedLock
+}
+
+func TestRunOnce_NoMarker(t *testing.T) {
+	err := RunOnce(context.Background(), lockOnly{newMemoryLock()}, "seed", func(context.Context) error {
+		assert.Fail(t, "the task should not run")
+		return nil
+	})
+	assert.Error(t, err)
+}
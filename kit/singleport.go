@@ -0,0 +1,266 @@
+package kit
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// http2Preface is the connection preface sent by HTTP/2 clients, gRPC clients included.
+var http2Preface = []byte("PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n")
+
+const (
+	// prefaceTimeout is the maximum time to receive the first request of a connection to route it.
+	prefaceTimeout = 10 * time.Second
+	// http2FrameHeaderLen is the length of the header of an HTTP/2 frame.
+	http2FrameHeaderLen = 9
+	// http2DefaultFrameSize is the maximum frame size a client can send before the server changes it.
+	http2DefaultFrameSize = 16 << 10
+	// http2DefaultHeaderTableSize is the size of the header compression table before the server changes it.
+	http2DefaultHeaderTableSize = 4 << 10
+)
+
+// portMux splits the connections accepted on a single listener between the gRPC server
+// and the HTTP server, like cmux.
+//
+// The HTTP/2 cleartext (h2c) connections whose first request has the `content-type: application/grpc`
+// header are routed to the gRPC server, the other h2c connections are served by the HTTP handler (see serveH2C),
+// and everything else goes to the HTTP server.
+type portMux struct {
+	root net.Listener
+	grpc *muxListener
+	http *muxListener
+	// h2c serves the non-gRPC h2c connections, they are routed to the gRPC server if nil.
+	h2c func(conn net.Conn)
+	// the root listener is closed once both servers closed their listener.
+	closed sync.WaitGroup
+}
+
+// newPortMux creates a portMux on the given address and starts routing the connections,
+// the non-gRPC h2c connections are served by h2c (see serveH2C).
+func newPortMux(addr string, h2c func(conn net.Conn)) (*portMux, error) {
+	root, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &portMux{root: root, h2c: h2c}
+	m.grpc = newMuxListener(root.Addr(), &m.closed)
+	m.http = newMuxListener(root.Addr(), &m.closed)
+	m.closed.Add(2)
+	go func() {
+		m.closed.Wait()
+		_ = root.Close() //nolint
+	}()
+	go m.serve()
+	return m, nil
+}
+
+func (m *portMux) serve() {
+	for {
+		conn, err := m.root.Accept()
+		if err != nil {
+			_ = m.grpc.Close() //nolint
+			_ = m.http.Close() //nolint
+			return
+		}
+		go m.route(conn)
+	}
+}
+
+// route reads the first bytes of the connection to dispatch it to the gRPC or HTTP listener.
+func (m *portMux) route(conn net.Conn) {
+	_ = conn.SetReadDeadline(time.Now().Add(prefaceTimeout)) //nolint
+	buf := make([]byte, 0, len(http2Preface))
+	for len(buf) < len(http2Preface) && bytes.HasPrefix(http2Preface, buf) {
+		n, err := conn.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if err != nil {
+			_ = conn.Close() //nolint
+			return
+		}
+	}
+
+	if !bytes.HasPrefix(buf, http2Preface) {
+		_ = conn.SetReadDeadline(time.Time{}) //nolint
+		m.http.deliver(&peekedConn{Conn: conn, r: io.MultiReader(bytes.NewReader(buf), conn)})
+		return
+	}
+
+	// the frames read until the first request are replayed to the server.
+	var frames bytes.Buffer
+	grpc, err := isGRPC(conn, io.TeeReader(conn, &frames))
+	_ = conn.SetReadDeadline(time.Time{}) //nolint
+	if err != nil {
+		_ = conn.Close() //nolint
+		return
+	}
+	replay := io.MultiReader(bytes.NewReader(frames.Bytes()), conn)
+
+	if grpc || m.h2c == nil {
+		m.grpc.deliver(&peekedConn{Conn: conn, r: io.MultiReader(bytes.NewReader(buf), replay)})
+		return
+	}
+	select {
+	case <-m.http.done:
+		_ = conn.Close() //nolint
+	default:
+		// the HTTP/2 server acknowledges the settings itself.
+		m.h2c(&peekedConn{Conn: conn, r: io.MultiReader(bytes.NewReader(buf), &settingsAckSkipper{r: replay})})
+	}
+}
+
+// isGRPC reads the frames of an HTTP/2 connection following its preface until the headers of the first request,
+// reporting whether it is a gRPC request.
+// The server settings are sent to the client (w) on the way: gRPC clients wait for them before sending a request.
+func isGRPC(w io.Writer, r io.Reader) (bool, error) {
+	framer := http2.NewFramer(w, r)
+	framer.SetMaxReadFrameSize(http2DefaultFrameSize)
+	framer.ReadMetaHeaders = hpack.NewDecoder(http2DefaultHeaderTableSize, nil)
+
+	sentSettings := false
+	for {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			return false, err
+		}
+		switch f := frame.(type) {
+		case *http2.SettingsFrame:
+			if f.IsAck() || sentSettings {
+				continue
+			}
+			if err := framer.WriteSettings(); err != nil {
+				return false, err
+			}
+			sentSettings = true
+		case *http2.MetaHeadersFrame:
+			for _, hf := range f.RegularFields() {
+				if hf.Name == "content-type" {
+					return strings.HasPrefix(hf.Value, "application/grpc"), nil
+				}
+			}
+			return false, nil
+		}
+	}
+}
+
+// settingsAckSkipper reads the HTTP/2 frames of r, skipping the first settings acknowledgement:
+// the one of the settings sent by isGRPC, the HTTP/2 server rejects the acknowledgements of settings it did not send.
+type settingsAckSkipper struct {
+	r       io.Reader
+	skipped bool
+	// header is the pending header of the current frame, payload the length of its payload left to read.
+	header  []byte
+	payload int
+}
+
+func (s *settingsAckSkipper) Read(b []byte) (int, error) {
+	for {
+		switch {
+		case s.skipped:
+			return s.r.Read(b)
+		case len(s.header) > 0:
+			n := copy(b, s.header)
+			s.header = s.header[n:]
+			return n, nil
+		case s.payload > 0:
+			if len(b) > s.payload {
+				b = b[:s.payload]
+			}
+			n, err := s.r.Read(b)
+			s.payload -= n
+			return n, err
+		}
+
+		header := make([]byte, http2FrameHeaderLen)
+		if _, err := io.ReadFull(s.r, header); err != nil {
+			return 0, err
+		}
+		length := int(header[0])<<16 | int(header[1])<<8 | int(header[2])
+		if http2.FrameType(header[3]) == http2.FrameSettings && http2.Flags(header[4]).Has(http2.FlagSettingsAck) {
+			s.skipped = true
+			if _, err := io.CopyN(io.Discard, s.r, int64(length)); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		s.header, s.payload = header, length
+	}
+}
+
+// serveH2C returns the function serving the h2c connections with the handler of srv,
+// they are gracefully closed on srv.Shutdown.
+func serveH2C(srv *http.Server) (func(conn net.Conn), error) {
+	h2s := &http2.Server{}
+	if err := http2.ConfigureServer(srv, h2s); err != nil {
+		return nil, err
+	}
+	return func(conn net.Conn) {
+		h2s.ServeConn(conn, &http2.ServeConnOpts{BaseConfig: srv})
+	}, nil
+}
+
+// muxListener is a net.Listener receiving the connections routed by a portMux.
+type muxListener struct {
+	addr      net.Addr
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+	closed    *sync.WaitGroup
+}
+
+func newMuxListener(addr net.Addr, closed *sync.WaitGroup) *muxListener {
+	return &muxListener{
+		addr:   addr,
+		conns:  make(chan net.Conn),
+		done:   make(chan struct{}),
+		closed: closed,
+	}
+}
+
+// deliver hands the connection to the server accepting on the listener, it is closed if the listener is closed.
+func (l *muxListener) deliver(conn net.Conn) {
+	select {
+	case l.conns <- conn:
+	case <-l.done:
+		_ = conn.Close() //nolint
+	}
+}
+
+func (l *muxListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *muxListener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.done)
+		l.closed.Done()
+	})
+	return nil
+}
+
+func (l *muxListener) Addr() net.Addr {
+	return l.addr
+}
+
+// peekedConn is a connection whose first bytes have already been read.
+type peekedConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *peekedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
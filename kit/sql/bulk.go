@@ -0,0 +1,66 @@
+package sql
+
+import (
+	"context"
+	"strings"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/jackc/pgx/v4"
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// BulkInsert inserts the given rows into table using the postgres COPY protocol
+// and returns the number of rows copied.
+//
+// It is meant for inserting a large number of rows at once, and is much faster
+// than issuing individual INSERTs. Each row must provide a value for every column,
+// in the same order as columns.
+// table can be schema qualified (eg: schema.table).
+func BulkInsert(ctx context.Context, db *sqlx.DB, table string, columns []string, rows [][]interface{}) (int64, error) {
+	ctx, span := otel.Tracer("db").Start(ctx, "db.BulkInsert")
+	span.SetAttributes(
+		attribute.String("table", table),
+		attribute.Int("rows", len(rows)),
+	)
+	defer span.End()
+
+	if len(table) == 0 {
+		return 0, errors.New("table is required")
+	}
+	if len(columns) == 0 {
+		return 0, errors.New("columns are required")
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, errors.Wrap(err, "acquiring connection")
+	}
+	defer conn.Close()
+
+	var copied int64
+	err = conn.Raw(func(driverConn interface{}) error {
+		pc, err := pgxConn(driverConn)
+		if err != nil {
+			return err
+		}
+
+		copied, err = pc.CopyFrom(ctx, pgx.Identifier(strings.Split(table, ".")), columns, pgx.CopyFromRows(rows))
+		return err
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return copied, errors.Wrapf(err, "copying rows into '%s'", table)
+	}
+
+	span.SetAttributes(attribute.Int64("copied", copied))
+	return copied, nil
+}
@@ -0,0 +1,65 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkInsert(t *testing.T) {
+	if os.Getenv("TESTINGDB_URL") == "" {
+		t.Skip("Skipping, no testing database setup via env variable TESTINGDB_URL")
+	}
+
+	// Creating a testing DB
+	var tdb TestingDB
+	err := tdb.Open()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer tdb.Close()
+
+	ctx := context.Background()
+	_, err = tdb.ExecContext(ctx, `CREATE TABLE bulk (id INT PRIMARY KEY, name TEXT NOT NULL)`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = tdb.ExecContext(ctx, `CREATE TABLE single (id INT PRIMARY KEY, name TEXT NOT NULL)`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	const n = 10000
+	rows := make([][]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		rows = append(rows, []interface{}{i, fmt.Sprintf("name_%d", i)})
+	}
+
+	// Bulk insert
+	start := time.Now()
+	copied, err := BulkInsert(ctx, tdb.DB, "bulk", []string{"id", "name"}, rows)
+	bulkDuration := time.Since(start)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(n), copied)
+
+	var count int64
+	err = tdb.GetContext(ctx, &count, `SELECT count(*) FROM bulk`)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(n), count)
+
+	// Row by row insert
+	start = time.Now()
+	for _, row := range rows {
+		_, err = tdb.ExecContext(ctx, `INSERT INTO single (id, name) VALUES ($1, $2)`, row...)
+		if !assert.NoError(t, err) {
+			return
+		}
+	}
+	singleDuration := time.Since(start)
+
+	assert.Less(t, bulkDuration*5, singleDuration, "bulk insert should be substantially faster than row by row")
+}
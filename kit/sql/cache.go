@@ -0,0 +1,70 @@
+package sql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/cache"
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// SelectCached runs the query and scans the resulting rows into a slice of T, the same way
+// sqlx SelectContext does, caching the result set for the ttl duration.
+//
+// The result set is cached under keyPrefix followed by a hash of the query and its args,
+// identical queries issued while the entry is fresh are served from the cache without hitting the database.
+// Invalidation is TTL based only, writes to the underlying tables are not reflected until the entry expires.
+// Cache failures are not fatal, the query is served from the database instead.
+func SelectCached[T any](ctx context.Context, db Queryable, c cache.Cache, keyPrefix string, ttl time.Duration, query string, args ...interface{}) ([]T, error) {
+	ctx, span := otel.Tracer("db").Start(ctx, "db.SelectCached")
+	defer span.End()
+
+	key, err := cacheKey(keyPrefix, query, args...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("key", key))
+
+	var rows []T
+	err = c.Get(ctx, key, &rows)
+	if err == nil {
+		span.SetAttributes(attribute.Bool("cached", true))
+		return rows, nil
+	}
+	if !errors.Is(err, cache.ErrNotFound) {
+		span.RecordError(err)
+	}
+	span.SetAttributes(attribute.Bool("cached", false))
+
+	if err := db.SelectContext(ctx, &rows, query, args...); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, errors.Wrap(err, "select")
+	}
+
+	if err := c.Set(ctx, key, rows, ttl); err != nil {
+		span.RecordError(err)
+	}
+	return rows, nil
+}
+
+// cacheKey returns the cache key of the query and its args.
+func cacheKey(keyPrefix string, query string, args ...interface{}) (string, error) {
+	encodedArgs, err := cache.Marshal(args)
+	if err != nil {
+		return "", errors.Wrap(err, "encoding query args")
+	}
+
+	h := sha256.New()
+	h.Write([]byte(query))
+	h.Write([]byte{0})
+	h.Write(encodedArgs)
+	return keyPrefix + hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,153 @@
+package sql
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// mapCache is a minimal cache.Cache keeping the values in memory, expiration is ignored.
+type mapCache struct {
+	values map[string][]byte
+	lock   sync.Mutex
+}
+
+func (m *mapCache) Get(_ context.Context, key string, value interface{}) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	b, ok := m.values[key]
+	if !ok {
+		return cache.ErrNotFound
+	}
+	return cache.Unmarshal(b, value)
+}
+
+func (m *mapCache) MultiGet(context.Context, []string, interface{}) error {
+	return nil
+}
+
+func (m *mapCache) Set(_ context.Context, key string, value interface{}, _ time.Duration) error {
+	b, err := cache.Marshal(value)
+	if err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.values[key] = b
+	return nil
+}
+
+func (m *mapCache) SetNX(context.Context, string, interface{}, time.Duration) (bool, error) {
+	return false, nil
+}
+
+func (m *mapCache) GetOrSet(ctx context.Context, key string, dest interface{}, ttl time.Duration, loader func(ctx context.Context) (interface{}, error)) error {
+	if err := m.Get(ctx, key, dest); err == nil {
+		return nil
+	}
+	value, err := loader(ctx)
+	if err != nil {
+		return err
+	}
+	if err := m.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	return m.Get(ctx, key, dest)
+}
+
+func (m *mapCache) Increment(context.Context, string, int64) (int64, error) {
+	return 0, nil
+}
+
+func (m *mapCache) Decrement(context.Context, string, int64) (int64, error) {
+	return 0, nil
+}
+
+func (m *mapCache) Expire(context.Context, string, time.Duration) error {
+	return nil
+}
+
+func (m *mapCache) Delete(_ context.Context, key string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.values, key)
+	return nil
+}
+
+// countingDB counts the select queries hitting the database.
+type countingDB struct {
+	Queryable
+	selects int
+}
+
+func (c *countingDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	c.selects++
+	return c.Queryable.SelectContext(ctx, dest, query, args...)
+}
+
+func TestCacheKey(t *testing.T) {
+	a, err := cacheKey("items:", "SELECT name FROM items WHERE id = $1", 1)
+	assert.NoError(t, err)
+	b, err := cacheKey("items:", "SELECT name FROM items WHERE id = $1", 2)
+	assert.NoError(t, err)
+	c, err := cacheKey("items:", "SELECT name FROM items WHERE id = $1", 1)
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, a, b)
+	assert.Equal(t, a, c)
+	assert.Contains(t, a, "items:")
+}
+
+func TestSelectCached(t *testing.T) {
+	if os.Getenv("TESTINGDB_URL") == "" {
+		t.Skip("Skipping, no testing database setup via env variable TESTINGDB_URL")
+	}
+
+	// Creating a testing DB
+	var tdb TestingDB
+	err := tdb.Open()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer tdb.Close()
+
+	ctx := context.Background()
+	_, err = tdb.ExecContext(ctx, `CREATE TABLE items (id INT NOT NULL, name TEXT NOT NULL)`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = tdb.ExecContext(ctx, `INSERT INTO items VALUES (1, 'one'), (2, 'two'), (3, 'three')`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	type item struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	db := &countingDB{Queryable: tdb.DB}
+	c := &mapCache{values: map[string][]byte{}}
+	const q = `SELECT id, name FROM items WHERE id < $1 ORDER BY id`
+
+	items, err := SelectCached[item](ctx, db, c, "items:", time.Minute, q, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []item{{1, "one"}, {2, "two"}}, items)
+	assert.Equal(t, 1, db.selects)
+
+	// identical query served from the cache
+	items, err = SelectCached[item](ctx, db, c, "items:", time.Minute, q, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []item{{1, "one"}, {2, "two"}}, items)
+	assert.Equal(t, 1, db.selects)
+
+	// different args hit the database
+	items, err = SelectCached[item](ctx, db, c, "items:", time.Minute, q, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []item{{1, "one"}}, items)
+	assert.Equal(t, 2, db.selects)
+}
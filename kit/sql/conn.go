@@ -0,0 +1,43 @@
+package sql
+
+import (
+	"database/sql/driver"
+	"reflect"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/stdlib"
+)
+
+// pgxConn returns the underlying pgx connection of a database/sql driver connection.
+//
+// Connections opened via Open are wrapped by the otel driver which doesn't expose
+// the wrapped connection, so we need to walk down the embedded driver.Conn
+// until we reach the pgx stdlib connection.
+func pgxConn(driverConn interface{}) (*pgx.Conn, error) {
+	for driverConn != nil {
+		if c, ok := driverConn.(*stdlib.Conn); ok {
+			return c.Conn(), nil
+		}
+
+		v := reflect.ValueOf(driverConn)
+		if v.Kind() == reflect.Ptr {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			break
+		}
+
+		f := v.FieldByName("Conn")
+		if !f.IsValid() || !f.CanInterface() {
+			break
+		}
+		next, ok := f.Interface().(driver.Conn)
+		if !ok {
+			break
+		}
+		driverConn = next
+	}
+
+	return nil, errors.New("connection is not a pgx connection")
+}
@@ -8,19 +8,20 @@ import (
 
 	"github.com/golang-migrate/migrate/v4"
 
+	"github.com/anthonycorbacho/workspace/kit/dlock"
 	"github.com/anthonycorbacho/workspace/kit/errors"
-	"github.com/golang-migrate/migrate/v4/database/pgx"
+	migratepgx "github.com/golang-migrate/migrate/v4/database/pgx"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/stdlib"
 	"github.com/jmoiron/sqlx"
 	"github.com/uptrace/opentelemetry-go-extra/otelsql"
 	"github.com/uptrace/opentelemetry-go-extra/otelsqlx"
 	"github.com/xo/dburl"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
-
-	// force import pgx (postgres driver)
-	_ "github.com/jackc/pgx/v4/stdlib"
 )
 
 // Open knows how to open a database connection based on connection string.
@@ -45,20 +46,6 @@ func Open(connection string, ops ...Option) (*sqlx.DB, error) {
 		Value: attribute.StringValue(url.Host),
 	})
 
-	// Connect to the database using the otel driver wrapper.
-	db, err := otelsqlx.Open(driver, connection, otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
-		otelAttributes,
-	)
-	if err != nil {
-		return nil, errors.Wrap(err, "open db")
-	}
-
-	// By default, otelsqlx do not record DB stats.
-	// In order to get DB stats (e.g. # sql connection, etc) we need to call ReportDBStatsMetrics
-	// and pass sql.DB pointer.
-	// this is only available from otelsql pkg.
-	otelsql.ReportDBStatsMetrics(db.DB, otelAttributes)
-
 	// Setup SQL options.
 	opts := &options{
 		MaxOpenConns:    10,
@@ -70,6 +57,35 @@ func Open(connection string, ops ...Option) (*sqlx.DB, error) {
 		o(opts)
 	}
 
+	// Connect to the database using the otel driver wrapper.
+	var db *sqlx.DB
+	if opts.AfterConnect != nil {
+		// Connection hooks are only available via the pgx connection config,
+		// we need to create the connector ourselves.
+		if driver != "pgx" {
+			return nil, errors.New("after connect hook is only supported by postgres")
+		}
+		config, err := pgx.ParseConfig(connection)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing pgx connection config")
+		}
+		connector := stdlib.GetConnector(*config, stdlib.OptionAfterConnect(opts.AfterConnect))
+		db = sqlx.NewDb(otelsql.OpenDB(connector, otelsql.WithAttributes(semconv.DBSystemPostgreSQL), otelAttributes), driver)
+	} else {
+		db, err = otelsqlx.Open(driver, connection, otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+			otelAttributes,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "open db")
+		}
+	}
+
+	// By default, otelsqlx do not record DB stats.
+	// In order to get DB stats (e.g. # sql connection, etc) we need to call ReportDBStatsMetrics
+	// and pass sql.DB pointer.
+	// this is only available from otelsql pkg.
+	otelsql.ReportDBStatsMetrics(db.DB, otelAttributes)
+
 	db.SetMaxOpenConns(opts.MaxOpenConns)
 	db.SetMaxIdleConns(opts.MaxIdleConns)
 	db.SetConnMaxLifetime(opts.MaxConnLifeTime)
@@ -96,34 +112,81 @@ func StatusCheck(ctx context.Context, db *sqlx.DB) error {
 // Migrate looks at the currently active migration version of the service
 // and will migrate all the way up (applying all up migrations).
 // Migrate will look at the folder `db` by default (generally assets/db).
-func Migrate(db *sqlx.DB, service string, fs fs.FS) error {
-	return MigrateWithPath(db, fs, service, "db")
+func Migrate(db *sqlx.DB, service string, fs fs.FS, opts ...MigrateOption) error {
+	return MigrateWithPath(db, fs, service, "db", opts...)
 }
 
-// MigrateWithPath looks at the currently active migration version of the service
-// and will migrate all the way up (applying all up migrations)
-// from the given fs path.
-func MigrateWithPath(db *sqlx.DB, fs fs.FS, service string, path string) error {
-	m, err := getMigrate(db, fs, service, path)
+// MigrateWithLock migrates like Migrate while holding the service migration lock,
+// so only one replica of the service migrates at a time, the others wait for the lock
+// and find the migrations already applied.
+func MigrateWithLock(ctx context.Context, db *sqlx.DB, service string, fs fs.FS, dl dlock.DistributedLock, opts ...MigrateOption) error {
+	ctx, span := otel.Tracer("db").Start(ctx, "db.MigrateWithLock")
+	span.SetAttributes(attribute.String("service", service))
+	defer span.End()
+
+	if len(service) == 0 {
+		return errors.New("service name is required")
+	}
+
+	lock, err := dl.Lock(ctx, fmt.Sprintf("%s_schema_migrations", service))
 	if err != nil {
-		return err
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return errors.Wrap(err, "acquire migration lock")
 	}
+	defer func() {
+		_ = lock.Unlock(context.Background()) //nolint
+	}()
 
-	// Apply the migration.
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+	if err := migrateWithPath(ctx, db, fs, service, "db", opts...); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		return err
 	}
-
 	return nil
 }
 
-// MigrateToVersion should be use to apply down or up script to a given version
-func MigrateToVersion(db *sqlx.DB, service string, fs fs.FS, version uint) error {
-	m, err := getMigrate(db, fs, service, "db")
-	if err != nil {
-		return err
+// MigrateWithPath looks at the currently active migration version of the service
+// and will migrate all the way up (applying all up migrations)
+// from the given fs path.
+//
+// The migration is traced, its duration and outcome are recorded as metrics
+// and the applied version is logged (see WithMigrationLogger).
+func MigrateWithPath(db *sqlx.DB, fs fs.FS, service string, path string, opts ...MigrateOption) error {
+	return migrateWithPath(context.Background(), db, fs, service, path, opts...)
+}
+
+func migrateWithPath(ctx context.Context, db *sqlx.DB, fs fs.FS, service string, path string, opts ...MigrateOption) error {
+	o := newMigrateOptions(opts)
+	if o.dryRun {
+		return logMigrationPlan(ctx, o.logger, db, fs, service, path)
 	}
-	return m.Migrate(version)
+
+	return instrumentMigration(ctx, "db.Migrate", service, o.logger, func() (*migrate.Migrate, error) {
+		m, err := getMigrate(db, fs, service, path)
+		if err != nil {
+			return nil, err
+		}
+
+		// Apply the migration.
+		if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+			return m, err
+		}
+		return m, nil
+	})
+}
+
+// MigrateToVersion should be use to apply down or up script to a given version.
+// It is instrumented like MigrateWithPath, WithDryRun is not supported.
+func MigrateToVersion(db *sqlx.DB, service string, fs fs.FS, version uint, opts ...MigrateOption) error {
+	o := newMigrateOptions(opts)
+	return instrumentMigration(context.Background(), "db.MigrateToVersion", service, o.logger, func() (*migrate.Migrate, error) {
+		m, err := getMigrate(db, fs, service, "db")
+		if err != nil {
+			return nil, err
+		}
+		return m, m.Migrate(version)
+	})
 }
 
 func getMigrate(db *sqlx.DB, fs fs.FS, service string, path string) (*migrate.Migrate, error) {
@@ -136,7 +199,7 @@ func getMigrate(db *sqlx.DB, fs fs.FS, service string, path string) (*migrate.Mi
 		return nil, err
 	}
 
-	driver, err := pgx.WithInstance(db.DB, &pgx.Config{
+	driver, err := migratepgx.WithInstance(db.DB, &migratepgx.Config{
 		MigrationsTable: fmt.Sprintf("%s_schema_migrations", service),
 	})
 	if err != nil {
@@ -0,0 +1,206 @@
+package sql
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/log"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// MigrationStep represents a migration pending to be applied.
+type MigrationStep struct {
+	// Version of the migration.
+	Version uint
+	// Identifier of the migration, eg: the name of the file without version and extension.
+	Identifier string
+	// SQL of the up migration.
+	SQL string
+}
+
+// migrateOptions provides a set of configurable options for migrations.
+type migrateOptions struct {
+	dryRun bool
+	logger *log.Logger
+}
+
+// MigrateOption defines a migration option.
+type MigrateOption func(*migrateOptions)
+
+// WithDryRun logs the migrations that would be applied without applying them.
+func WithDryRun() MigrateOption {
+	return func(o *migrateOptions) {
+		o.dryRun = true
+	}
+}
+
+// WithMigrationLogger defines the logger reporting the outcome of the migrations and the applied version.
+// By default, the global logger is used.
+func WithMigrationLogger(logger *log.Logger) MigrateOption {
+	return func(o *migrateOptions) {
+		o.logger = logger
+	}
+}
+
+func newMigrateOptions(opts []MigrateOption) *migrateOptions {
+	o := &migrateOptions{logger: log.L()}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// instrumentMigration runs the migration fn of the service within a span,
+// records its duration (db.migration.duration) and outcome (db.migrations)
+// and logs the version applied once done.
+func instrumentMigration(ctx context.Context, name string, service string, logger *log.Logger, fn func() (*migrate.Migrate, error)) error {
+	ctx, span := otel.Tracer("db").Start(ctx, name)
+	span.SetAttributes(attribute.String("service", service))
+	defer span.End()
+
+	start := time.Now()
+	m, err := fn()
+	duration := time.Since(start)
+
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+	recordMigration(ctx, service, outcome, duration)
+
+	fields := []log.Field{
+		log.String("service", service),
+		log.Duration("duration", duration),
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		logger.Error(ctx, "migration failure", append(fields, log.Error(err))...)
+		return err
+	}
+
+	if m != nil {
+		version, dirty, verr := m.Version()
+		if verr == nil {
+			span.SetAttributes(attribute.Int64("version", int64(version)))
+			fields = append(fields, log.Uint("version", version), log.Bool("dirty", dirty))
+		}
+	}
+	logger.Info(ctx, "migration applied", fields...)
+	return nil
+}
+
+// recordMigration records the duration and the outcome of a migration of the service.
+func recordMigration(ctx context.Context, service string, outcome string, duration time.Duration) {
+	meter := otel.Meter("kit/sql")
+	attrs := metric.WithAttributes(attribute.String("service", service), attribute.String("outcome", outcome))
+
+	if h, err := meter.Float64Histogram("db.migration.duration",
+		metric.WithDescription("Duration of the database migrations"),
+		metric.WithUnit("s"),
+	); err == nil {
+		h.Record(ctx, duration.Seconds(), attrs)
+	}
+	if c, err := meter.Int64Counter("db.migrations",
+		metric.WithDescription("Number of database migrations by outcome"),
+	); err == nil {
+		c.Add(ctx, 1, attrs)
+	}
+}
+
+// MigrationPlan returns the migrations that would be applied by Migrate,
+// from the currently active migration version of the service, without applying them.
+func MigrationPlan(db *sqlx.DB, service string, fs fs.FS) ([]MigrationStep, error) {
+	return migrationPlan(db, fs, service, "db")
+}
+
+func migrationPlan(db *sqlx.DB, fs fs.FS, service string, path string) ([]MigrationStep, error) {
+	m, err := getMigrate(db, fs, service, path)
+	if err != nil {
+		return nil, err
+	}
+
+	current, dirty, err := m.Version()
+	hasVersion := err == nil
+	if err != nil && err != migrate.ErrNilVersion {
+		return nil, errors.Wrap(err, "getting migration version")
+	}
+	if dirty {
+		return nil, errors.Newf("database is dirty at version %d", current)
+	}
+
+	src, err := iofs.New(fs, path)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	var steps []MigrationStep
+	version, err := src.First()
+	for err == nil {
+		if !hasVersion || version > current {
+			step, readErr := readMigration(src, version)
+			if readErr != nil {
+				return nil, readErr
+			}
+			if step != nil {
+				steps = append(steps, *step)
+			}
+		}
+		version, err = src.Next(version)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, errors.Wrap(err, "reading migrations")
+	}
+
+	return steps, nil
+}
+
+// readMigration reads the up migration of the given version.
+// It returns nil if the version has no up migration.
+func readMigration(src source.Driver, version uint) (*MigrationStep, error) {
+	r, identifier, err := src.ReadUp(version)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading migration %d", version)
+	}
+	defer r.Close()
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading migration %d", version)
+	}
+	return &MigrationStep{Version: version, Identifier: identifier, SQL: string(b)}, nil
+}
+
+// logMigrationPlan logs the migrations that would be applied with the migration logger, see WithMigrationLogger.
+func logMigrationPlan(ctx context.Context, logger *log.Logger, db *sqlx.DB, fs fs.FS, service string, path string) error {
+	steps, err := migrationPlan(db, fs, service, path)
+	if err != nil {
+		return err
+	}
+
+	logger.Info(ctx, "migration dry-run", log.String("service", service), log.Int("pending", len(steps)))
+	for _, step := range steps {
+		logger.Info(ctx, "pending migration",
+			log.String("service", service),
+			log.Uint("version", step.Version),
+			log.String("identifier", step.Identifier),
+			log.String("sql", step.SQL),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package sql_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	dlocksql "github.com/anthonycorbacho/workspace/kit/dlock/sql"
+	kitsql "github.com/anthonycorbacho/workspace/kit/sql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateWithLock(t *testing.T) {
+	if os.Getenv("TESTINGDB_URL") == "" {
+		t.Skip("Skipping, no testing database setup via env variable TESTINGDB_URL")
+	}
+
+	// Creating a testing DB
+	var tdb kitsql.TestingDB
+	err := tdb.Open()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer tdb.Close()
+
+	dl, err := dlocksql.NewDistributedLock(tdb.DSN)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer dl.Close()
+
+	// the migration fails if applied twice.
+	migrations := fstest.MapFS{
+		"db/1_init.up.sql": &fstest.MapFile{Data: []byte(`
+			CREATE TABLE applied (version int PRIMARY KEY);
+			INSERT INTO applied VALUES (1);
+		`)},
+		"db/1_init.down.sql": &fstest.MapFile{Data: []byte(`DROP TABLE applied;`)},
+	}
+
+	// another replica holds the migration lock of the service from its own connection.
+	ctx := context.Background()
+	held, err := dl.Lock(ctx, "test_schema_migrations")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	migrated := make(chan error, 1)
+	go func() {
+		migrated <- kitsql.MigrateWithLock(ctx, tdb.DB, "test", migrations, dl)
+	}()
+
+	// the migration waits for the lock, nothing is applied meanwhile.
+	select {
+	case err := <-migrated:
+		assert.Fail(t, "migrated while the lock is held", err)
+	case <-time.After(300 * time.Millisecond):
+	}
+	var exists bool
+	err = tdb.Get(&exists, `SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'applied')`)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// once released, the migration is applied.
+	assert.NoError(t, held.Unlock(ctx))
+	select {
+	case err := <-migrated:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		assert.Fail(t, "migration still waiting once the lock is released")
+	}
+
+	// the next replica finds the migration applied.
+	assert.NoError(t, kitsql.MigrateWithLock(ctx, tdb.DB, "test", migrations, dl))
+
+	var count int
+	err = tdb.Get(&count, `SELECT count(*) FROM applied`)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,52 @@
+package sql
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListenNotify(t *testing.T) {
+	if os.Getenv("TESTINGDB_URL") == "" {
+		t.Skip("Skipping, no testing database setup via env variable TESTINGDB_URL")
+	}
+
+	// Creating a testing DB
+	var tdb TestingDB
+	err := tdb.Open()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer tdb.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	notifications, err := Listen(ctx, tdb.DSN, "kit_events")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	err = Notify(ctx, tdb.DB, "kit_events", "hello")
+	assert.NoError(t, err)
+
+	select {
+	case n := <-notifications:
+		assert.Equal(t, "kit_events", n.Channel)
+		assert.Equal(t, "hello", n.Payload)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "timeout waiting for notification")
+	}
+
+	// cancelling the context should close the notification channel.
+	cancel()
+	select {
+	case _, ok := <-notifications:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "timeout waiting for listener to stop")
+	}
+}
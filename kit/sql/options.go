@@ -1,6 +1,11 @@
 package sql
 
-import "time"
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
 
 // options provides a set of configurable options for SQL.
 type options struct {
@@ -9,6 +14,7 @@ type options struct {
 	MaxConnLifeTime    time.Duration
 	MaxConnIdleTime    time.Duration
 	StatementCacheMode string
+	AfterConnect       func(ctx context.Context, conn *pgx.Conn) error
 }
 
 // Option defines a SQL option.
@@ -48,3 +54,14 @@ func WithStatementCacheMode(mode string) Option {
 		o.StatementCacheMode = mode
 	}
 }
+
+// WithAfterConnect defines a function called on every new connection of the pool
+// before it is used, eg: for setting session parameters (search_path, timezone, role).
+// If the function returns an error, the connection is discarded.
+//
+// It is only supported by postgres.
+func WithAfterConnect(fn func(ctx context.Context, conn *pgx.Conn) error) Option {
+	return func(o *options) {
+		o.AfterConnect = fn
+	}
+}
@@ -0,0 +1,54 @@
+package sql
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithAfterConnect(t *testing.T) {
+	if os.Getenv("TESTINGDB_URL") == "" {
+		t.Skip("Skipping, no testing database setup via env variable TESTINGDB_URL")
+	}
+
+	// Creating a testing DB
+	var tdb TestingDB
+	err := tdb.Open()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer tdb.Close()
+
+	ctx := context.Background()
+	_, err = tdb.ExecContext(ctx, `CREATE SCHEMA kit; CREATE TABLE kit.hooks (name TEXT); INSERT INTO kit.hooks VALUES ('hook')`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	db, err := Open(tdb.DSN, WithMaxOpenConns(3), WithAfterConnect(func(ctx context.Context, conn *pgx.Conn) error {
+		_, err := conn.Exec(ctx, `SET search_path TO kit`)
+		return err
+	}))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	// Hold every connection of the pool at once, so we make sure
+	// each of them has been set up by the hook.
+	for i := 0; i < 3; i++ {
+		conn, err := db.Connx(ctx)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+
+		var name string
+		err = conn.GetContext(ctx, &name, `SELECT name FROM hooks`)
+		assert.NoError(t, err)
+		assert.Equal(t, "hook", name)
+	}
+}
@@ -0,0 +1,67 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/jackc/pgx/v4"
+	"github.com/jmoiron/sqlx"
+)
+
+// ReadCommitted returns transaction options using the read committed isolation level.
+// A statement can only see rows committed before it began.
+//
+//	tx, err := db.BeginTxx(ctx, sql.ReadCommitted())
+func ReadCommitted() *sql.TxOptions {
+	return &sql.TxOptions{Isolation: sql.LevelReadCommitted}
+}
+
+// Serializable returns transaction options using the serializable isolation level.
+// Transactions behave as if they were executed one after another, the caller is
+// responsible for retrying transactions failing with a serialization error.
+//
+//	tx, err := db.BeginTxx(ctx, sql.Serializable())
+func Serializable() *sql.TxOptions {
+	return &sql.TxOptions{Isolation: sql.LevelSerializable}
+}
+
+// Savepoint establishes a new savepoint with the given name within the transaction.
+// It allows rolling back the operations issued after the savepoint via RollbackTo
+// while keeping the rest of the transaction.
+func Savepoint(ctx context.Context, tx *sqlx.Tx, name string) error {
+	if len(name) == 0 {
+		return errors.New("savepoint name is required")
+	}
+
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+pgx.Identifier{name}.Sanitize()); err != nil {
+		return errors.Wrapf(err, "creating savepoint '%s'", name)
+	}
+	return nil
+}
+
+// RollbackTo rolls back all operations issued within the transaction after the given savepoint.
+// The savepoint remains valid and can be rolled back to again later.
+func RollbackTo(ctx context.Context, tx *sqlx.Tx, name string) error {
+	if len(name) == 0 {
+		return errors.New("savepoint name is required")
+	}
+
+	if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+pgx.Identifier{name}.Sanitize()); err != nil {
+		return errors.Wrapf(err, "rolling back to savepoint '%s'", name)
+	}
+	return nil
+}
+
+// ReleaseSavepoint destroys the given savepoint, keeping the effects of the operations
+// issued after it was established.
+func ReleaseSavepoint(ctx context.Context, tx *sqlx.Tx, name string) error {
+	if len(name) == 0 {
+		return errors.New("savepoint name is required")
+	}
+
+	if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+pgx.Identifier{name}.Sanitize()); err != nil {
+		return errors.Wrapf(err, "releasing savepoint '%s'", name)
+	}
+	return nil
+}
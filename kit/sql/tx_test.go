@@ -0,0 +1,67 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxOptions(t *testing.T) {
+	assert.Equal(t, sql.LevelReadCommitted, ReadCommitted().Isolation)
+	assert.Equal(t, sql.LevelSerializable, Serializable().Isolation)
+}
+
+func TestSavepoint(t *testing.T) {
+	if os.Getenv("TESTINGDB_URL") == "" {
+		t.Skip("Skipping, no testing database setup via env variable TESTINGDB_URL")
+	}
+
+	// Creating a testing DB
+	var tdb TestingDB
+	err := tdb.Open()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer tdb.Close()
+
+	ctx := context.Background()
+	_, err = tdb.ExecContext(ctx, `CREATE TABLE items (name TEXT NOT NULL)`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tx, err := tdb.BeginTxx(ctx, Serializable())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO items VALUES ('outer')`)
+	assert.NoError(t, err)
+
+	// sub operation rolled back
+	err = Savepoint(ctx, tx, "sub")
+	assert.NoError(t, err)
+	_, err = tx.ExecContext(ctx, `INSERT INTO items VALUES ('inner')`)
+	assert.NoError(t, err)
+	err = RollbackTo(ctx, tx, "sub")
+	assert.NoError(t, err)
+
+	// sub operation kept
+	err = Savepoint(ctx, tx, "kept")
+	assert.NoError(t, err)
+	_, err = tx.ExecContext(ctx, `INSERT INTO items VALUES ('kept')`)
+	assert.NoError(t, err)
+	err = ReleaseSavepoint(ctx, tx, "kept")
+	assert.NoError(t, err)
+
+	err = tx.Commit()
+	assert.NoError(t, err)
+
+	var names []string
+	err = tdb.SelectContext(ctx, &names, `SELECT name FROM items ORDER BY name`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"kept", "outer"}, names)
+}
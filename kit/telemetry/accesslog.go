@@ -0,0 +1,64 @@
+package telemetry
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/log"
+)
+
+// AccessLogMiddleware sets up a handler logging a summary of each request served (access log):
+// method, route template, path, status, latency, bytes written and client IP.
+// The entries are logged with the request context, they carry the trace id of the request when traced.
+//
+// Requests with a path starting with one of skipPaths (eg: "/healthz", "/debug/") are not logged.
+// The entries all have the same message, a logger sampling them (the default) drops part of them under load,
+// use a logger created with log.WithoutSampling to log every request.
+func AccessLogMiddleware(l *log.Logger, skipPaths ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, p := range skipPaths {
+				if strings.HasPrefix(r.URL.Path, p) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			wi := &responseWriterInterceptor{
+				statusCode:     http.StatusOK,
+				ResponseWriter: w,
+			}
+			start := time.Now()
+			next.ServeHTTP(wi, r)
+
+			l.Info(r.Context(), "http request",
+				log.String("http.method", r.Method),
+				log.String("http.route", routePath(r)),
+				log.String("http.path", r.URL.Path),
+				log.Int("http.status_code", wi.statusCode),
+				log.Duration("http.latency", time.Since(start)),
+				log.Int("http.response_size", wi.bytesWritten),
+				log.String("http.client_ip", clientIP(r)),
+			)
+		})
+	}
+}
+
+// clientIP returns the IP of the client of the request,
+// from the X-Forwarded-For or X-Real-Ip headers set by proxies, falling back to the remote address.
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		ip, _, _ := strings.Cut(forwarded, ",")
+		return strings.TrimSpace(ip)
+	}
+	if ip := r.Header.Get("X-Real-Ip"); ip != "" {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,72 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anthonycorbacho/workspace/kit/log"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessLogMiddleware(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	logger, err := log.New(log.WithOutputPaths(path), log.WithoutSampling())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	r := mux.NewRouter()
+	r.Use(AccessLogMiddleware(logger, "/healthz"))
+	r.HandleFunc("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	})
+	r.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodPost, "/items/42", nil)
+	req.Header.Set("X-Forwarded-For", "10.0.0.1, 10.0.0.2")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	logger.Close()
+
+	b, err := os.ReadFile(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	// the health check is not logged.
+	if !assert.Len(t, lines, 1) {
+		return
+	}
+	var entry struct {
+		Body       string
+		Attributes map[string]interface{}
+	}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "http request", entry.Body)
+	assert.Equal(t, "POST", entry.Attributes["http.method"])
+	assert.Equal(t, "/items/{id}", entry.Attributes["http.route"])
+	assert.Equal(t, "/items/42", entry.Attributes["http.path"])
+	assert.Equal(t, float64(http.StatusCreated), entry.Attributes["http.status_code"])
+	assert.Equal(t, float64(5), entry.Attributes["http.response_size"])
+	assert.Equal(t, "10.0.0.1", entry.Attributes["http.client_ip"])
+	assert.Contains(t, entry.Attributes, "http.latency")
+}
+
+func TestClientIP(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.168.1.1:1234"
+	assert.Equal(t, "192.168.1.1", clientIP(r))
+
+	r.Header.Set("X-Real-Ip", "10.0.0.3")
+	assert.Equal(t, "10.0.0.3", clientIP(r))
+
+	r.Header.Set("X-Forwarded-For", "10.0.0.1")
+	assert.Equal(t, "10.0.0.1", clientIP(r))
+}
@@ -0,0 +1,27 @@
+package telemetry
+
+import (
+	"net/http"
+
+	"github.com/anthonycorbacho/workspace/kit/telemetry/metric"
+)
+
+// InFlightMiddleware sets up a handler keeping track of the number of requests
+// being served in the given gauge metric, labeled by method and route template.
+//
+// The metric must be defined as a gauge with the labels "method" and "route", eg:
+//
+//	m.Register("http_requests_in_flight", "in-flight requests", metric.Gauge(), metric.Labels("method", "route"))
+//
+// The gauge is decremented even if the handler panics.
+func InFlightMiddleware(m *metric.Metrics, name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			done, err := m.InFlight(name, r.Method, routePath(r))
+			if err == nil {
+				defer done()
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
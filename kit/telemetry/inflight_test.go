@@ -0,0 +1,81 @@
+package telemetry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/telemetry/metric"
+	"github.com/gorilla/mux"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInFlightMiddleware(t *testing.T) {
+	const name = "test_http_requests_in_flight"
+	m := metric.New()
+	err := m.Register(name, "in-flight requests", metric.Gauge(), metric.Labels("method", "route"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	release := make(chan struct{})
+	r := mux.NewRouter()
+	r.Use(InFlightMiddleware(m, name))
+	r.HandleFunc("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	r.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("handler panic")
+	})
+
+	// holding concurrent requests
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/42", nil))
+		}()
+	}
+	assert.Eventually(t, func() bool {
+		return gaugeValue(t, name, "GET", "/items/{id}") == 3
+	}, time.Second, 10*time.Millisecond)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, float64(0), gaugeValue(t, name, "GET", "/items/{id}"))
+
+	// panicking handler
+	assert.Panics(t, func() {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/panic", nil))
+	})
+	assert.Equal(t, float64(0), gaugeValue(t, name, "POST", "/panic"))
+}
+
+// gaugeValue returns the value of the gauge with the given method and route
+// from the default prometheus registry.
+func gaugeValue(t *testing.T, name, method, route string) float64 {
+	t.Helper()
+	families, err := prom.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, mtr := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range mtr.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["method"] == method && labels["route"] == route {
+				return mtr.GetGauge().GetValue()
+			}
+		}
+	}
+	return -1
+}
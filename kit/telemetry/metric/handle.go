@@ -0,0 +1,91 @@
+package metric
+
+import (
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// Handle is a metric resolved for a set of label values, see Metrics.Handle.
+// Recording through a Handle skips the metric lookup (and its lock) and the label resolution,
+// making it suited for hot paths.
+type Handle struct {
+	kind     int
+	observer prom.Observer
+	gauge    prom.Gauge
+	counter  prom.Counter
+}
+
+// Handle resolves the metric for the given label values once, to record values without lookup.
+// The name and labels must match a previously defined metric.
+//
+// Example:
+//
+//	h, err := m.Handle("operation_duration_seconds", "get")
+//	...
+//	h.Observe(time.Since(start).Seconds())
+func (m *Metrics) Handle(name string, labels ...string) (*Handle, error) {
+	m.metricLock.RLock()
+	mtr, ok := m.metrics[name]
+	m.metricLock.RUnlock()
+	if !ok {
+		return nil, errors.Newf("unknown metric '%s'", name)
+	}
+
+	h := &Handle{kind: mtr.kind}
+	var err error
+	switch mtr.kind {
+	case histogram:
+		h.observer, err = mtr.histogramVec.GetMetricWithLabelValues(labels...)
+	case summary:
+		h.observer, err = mtr.summaryVec.GetMetricWithLabelValues(labels...)
+	case gauge:
+		h.gauge, err = mtr.gaugeVec.GetMetricWithLabelValues(labels...)
+	case counter:
+		h.counter, err = mtr.counterVec.GetMetricWithLabelValues(labels...)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "metric '%s' handle", name)
+	}
+	return h, nil
+}
+
+// Add the given value to a counter or gauge metric.
+// An error will be returned if a negative value is added to a counter.
+func (h *Handle) Add(val float64) error {
+	switch h.kind {
+	case counter:
+		if val < 0 {
+			return errors.New("value must not be negative")
+		}
+		h.counter.Add(val)
+		return nil
+	case gauge:
+		h.gauge.Add(val)
+		return nil
+	default:
+		return errors.New("unsupported operation")
+	}
+}
+
+// Set the given value to a gauge metric.
+func (h *Handle) Set(val float64) error {
+	if h.kind != gauge {
+		return errors.New("unsupported operation")
+	}
+	h.gauge.Set(val)
+	return nil
+}
+
+// Observe the given value using a histogram or summary, or set it as a gauge's value.
+func (h *Handle) Observe(val float64) error {
+	switch h.kind {
+	case histogram, summary:
+		h.observer.Observe(val)
+		return nil
+	case gauge:
+		h.gauge.Set(val)
+		return nil
+	default:
+		return errors.New("unsupported operation")
+	}
+}
@@ -0,0 +1,85 @@
+package metric
+
+import (
+	"testing"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandle(t *testing.T) {
+	m := New()
+	err := m.Register("test_handle_seconds", "handle histogram", Histogram(1, 5), Labels("operation"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer prom.Unregister(m.metrics["test_handle_seconds"].Collector())
+	err = m.Register("test_handle_total", "handle counter", Labels("operation"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer prom.Unregister(m.metrics["test_handle_total"].Collector())
+
+	h, err := m.Handle("test_handle_seconds", "get")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, h.Observe(0.5))
+	assert.NoError(t, h.Observe(2))
+	assert.Error(t, h.Add(1))
+	assert.Error(t, h.Set(1))
+
+	// recorded on the same metric as the name based calls.
+	assert.NoError(t, m.Observe("test_handle_seconds", 3, "get"))
+	obs, err := m.metrics["test_handle_seconds"].histogramVec.GetMetricWithLabelValues("get")
+	if !assert.NoError(t, err) {
+		return
+	}
+	var out dto.Metric
+	assert.NoError(t, obs.(prom.Metric).Write(&out))
+	assert.Equal(t, uint64(3), out.GetHistogram().GetSampleCount())
+
+	c, err := m.Handle("test_handle_total", "get")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, c.Add(2))
+	assert.Error(t, c.Add(-1))
+	assert.Error(t, c.Observe(1))
+
+	// unknown metric or labels
+	_, err = m.Handle("unknown", "get")
+	assert.Error(t, err)
+	_, err = m.Handle("test_handle_total", "get", "extra")
+	assert.Error(t, err)
+}
+
+func BenchmarkObserve(b *testing.B) {
+	m := New()
+	if err := m.Register("bench_observe_seconds", "bench", Histogram(.1, 1, 10), Labels("operation")); err != nil {
+		b.Fatal(err)
+	}
+	defer prom.Unregister(m.metrics["bench_observe_seconds"].Collector())
+
+	b.Run("name", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				_ = m.Observe("bench_observe_seconds", 0.5, "get")
+			}
+		})
+	})
+
+	b.Run("handle", func(b *testing.B) {
+		h, err := m.Handle("bench_observe_seconds", "get")
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				_ = h.Observe(0.5)
+			}
+		})
+	})
+}
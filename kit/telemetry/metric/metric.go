@@ -26,6 +26,9 @@ type metric struct {
 	objectives map[float64]float64
 	maxAge     time.Duration
 
+	nativeFactor     float64
+	nativeMaxBuckets uint32
+
 	histogramVec *prom.HistogramVec
 	summaryVec   *prom.SummaryVec
 	gaugeVec     *prom.GaugeVec
@@ -54,9 +57,11 @@ func newMetric(name, help string, options ...Option) (*metric, error) {
 	switch m.kind {
 	case histogram:
 		m.histogramVec = prom.NewHistogramVec(prom.HistogramOpts{
-			Name:    m.Name,
-			Help:    m.Help,
-			Buckets: m.buckets,
+			Name:                           m.Name,
+			Help:                           m.Help,
+			Buckets:                        m.buckets,
+			NativeHistogramBucketFactor:    m.nativeFactor,
+			NativeHistogramMaxBucketNumber: m.nativeMaxBuckets,
 		}, m.labels)
 
 	case summary:
@@ -0,0 +1,95 @@
+package metric
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNativeHistogram(t *testing.T) {
+	m := New()
+	err := m.Register("test_native_histogram_seconds", "native histogram", NativeHistogram(1.1, 100), Labels("operation"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer prom.Unregister(m.metrics["test_native_histogram_seconds"].Collector())
+
+	for _, v := range []float64{0.001, 0.02, 0.3, 4} {
+		err = m.Observe("test_native_histogram_seconds", v, "get")
+		assert.NoError(t, err)
+	}
+
+	h, err := m.metrics["test_native_histogram_seconds"].histogramVec.GetMetricWithLabelValues("get")
+	if !assert.NoError(t, err) {
+		return
+	}
+	var out dto.Metric
+	err = h.(prom.Metric).Write(&out)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	histogram := out.GetHistogram()
+	assert.Equal(t, uint64(4), histogram.GetSampleCount())
+	assert.InDelta(t, 4.321, histogram.GetSampleSum(), 0.0001)
+	// native histograms expose sparse buckets and no classic buckets.
+	assert.NotEmpty(t, histogram.GetPositiveSpan())
+	assert.Empty(t, histogram.GetBucket())
+	assert.Equal(t, int32(3), histogram.GetSchema())
+}
+
+func TestNativeHistogramInvalidOptions(t *testing.T) {
+	_, err := newMetric("invalid", "invalid", NativeHistogram(1, 100))
+	assert.Error(t, err)
+
+	_, err = newMetric("invalid", "invalid", NativeHistogram(1.1, -1))
+	assert.Error(t, err)
+}
+
+func TestObserveSince(t *testing.T) {
+	m := New()
+	err := m.Register("test_observe_since_seconds", "observe since", Histogram(1, 5, 10), Labels("operation", OutcomeLabel))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer prom.Unregister(m.metrics["test_observe_since_seconds"].Collector())
+
+	// completed operation
+	err = m.ObserveSince(context.Background(), "test_observe_since_seconds", time.Now(), "get")
+	assert.NoError(t, err)
+
+	// cancelled operation
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = m.ObserveSince(ctx, "test_observe_since_seconds", time.Now().Add(-7*time.Second), "get")
+	assert.NoError(t, err)
+
+	sampleCount := func(outcome string) uint64 {
+		h, err := m.metrics["test_observe_since_seconds"].histogramVec.GetMetricWithLabelValues("get", outcome)
+		if !assert.NoError(t, err) {
+			return 0
+		}
+		var out dto.Metric
+		if !assert.NoError(t, h.(prom.Metric).Write(&out)) {
+			return 0
+		}
+		return out.GetHistogram().GetSampleCount()
+	}
+	assert.Equal(t, uint64(1), sampleCount(OutcomeCompleted))
+	assert.Equal(t, uint64(1), sampleCount(OutcomeCancelled))
+
+	h, _ := m.metrics["test_observe_since_seconds"].histogramVec.GetMetricWithLabelValues("get", OutcomeCompleted)
+	var out dto.Metric
+	_ = h.(prom.Metric).Write(&out)
+	assert.Less(t, out.GetHistogram().GetSampleSum(), 1.0)
+
+	// the outcome is not written to the spare capacity of the labels of the caller.
+	labels := make([]string, 1, 2)
+	labels[0] = "get"
+	assert.NoError(t, m.ObserveSince(ctx, "test_observe_since_seconds", time.Now(), labels...))
+	assert.Equal(t, "", labels[:2][1])
+}
@@ -1,12 +1,21 @@
 package metric
 
 import (
+	"context"
 	"sync"
+	"time"
 
 	"github.com/anthonycorbacho/workspace/kit/errors"
 	prom "github.com/prometheus/client_golang/prometheus"
 )
 
+// Outcome label of the metrics observed with ObserveSince.
+const (
+	OutcomeLabel     = "outcome"
+	OutcomeCompleted = "completed"
+	OutcomeCancelled = "_cancelled"
+)
+
 // Metrics defines a set of metric collection.
 type Metrics struct {
 	metricLock sync.RWMutex
@@ -75,3 +84,35 @@ func (m *Metrics) Observe(name string, val float64, labels ...string) error {
 
 	return mtr.Observe(val, labels...)
 }
+
+// ObserveSince observes the number of seconds elapsed since start using a histogram or summary.
+// The metric must be defined with OutcomeLabel as last label, its value is set to OutcomeCancelled
+// when ctx has been cancelled (or its deadline exceeded), OutcomeCompleted otherwise.
+// This keeps the latency of cancelled operations out of the completed ones.
+//
+// Example:
+//
+//	m.Register("operation_duration_seconds", "operation duration", Histogram(.1, .5, 1), Labels("operation", OutcomeLabel))
+//	defer m.ObserveSince(ctx, "operation_duration_seconds", time.Now(), "get")
+func (m *Metrics) ObserveSince(ctx context.Context, name string, start time.Time, labels ...string) error {
+	outcome := OutcomeCompleted
+	if ctx.Err() != nil {
+		outcome = OutcomeCancelled
+	}
+
+	// the labels are copied, appending to them could write to the backing array of the caller.
+	values := append(append(make([]string, 0, len(labels)+1), labels...), outcome)
+	return m.Observe(name, time.Since(start).Seconds(), values...)
+}
+
+// InFlight increments the given gauge metric and returns a function decrementing it,
+// to be called (usually deferred) once the tracked operation is done.
+// The name and labels must match a previously defined gauge metric.
+func (m *Metrics) InFlight(name string, labels ...string) (func(), error) {
+	if err := m.Increment(name, 1, labels...); err != nil {
+		return func() {}, err
+	}
+	return func() {
+		_ = m.Increment(name, -1, labels...) //nolint
+	}, nil
+}
@@ -38,6 +38,28 @@ func Histogram(buckets ...float64) Option {
 	}
 }
 
+// NativeHistogram counts individual observations using Prometheus native histograms (sparse buckets).
+// Buckets are exponential and do not need to be chosen upfront, the width of a bucket grows by
+// at most factor (must be greater than 1, 1.1 being a good trade-off) from one bucket to the next.
+// maxBuckets limits the number of populated buckets, 0 means no limit.
+//
+// Combined with Histogram, the classic buckets are exposed as well.
+// Native histograms require Prometheus v2.40+ with the native histograms feature enabled.
+func NativeHistogram(factor float64, maxBuckets int) Option {
+	return func(m *metric) error {
+		if factor <= 1 {
+			return errors.New("native histogram factor must be greater than 1")
+		}
+		if maxBuckets < 0 {
+			return errors.New("native histogram max buckets must not be negative")
+		}
+		m.kind = histogram
+		m.nativeFactor = factor
+		m.nativeMaxBuckets = uint32(maxBuckets)
+		return nil
+	}
+}
+
 // Summary captures individual observations from an event or sample stream and
 // summarizes them in a manner similar to traditional summary statistics:
 // 1. sum of observations
@@ -22,21 +22,29 @@ func handler(m middleware.Middleware, h http.Handler) http.Handler {
 			w: wi,
 			r: r,
 		}
-		route := mux.CurrentRoute(r)
-		path, err := route.GetPathTemplate()
-		if err != nil {
-			path, err = route.GetPathRegexp()
-			if err != nil {
-				path = r.URL.Path
-			}
-		}
-
-		m.Measure(path, reporter, func() {
+		m.Measure(routePath(r), reporter, func() {
 			h.ServeHTTP(wi, r)
 		})
 	})
 }
 
+// routePath returns the template of the mux route matching the request,
+// falling back to the url path.
+func routePath(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return r.URL.Path
+	}
+	path, err := route.GetPathTemplate()
+	if err != nil {
+		path, err = route.GetPathRegexp()
+		if err != nil {
+			path = r.URL.Path
+		}
+	}
+	return path
+}
+
 // Middleware sets up a handler to record metric of the incoming
 // requests.
 // This middleware will register the route template and not the url path.
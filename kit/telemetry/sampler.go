@@ -0,0 +1,118 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Context type for forced sampling
+type sampleCtxKeyType string
+
+const sampleCtxKey sampleCtxKeyType = "always-sample"
+
+// AlwaysSample marks the context so the spans started from it are always sampled,
+// regardless of the sample rate (eg: for critical flows like payments).
+// It requires the tracer provider to use ContextSampler, as done by NewTracer.
+func AlwaysSample(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sampleCtxKey, true)
+}
+
+// isAlwaysSampled returns true if the context has been marked with AlwaysSample.
+func isAlwaysSampled(ctx context.Context) bool {
+	v, _ := ctx.Value(sampleCtxKey).(bool)
+	return v
+}
+
+// ContextSampler wraps a sampler so the spans started from a context marked with AlwaysSample are always sampled,
+// the other spans follow the decision of the wrapped sampler.
+func ContextSampler(sampler sdktrace.Sampler) sdktrace.Sampler {
+	return contextSampler{sampler: sampler}
+}
+
+type contextSampler struct {
+	sampler sdktrace.Sampler
+}
+
+// ShouldSample samples the spans started from an AlwaysSample context, otherwise
+// returns the decision of the wrapped sampler.
+func (s contextSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if isAlwaysSampled(p.ParentContext) {
+		return sdktrace.SamplingResult{
+			Decision:   sdktrace.RecordAndSample,
+			Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+		}
+	}
+	return s.sampler.ShouldSample(p)
+}
+
+// Description returns the description of the sampler.
+func (s contextSampler) Description() string {
+	return "ContextSampler{" + s.sampler.Description() + "}"
+}
+
+// ErrorSampler wraps a sampler so the spans it drops are still recorded (but not sampled).
+// Combined with ErrorSpanProcessor, recorded spans ending with an error status are exported,
+// keeping errored spans regardless of the sampling ratio.
+//
+// Recording every span has a cost, as attributes and events are collected even for the spans that are not exported.
+func ErrorSampler(sampler sdktrace.Sampler) sdktrace.Sampler {
+	return errorSampler{sampler: sampler}
+}
+
+type errorSampler struct {
+	sampler sdktrace.Sampler
+}
+
+// ShouldSample returns the decision of the wrapped sampler, turning Drop into RecordOnly.
+func (s errorSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := s.sampler.ShouldSample(p)
+	if result.Decision == sdktrace.Drop {
+		result.Decision = sdktrace.RecordOnly
+	}
+	return result
+}
+
+// Description returns the description of the sampler.
+func (s errorSampler) Description() string {
+	return "ErrorSampler{" + s.sampler.Description() + "}"
+}
+
+// ErrorSpanProcessor wraps a span processor so the recorded but not sampled spans
+// ending with an error status are processed as sampled spans.
+func ErrorSpanProcessor(processor sdktrace.SpanProcessor) sdktrace.SpanProcessor {
+	return errorSpanProcessor{SpanProcessor: processor}
+}
+
+type errorSpanProcessor struct {
+	sdktrace.SpanProcessor
+}
+
+// OnEnd forwards the sampled and the errored spans to the wrapped processor.
+func (p errorSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		p.SpanProcessor.OnEnd(s)
+		return
+	}
+	if s.Status().Code == codes.Error {
+		p.SpanProcessor.OnEnd(sampledSpan{ReadOnlySpan: s})
+	}
+}
+
+// OnStart forwards the span to the wrapped processor.
+func (p errorSpanProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	p.SpanProcessor.OnStart(parent, s)
+}
+
+// sampledSpan is a span marked as sampled.
+type sampledSpan struct {
+	sdktrace.ReadOnlySpan
+}
+
+// SpanContext returns the span context with the sampled flag set.
+func (s sampledSpan) SpanContext() trace.SpanContext {
+	sc := s.ReadOnlySpan.SpanContext()
+	return sc.WithTraceFlags(sc.TraceFlags().WithSampled(true))
+}
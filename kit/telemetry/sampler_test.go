@@ -0,0 +1,103 @@
+package telemetry
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestErrorSampler(t *testing.T) {
+	exporter := &memoryExporter{}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(ErrorSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(0)))),
+		sdktrace.WithSpanProcessor(ErrorSpanProcessor(sdktrace.NewSimpleSpanProcessor(exporter))),
+	)
+	defer tp.Shutdown(context.Background()) //nolint
+	tracer := tp.Tracer("test")
+
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	_, ok := tracer.Start(ctx, "ok")
+	ok.End()
+	_, failed := tracer.Start(ctx, "failed")
+	failed.SetStatus(codes.Error, "boom")
+	failed.End()
+	parent.End()
+
+	spans := exporter.Spans()
+	if !assert.Len(t, spans, 1) {
+		return
+	}
+	assert.Equal(t, "failed", spans[0].Name())
+	assert.True(t, spans[0].SpanContext().IsSampled())
+	assert.Equal(t, parent.SpanContext().TraceID(), spans[0].SpanContext().TraceID())
+}
+
+func TestErrorSamplerKeepsSampledSpans(t *testing.T) {
+	exporter := &memoryExporter{}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(ErrorSampler(sdktrace.AlwaysSample())),
+		sdktrace.WithSpanProcessor(ErrorSpanProcessor(sdktrace.NewSimpleSpanProcessor(exporter))),
+	)
+	defer tp.Shutdown(context.Background()) //nolint
+
+	_, span := tp.Tracer("test").Start(context.Background(), "ok")
+	span.End()
+
+	assert.Len(t, exporter.Spans(), 1)
+}
+
+func TestAlwaysSample(t *testing.T) {
+	exporter := &memoryExporter{}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(ContextSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(0)))),
+		sdktrace.WithSpanProcessor(sdktrace.NewSimpleSpanProcessor(exporter)),
+	)
+	defer tp.Shutdown(context.Background()) //nolint
+	tracer := tp.Tracer("test")
+
+	// not sampled at ratio 0
+	_, span := tracer.Start(context.Background(), "ignored")
+	span.End()
+	assert.Empty(t, exporter.Spans())
+
+	// always sampled, including children.
+	ctx, span := tracer.Start(AlwaysSample(context.Background()), "payment")
+	_, child := tracer.Start(ctx, "charge")
+	child.End()
+	span.End()
+
+	spans := exporter.Spans()
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+	assert.Equal(t, "charge", spans[0].Name())
+	assert.Equal(t, "payment", spans[1].Name())
+	assert.True(t, spans[1].SpanContext().IsSampled())
+}
+
+// memoryExporter is a span exporter keeping the spans in memory.
+type memoryExporter struct {
+	mu    sync.Mutex
+	spans []sdktrace.ReadOnlySpan
+}
+
+func (e *memoryExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *memoryExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+func (e *memoryExporter) Spans() []sdktrace.ReadOnlySpan {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]sdktrace.ReadOnlySpan{}, e.spans...)
+}
@@ -15,6 +15,8 @@ import (
 // NewTracer returns a new and configured TracerProvider.
 //
 // You can define the Trace sample rate by env var via OTL_TRACE_SAMPLE_RATE
+// You can export the errored spans regardless of the sample rate by env var via OTL_TRACE_SAMPLE_ERRORS
+// Spans started from a context marked with AlwaysSample are always sampled.
 // You can define the OTL endpoint by env var via OTL_ENDPOINT
 // A list of attributes can be passed via env variable OTEL_RESOURCE_ATTRIBUTES;
 //
@@ -30,12 +32,17 @@ func NewTracer(serviceName string, opts ...func(*TracerOption)) (*sdktrace.Trace
 	if err != nil {
 		return nil, errors.Wrap(err, "getting sample rate from OTL_TRACE_SAMPLE_RATE")
 	}
+	sampleErrors, err := strconv.ParseBool(config.LookupEnv("OTL_TRACE_SAMPLE_ERRORS", "false"))
+	if err != nil {
+		return nil, errors.Wrap(err, "getting error sampling from OTL_TRACE_SAMPLE_ERRORS")
+	}
 	otlEndpoint := config.LookupEnv("OTL_ENDPOINT", "127.0.0.1:4317")
 
 	// Default configuration
 	option := &TracerOption{
-		OtlEndpoint: otlEndpoint,
-		SampleRate:  sampleRate,
+		OtlEndpoint:  otlEndpoint,
+		SampleRate:   sampleRate,
+		SampleErrors: sampleErrors,
 	}
 	for _, o := range opts {
 		o(option)
@@ -51,13 +58,18 @@ func NewTracer(serviceName string, opts ...func(*TracerOption)) (*sdktrace.Trace
 	}
 
 	bsp := sdktrace.NewBatchSpanProcessor(exporter)
+	sampler := ContextSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(option.SampleRate)))
+	if option.SampleErrors {
+		bsp = ErrorSpanProcessor(bsp)
+		sampler = ErrorSampler(sampler)
+	}
 
 	resource, err := newResource(serviceName)
 	if err != nil {
 		return nil, err
 	}
 	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(option.SampleRate))),
+		sdktrace.WithSampler(sampler),
 		sdktrace.WithSpanProcessor(bsp),
 		sdktrace.WithResource(resource),
 	)
@@ -71,8 +83,9 @@ func NewTracer(serviceName string, opts ...func(*TracerOption)) (*sdktrace.Trace
 
 // TracerOption for the Tracer.
 type TracerOption struct {
-	OtlEndpoint string
-	SampleRate  float64
+	OtlEndpoint  string
+	SampleRate   float64
+	SampleErrors bool
 }
 
 // WithSampleRate set the sample rate of tracing.
@@ -84,6 +97,14 @@ func WithSampleRate(rate float64) func(*TracerOption) {
 	}
 }
 
+// WithErrorSampling exports the spans ending with an error, even when they are not sampled by the sample rate.
+// See ErrorSampler.
+func WithErrorSampling() func(*TracerOption) {
+	return func(o *TracerOption) {
+		o.SampleErrors = true
+	}
+}
+
 func WithOtelEndpoint(endpoint string) func(option *TracerOption) {
 	return func(o *TracerOption) {
 		o.OtlEndpoint = endpoint
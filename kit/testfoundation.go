@@ -0,0 +1,204 @@
+package kit
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	// testStartTimeout is the maximum time TestFoundation waits for the servers to listen.
+	testStartTimeout = 5 * time.Second
+	// testStopTimeout is the maximum time TestFoundation waits for the foundation to stop.
+	testStopTimeout = 30 * time.Second
+)
+
+// TestFoundation is a Foundation served on local ephemeral ports, for the integration tests of a service.
+//
+// Services and handlers are registered on the embedded Foundation as usual, the foundation is then served
+// on the first call of HTTPClient, URL or GRPCConn and must be stopped with Stop:
+//
+//	tf, err := kit.NewTestFoundation("myservice")
+//	tf.RegisterService(func(s *grpc.Server) { pb.RegisterMyServiceServer(s, svc) })
+//	defer tf.Stop()
+//	client := pb.NewMyServiceClient(tf.GRPCConn())
+type TestFoundation struct {
+	*Foundation
+
+	httpAddr string
+	grpcAddr string
+
+	startOnce sync.Once
+	started   bool
+	startErr  error
+	served    chan error
+	conn      *grpc.ClientConn
+	stopOnce  sync.Once
+	stopErr   error
+}
+
+// NewTestFoundation creates a new TestFoundation, the options are applied like with NewFoundation.
+// The telemetry setup is disabled so several test foundations can be served in the same process.
+func NewTestFoundation(name string, options ...Option) (*TestFoundation, error) {
+	httpAddr, err := localAddr()
+	if err != nil {
+		return nil, errors.Wrap(err, "http address")
+	}
+	grpcAddr, err := localAddr()
+	if err != nil {
+		return nil, errors.Wrap(err, "grpc address")
+	}
+
+	options = append(options, WithHTTPAddr(httpAddr), WithGrpcAddr(grpcAddr), withoutTelemetry())
+	f, err := NewFoundation(name, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &TestFoundation{
+		Foundation: f,
+		httpAddr:   httpAddr,
+		grpcAddr:   grpcAddr,
+		served:     make(chan error, 1),
+	}, nil
+}
+
+// start serves the foundation once and waits for the servers to listen.
+func (tf *TestFoundation) start() error {
+	tf.startOnce.Do(func() {
+		tf.started = true
+		// the foundation refuses to serve without services, no need to wait for it.
+		if tf.grpcServer == nil && tf.httpServer == nil && !tf.opts.allowEmpty {
+			err := tf.Foundation.Serve()
+			tf.startErr = errors.Wrap(err, "foundation stopped")
+			tf.served <- err
+			return
+		}
+
+		// the gRPC server (and its health service) is always served.
+		tf.initGRPCServerOnce()
+		go func() {
+			tf.served <- tf.Foundation.Serve()
+		}()
+
+		addrs := []string{tf.grpcAddr}
+		if tf.httpServer != nil {
+			addrs = append(addrs, tf.httpAddr)
+		}
+		deadline := time.Now().Add(testStartTimeout)
+		for _, addr := range addrs {
+			for {
+				conn, err := net.Dial("tcp", addr)
+				if err == nil {
+					_ = conn.Close()
+					break
+				}
+				if time.Now().After(deadline) {
+					tf.startErr = errors.Wrapf(err, "foundation not listening on %s", addr)
+					return
+				}
+				select {
+				case err := <-tf.served:
+					tf.startErr = errors.Wrap(err, "foundation stopped")
+					tf.served <- err
+					return
+				case <-time.After(10 * time.Millisecond):
+				}
+			}
+		}
+
+		conn, err := grpc.Dial(tf.grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			tf.startErr = errors.Wrap(err, "grpc client")
+			return
+		}
+		tf.conn = conn
+	})
+	return tf.startErr
+}
+
+// URL returns the base URL of the HTTP server, eg: http://127.0.0.1:54321.
+// It serves the foundation if not already served.
+func (tf *TestFoundation) URL() string {
+	_ = tf.start()
+	return "http://" + tf.httpAddr
+}
+
+// HTTPClient returns a client sending all the requests to the HTTP server,
+// the URL of the requests can be relative (eg: client.Get("/users/42")).
+// It serves the foundation if not already served.
+func (tf *TestFoundation) HTTPClient() *http.Client {
+	_ = tf.start()
+	return &http.Client{
+		Transport: &testTransport{host: tf.httpAddr, base: http.DefaultTransport},
+	}
+}
+
+// GRPCConn returns a client connection to the gRPC server, closed by Stop.
+// It serves the foundation if not already served, the returned connection is nil if it failed.
+func (tf *TestFoundation) GRPCConn() *grpc.ClientConn {
+	_ = tf.start()
+	return tf.conn
+}
+
+// Stop shuts down the foundation and closes the gRPC client connection,
+// the error of the foundation serving or starting is returned.
+func (tf *TestFoundation) Stop() error {
+	// a foundation never served is not served anymore.
+	tf.startOnce.Do(func() {})
+	tf.stopOnce.Do(func() {
+		if !tf.started {
+			return
+		}
+		if tf.conn != nil {
+			_ = tf.conn.Close()
+		}
+
+		tf.Foundation.Shutdown()
+		select {
+		case err := <-tf.served:
+			tf.stopErr = err
+		case <-time.After(testStopTimeout):
+			tf.stopErr = errors.New("foundation not stopped")
+		}
+		if tf.stopErr == nil {
+			tf.stopErr = tf.startErr
+		}
+	})
+	return tf.stopErr
+}
+
+// testTransport sends the requests to the test HTTP server.
+type testTransport struct {
+	host string
+	base http.RoundTripper
+}
+
+func (t *testTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = "http"
+	req.URL.Host = t.host
+	req.Host = t.host
+	return t.base.RoundTrip(req)
+}
+
+// localAddr returns a free local address to listen on.
+func localAddr() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer l.Close()
+	return l.Addr().String(), nil
+}
+
+// withoutTelemetry disables the telemetry setup so multiple foundations can be served in the same process.
+func withoutTelemetry() Option {
+	return func(fo *FoundationOptions) {
+		fo.noTelemetry = true
+	}
+}
@@ -0,0 +1,66 @@
+package kit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestTestFoundation(t *testing.T) {
+	tf, err := NewTestFoundation("test")
+	if !assert.NoError(t, err) {
+		return
+	}
+	tf.RegisterService(func(s *grpc.Server) {})
+	tf.RegisterHTTPHandler("/hello", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "hello")
+	}, http.MethodGet)
+
+	// HTTP, with a relative or an absolute URL.
+	resp, err := tf.HTTPClient().Get("/hello")
+	if assert.NoError(t, err) {
+		b, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "hello", string(b))
+	}
+	resp, err = http.Get(tf.URL() + "/hello")
+	if assert.NoError(t, err) {
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	// gRPC
+	conn := tf.GRPCConn()
+	if assert.NotNil(t, conn) {
+		res, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.GetStatus())
+	}
+
+	assert.NoError(t, tf.Stop())
+	assert.NoError(t, tf.Stop())
+	_, err = http.Get(tf.URL() + "/hello")
+	assert.Error(t, err)
+}
+
+func TestTestFoundation_NotServed(t *testing.T) {
+	tf, err := NewTestFoundation("test")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, tf.Stop())
+
+	// nothing registered.
+	tf, err = NewTestFoundation("test")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Nil(t, tf.GRPCConn())
+	assert.Error(t, tf.Stop())
+}
@@ -0,0 +1,17 @@
+package kit
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpanFromRequest returns the current span of a request served by the Foundation HTTP server,
+// started from the trace context propagated by the caller (eg: traceparent header).
+//
+// The logs written with the request context carry the trace and span ids:
+//
+//	f.logger.Info(r.Context(), "handling request")
+func SpanFromRequest(r *http.Request) trace.Span {
+	return trace.SpanFromContext(r.Context())
+}
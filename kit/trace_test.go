@@ -0,0 +1,79 @@
+package kit
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/log"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+func TestSpanFromRequest(t *testing.T) {
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(previous)
+
+	path := filepath.Join(t.TempDir(), "log")
+	logger, err := log.New(log.WithOutputPaths(path))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	addr := freeAddr(t)
+	f, err := NewFoundation("test", WithHTTPAddr(addr), WithGrpcAddr(freeAddr(t)), withoutTelemetry())
+	if !assert.NoError(t, err) {
+		return
+	}
+	traceIDs := make(chan string, 1)
+	f.RegisterHTTPHandler("/traced", func(w http.ResponseWriter, r *http.Request) {
+		traceIDs <- SpanFromRequest(r).SpanContext().TraceID().String()
+		logger.Info(r.Context(), "traced handler")
+	}, http.MethodGet)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- f.Serve()
+	}()
+	defer func() {
+		f.Shutdown()
+		<-served
+	}()
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req, err := http.NewRequest(http.MethodGet, "http://"+addr+"/traced", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+
+	var resp *http.Response
+	assert.Eventually(t, func() bool {
+		resp, err = http.DefaultClient.Do(req)
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
+	if resp == nil {
+		return
+	}
+	_ = resp.Body.Close()
+	assert.Equal(t, traceID, <-traceIDs)
+
+	// the handler log carries the propagated trace id.
+	logger.Close()
+	b, err := os.ReadFile(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var entry struct {
+		Body    string
+		TraceID string `json:"TraceId"`
+	}
+	assert.NoError(t, json.Unmarshal(b, &entry))
+	assert.Equal(t, "traced handler", entry.Body)
+	assert.Equal(t, traceID, entry.TraceID)
+}
@@ -0,0 +1,65 @@
+package kit
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build information, they can be injected at build time with ldflags, eg:
+//
+//	go build -ldflags "-X github.com/anthonycorbacho/workspace/kit.Version=v1.2.3 -X github.com/anthonycorbacho/workspace/kit.Commit=$(git rev-parse HEAD)"
+//
+// When not injected, they are read from the build information embedded in the binary.
+var (
+	Version   string
+	Commit    string
+	BuildTime string
+)
+
+// BuildInfo describes the build of the running binary, served on /version by the internal server.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// ReadBuildInfo returns the build information of the running binary,
+// the ldflags injected values take precedence over the embedded build information.
+func ReadBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	if info.Version == "" {
+		info.Version = bi.Main.Version
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = s.Value
+			}
+		case "vcs.time":
+			if info.BuildTime == "" {
+				info.BuildTime = s.Value
+			}
+		}
+	}
+	return info
+}
+
+// versionHandler serves the build information as JSON.
+func versionHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(ReadBuildInfo()) //nolint
+}
@@ -0,0 +1,33 @@
+package kit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionHandler(t *testing.T) {
+	Version, Commit = "v1.2.3", "abcdef"
+	defer func() { Version, Commit = "", "" }()
+
+	rec := httptest.NewRecorder()
+	versionHandler(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var body map[string]string
+	if !assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body)) {
+		return
+	}
+	for _, key := range []string{"version", "commit", "build_time", "go_version"} {
+		assert.Contains(t, body, key)
+	}
+	assert.Equal(t, "v1.2.3", body["version"])
+	assert.Equal(t, "abcdef", body["commit"])
+	assert.Equal(t, runtime.Version(), body["go_version"])
+}
@@ -0,0 +1,13 @@
+// Package workerpool provides a bounded pool of workers processing jobs concurrently,
+// eg: offloading the messages received by a pubsub handler or processing batch work.
+//
+//	pool, err := workerpool.New(10, func(ctx context.Context, job Job) error {
+//		return process(ctx, job)
+//	})
+//
+//	// Submit blocks until the job has been queued or ctx is done.
+//	err := pool.Submit(ctx, job)
+//
+//	// Close drains the queued and in-flight jobs.
+//	err := pool.Close(ctx)
+package workerpool
@@ -0,0 +1,14 @@
+package workerpool
+
+// Pool errors.
+const (
+	ErrClosed = Error("worker pool is closed")
+)
+
+// Error represents a worker pool error.
+type Error string
+
+// Error returns the error message.
+func (e Error) Error() string {
+	return string(e)
+}
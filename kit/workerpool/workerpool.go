@@ -0,0 +1,225 @@
+package workerpool
+
+import (
+	"context"
+	"sync"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// options provides a set of configurable options for the Pool.
+type options struct {
+	name      string
+	queueSize int
+	logger    *log.Logger
+}
+
+// Option defines a Pool option.
+type Option func(*options)
+
+// WithName defines the name of the pool, used as the "pool" attribute of its metrics.
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// WithQueueSize defines the number of jobs that can be queued waiting for a worker.
+// By default, the queue size is the number of workers.
+func WithQueueSize(n int) Option {
+	return func(o *options) {
+		o.queueSize = n
+	}
+}
+
+// WithLogger defines the logger used to report the failed jobs.
+// By default, the global logger is used.
+func WithLogger(logger *log.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// Pool is a bounded pool of workers processing jobs of type T.
+//
+// At most workers jobs are processed concurrently, the jobs submitted while all the workers are busy
+// are queued, Submit blocks once the queue is full. A job returning an error or panicking
+// is reported (log and metrics) without stopping its worker.
+//
+// The following metrics are recorded: workerpool.queue_depth, workerpool.inflight, workerpool.processed
+// and workerpool.errors.
+type Pool[T any] struct {
+	fn     func(ctx context.Context, job T) error
+	jobs   chan T
+	logger *log.Logger
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	closed     bool
+	closedLock sync.RWMutex
+	// closing is closed by Close to release the blocked submitters.
+	closing    chan struct{}
+	submitters sync.WaitGroup
+	workers    sync.WaitGroup
+
+	attributes metric.MeasurementOption
+	queueDepth metric.Int64UpDownCounter
+	inflight   metric.Int64UpDownCounter
+	processed  metric.Int64Counter
+	errors     metric.Int64Counter
+}
+
+// New creates a new Pool with the given number of workers processing jobs with fn.
+// The pool must be closed with Close once no more jobs are submitted.
+func New[T any](workers int, fn func(ctx context.Context, job T) error, opts ...Option) (*Pool[T], error) {
+	if workers <= 0 {
+		return nil, errors.New("workers must be positive")
+	}
+	if fn == nil {
+		return nil, errors.New("job function is required")
+	}
+
+	o := &options{
+		name:      "default",
+		queueSize: workers,
+		logger:    log.L(),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.queueSize < 0 {
+		return nil, errors.New("queue size must not be negative")
+	}
+
+	meter := otel.Meter("kit/workerpool")
+	queueDepth, err := meter.Int64UpDownCounter("workerpool.queue_depth",
+		metric.WithDescription("Number of jobs waiting for a worker"))
+	if err != nil {
+		return nil, errors.Wrap(err, "queue depth metric")
+	}
+	inflight, err := meter.Int64UpDownCounter("workerpool.inflight",
+		metric.WithDescription("Number of jobs being processed"))
+	if err != nil {
+		return nil, errors.Wrap(err, "inflight metric")
+	}
+	processed, err := meter.Int64Counter("workerpool.processed",
+		metric.WithDescription("Number of jobs processed"))
+	if err != nil {
+		return nil, errors.Wrap(err, "processed metric")
+	}
+	errs, err := meter.Int64Counter("workerpool.errors",
+		metric.WithDescription("Number of jobs that returned an error or panicked"))
+	if err != nil {
+		return nil, errors.Wrap(err, "errors metric")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool[T]{
+		fn:         fn,
+		jobs:       make(chan T, o.queueSize),
+		logger:     o.logger,
+		ctx:        ctx,
+		cancel:     cancel,
+		closing:    make(chan struct{}),
+		attributes: metric.WithAttributes(attribute.String("pool", o.name)),
+		queueDepth: queueDepth,
+		inflight:   inflight,
+		processed:  processed,
+		errors:     errs,
+	}
+
+	p.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p, nil
+}
+
+// Submit queues the job, blocking until a worker or a queue slot is available or ctx is done.
+// ErrClosed is returned if the pool is closed, including while Submit is blocked on a full queue.
+func (p *Pool[T]) Submit(ctx context.Context, job T) error {
+	p.closedLock.RLock()
+	if p.closed {
+		p.closedLock.RUnlock()
+		return ErrClosed
+	}
+	p.submitters.Add(1)
+	p.closedLock.RUnlock()
+	defer p.submitters.Done()
+
+	p.queueDepth.Add(p.ctx, 1, p.attributes)
+	select {
+	case p.jobs <- job:
+		return nil
+	case <-p.closing:
+		p.queueDepth.Add(p.ctx, -1, p.attributes)
+		return ErrClosed
+	case <-ctx.Done():
+		p.queueDepth.Add(p.ctx, -1, p.attributes)
+		return errors.Wrap(ctx.Err(), "submitting job")
+	}
+}
+
+// Close stops accepting jobs and waits for the queued and in-flight jobs to be processed.
+// If ctx is done before, the context given to the in-flight jobs is cancelled and ctx error is returned.
+// The submitters blocked on a full queue are released with ErrClosed.
+func (p *Pool[T]) Close(ctx context.Context) error {
+	p.closedLock.Lock()
+	if p.closed {
+		p.closedLock.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.closing)
+	p.closedLock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		// the jobs channel is closed once no submitter can send on it anymore.
+		p.submitters.Wait()
+		close(p.jobs)
+		p.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		return errors.Wrap(ctx.Err(), "draining worker pool")
+	}
+}
+
+// work processes jobs until the pool is closed.
+func (p *Pool[T]) work() {
+	defer p.workers.Done()
+	for job := range p.jobs {
+		p.queueDepth.Add(p.ctx, -1, p.attributes)
+		p.process(job)
+	}
+}
+
+// process runs a single job, recovering from panics.
+func (p *Pool[T]) process(job T) {
+	p.inflight.Add(p.ctx, 1, p.attributes)
+	defer p.inflight.Add(p.ctx, -1, p.attributes)
+	defer p.processed.Add(p.ctx, 1, p.attributes)
+
+	defer func() {
+		if r := recover(); r != nil {
+			p.errors.Add(p.ctx, 1, p.attributes)
+			p.logger.Error(p.ctx, "worker pool job panic", log.Any("panic", r))
+		}
+	}()
+
+	if err := p.fn(p.ctx, job); err != nil {
+		p.errors.Add(p.ctx, 1, p.attributes)
+		p.logger.Error(p.ctx, "worker pool job failed", log.Error(err))
+	}
+}
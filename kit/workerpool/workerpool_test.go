@@ -0,0 +1,202 @@
+package workerpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anthonycorbacho/workspace/kit/errors"
+	"github.com/anthonycorbacho/workspace/kit/log"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestBoundedConcurrency(t *testing.T) {
+	var current, max atomic.Int32
+	p, err := New(3, func(ctx context.Context, job int) error {
+		n := current.Add(1)
+		defer current.Add(-1)
+		for {
+			m := max.Load()
+			if n <= m || max.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}, WithLogger(log.NewNop()))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for i := 0; i < 30; i++ {
+		assert.NoError(t, p.Submit(context.Background(), i))
+	}
+	assert.NoError(t, p.Close(context.Background()))
+	assert.Equal(t, int32(3), max.Load())
+}
+
+func TestCloseDrains(t *testing.T) {
+	var processed atomic.Int32
+	p, err := New(2, func(ctx context.Context, job int) error {
+		time.Sleep(5 * time.Millisecond)
+		processed.Add(1)
+		return nil
+	}, WithQueueSize(20), WithLogger(log.NewNop()))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, p.Submit(context.Background(), i))
+	}
+	assert.NoError(t, p.Close(context.Background()))
+	assert.Equal(t, int32(20), processed.Load())
+
+	// no more jobs accepted once closed.
+	assert.Equal(t, ErrClosed, p.Submit(context.Background(), 21))
+	assert.NoError(t, p.Close(context.Background()))
+}
+
+func TestCloseTimeout(t *testing.T) {
+	cancelled := make(chan struct{})
+	p, err := New(1, func(ctx context.Context, job int) error {
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	}, WithLogger(log.NewNop()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, p.Submit(context.Background(), 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = p.Close(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	// in-flight jobs are cancelled
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		assert.Fail(t, "in-flight job not cancelled")
+	}
+}
+
+func TestCloseWithBlockedSubmit(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	p, err := New(1, func(ctx context.Context, job int) error {
+		<-release
+		return nil
+	}, WithQueueSize(1), WithLogger(log.NewNop()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	// one job in-flight, one queued: the next submit blocks.
+	assert.NoError(t, p.Submit(context.Background(), 1))
+	assert.NoError(t, p.Submit(context.Background(), 2))
+
+	submitted := make(chan error, 1)
+	go func() {
+		submitted <- p.Submit(context.Background(), 3)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	closed := make(chan error, 1)
+	go func() {
+		closed <- p.Close(ctx)
+	}()
+
+	select {
+	case err := <-closed:
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	case <-time.After(time.Second):
+		assert.Fail(t, "close blocked past its deadline")
+	}
+	select {
+	case err := <-submitted:
+		assert.Equal(t, ErrClosed, err)
+	case <-time.After(time.Second):
+		assert.Fail(t, "submit not released by close")
+	}
+}
+
+func TestSubmitCancel(t *testing.T) {
+	release := make(chan struct{})
+	p, err := New(1, func(ctx context.Context, job int) error {
+		<-release
+		return nil
+	}, WithQueueSize(0), WithLogger(log.NewNop()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, p.Submit(context.Background(), 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = p.Submit(ctx, 2)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	close(release)
+	assert.NoError(t, p.Close(context.Background()))
+}
+
+func TestPanickingJob(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	var processed atomic.Int32
+	p, err := New(1, func(ctx context.Context, job int) error {
+		switch job {
+		case 0:
+			panic("boom")
+		case 1:
+			return errors.New("failed")
+		}
+		processed.Add(1)
+		return nil
+	}, WithName("test"), WithLogger(log.NewNop()))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// the single worker survives the panic.
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, p.Submit(context.Background(), i))
+	}
+	assert.NoError(t, p.Close(context.Background()))
+	assert.Equal(t, int32(3), processed.Load())
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	sums := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if data, ok := m.Data.(metricdata.Sum[int64]); ok {
+				for _, dp := range data.DataPoints {
+					sums[m.Name] += dp.Value
+				}
+			}
+		}
+	}
+	assert.Equal(t, int64(5), sums["workerpool.processed"])
+	assert.Equal(t, int64(2), sums["workerpool.errors"])
+	assert.Equal(t, int64(0), sums["workerpool.inflight"])
+	assert.Equal(t, int64(0), sums["workerpool.queue_depth"])
+}
+
+func TestInvalidPool(t *testing.T) {
+	_, err := New(0, func(ctx context.Context, job int) error { return nil })
+	assert.Error(t, err)
+
+	_, err = New[int](1, nil)
+	assert.Error(t, err)
+}
@@ -36,16 +36,9 @@ func (u *grpcUser) Fetch(ctx context.Context, request *pb.FetchRequest) (*pb.Fet
 	if err != nil {
 		u.log.Error(ctx, "fetching user", log.Error(err), log.String("user.id", request.Id))
 
-		if errors.Is(err, sampleapp.ErrUserNotFound) {
-			return nil, errors.Status(
-				codes.NotFound,
-				"user not found",
-				&errdetails.ErrorInfo{
-					Reason: "USER_NOT_FOUND",
-					Metadata: map[string]string{
-						"user.id": request.Id,
-					},
-				})
+		// not found errors are NotFound statuses.
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, err
 		}
 
 		return nil, errors.Status(
@@ -130,16 +123,8 @@ func (u *grpcUser) Delete(ctx context.Context, request *pb.DeleteRequest) (*pb.D
 	if err := u.service.Delete(ctx, request.Id); err != nil {
 		u.log.Error(ctx, "deleting user", log.Error(err), log.String("user.id", request.Id))
 
-		if errors.Is(err, sampleapp.ErrUserNotFound) {
-			return nil, errors.Status(
-				codes.NotFound,
-				"cannot delete unknown user",
-				&errdetails.ErrorInfo{
-					Reason: "USER_NOT_FOUND",
-					Metadata: map[string]string{
-						"user.id": request.Id,
-					},
-				})
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, err
 		}
 
 		return nil, errors.Status(
@@ -1,8 +1,6 @@
 package sampleapp
 
 const (
-	// ErrUserNotFound when user is not found.
-	ErrUserNotFound = Error("user not found")
 	// ErrUserNameMissing when username is missing.
 	ErrUserNameMissing = Error("user name is missing")
 	// ErrUserAlreadyExist when user already exist in the system.
@@ -42,7 +42,7 @@ func (u *UserStorage) Fetch(ctx context.Context, id string) (*sampleapp.User, er
 
 	usr, ok := u.db[id]
 	if !ok {
-		err := sampleapp.ErrUserNotFound
+		err := errors.NotFound("user", id)
 		span.RecordError(err)
 		span.SetStatus(codes.Error, fmt.Sprintf("user %s doest exist", id))
 		return nil, err
@@ -61,6 +61,7 @@ func New(storage UserStorage) *UserService {
 // this is only designed for demo purpose.
 
 // Fetch fetches a single user by the given ID.
+// If the user does not exist, an error matching errors.ErrNotFound will be returned.
 func (u *UserService) Fetch(ctx context.Context, id string) (*User, error) {
 	const op = "user.user"
 	ctx, span := otel.Tracer("").Start(ctx, op)
@@ -93,7 +94,7 @@ func (u *UserService) Create(ctx context.Context, usr *User) error {
 }
 
 // Delete deletes the user form the user storage.
-// If the user does not exist, ErrUserNotFound will be returned.
+// If the user does not exist, an error matching errors.ErrNotFound will be returned.
 func (u *UserService) Delete(ctx context.Context, id string) error {
 	const op = "user.delete"
 	ctx, span := otel.Tracer("").Start(ctx, op)